@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// loadContractABIs reads the ABI file configured for each watched contract.
+func loadContractABIs(paths map[string]string) (map[common.Address]*abi.ABI, error) {
+	abis := make(map[common.Address]*abi.ABI, len(paths))
+	for addr, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("contract ABI for %s: %w", addr, err)
+		}
+		parsed, err := abi.JSON(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("contract ABI for %s: %w", addr, err)
+		}
+		abis[common.HexToAddress(addr)] = &parsed
+	}
+	return abis, nil
+}
+
+// setMethod adds the called method to p: decoded with named arguments
+// when the target has a configured ABI, otherwise from the selector alone.
+func (s *Scanner) setMethod(p *TxPayload, to common.Address, input []byte) {
+	if contract := s.abis[to]; contract != nil && len(input) >= 4 {
+		if method, err := contract.MethodById(input[:4]); err == nil {
+			if values, err := method.Inputs.Unpack(input[4:]); err == nil {
+				args := make(map[string]interface{}, len(values))
+				for i, v := range values {
+					name := method.Inputs[i].Name
+					if name == "" {
+						name = fmt.Sprintf("arg%d", i)
+					}
+					args[name] = abiValue(v)
+				}
+				p.Method = method.Name
+				p.MethodSignature = method.Sig
+				p.Args = args
+				return
+			}
+		}
+	}
+	if method, ok := decodeMethod(input); ok {
+		p.Method = method
+	}
+}
+
+// abiValue converts decoded ABI values into JSON-friendly forms: integers as
+// decimal strings so large amounts keep full precision, addresses and byte
+// strings as hex.
+func abiValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *big.Int:
+		return x.String()
+	case common.Address:
+		return x.Hex()
+	case []byte:
+		return "0x" + common.Bytes2Hex(x)
+	case [32]byte:
+		return common.Hash(x).Hex()
+	case []*big.Int:
+		return bigStrings(x)
+	case []common.Address:
+		out := make([]string, len(x))
+		for i, a := range x {
+			out[i] = a.Hex()
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,31 @@
+package main
+
+import "github.com/nidhish1/BlockSentinel/go-listener/routes"
+
+// alertSubscriberBuffer is how many alerts a live subscriber may fall behind
+// before it is disconnected.
+const alertSubscriberBuffer = 256
+
+// liveAlerts streams matched transactions and verdicts to API subscribers.
+var liveAlerts = routes.NewBroadcaster(alertSubscriberBuffer)
+
+// publishTransaction pushes a matched transaction to live subscribers and
+// the alert sinks.
+func publishTransaction(p *TxPayload) {
+	liveAlerts.Publish("transaction", p)
+	emitToSinks(p)
+}
+
+// publishRisk pushes an analyzer verdict to live subscribers.
+func publishRisk(chainID uint64, wallet string, p *TxPayload, result *RiskResult) {
+	liveAlerts.Publish("risk", map[string]interface{}{
+		"chainId":    chainID,
+		"hash":       p.Hash,
+		"wallet":     wallet,
+		"riskScore":  result.Score,
+		"riskLevel":  result.Level,
+		"reasons":    result.Reasons,
+		"confidence": result.Confidence,
+		"labels":     result.Labels,
+	})
+}
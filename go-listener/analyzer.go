@@ -2,33 +2,275 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
 )
 
-func sendToAIAnalyzer(analyzerURL string, txData map[string]interface{}) error {
-	jsonData, err := json.Marshal(txData)
+// RiskResult is the analyzer's verdict for one transaction.
+type RiskResult struct {
+	Score      float64  `json:"risk_score"`
+	Level      string   `json:"risk_level"`
+	Reasons    []string `json:"reasons,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+
+	// Raw is the response body as received, kept for storage.
+	Raw json.RawMessage `json:"-"`
+}
+
+// decodeRiskResult parses and validates an analyzer response body. The
+// analyzer may report reasons either as a "reasons" list or as a single
+// " | "-separated "reasoning" string.
+func decodeRiskResult(body []byte) (*RiskResult, error) {
+	var wire struct {
+		Score      *float64 `json:"risk_score"`
+		Level      *string  `json:"risk_level"`
+		Reasons    []string `json:"reasons"`
+		Reasoning  string   `json:"reasoning"`
+		Confidence *float64 `json:"confidence"`
+		Labels     []string `json:"labels"`
+	}
+	if err := json.Unmarshal(body, &wire); err != nil {
+		return nil, fmt.Errorf("invalid analyzer response: %w", err)
+	}
+
+	var problems []string
+	if wire.Score == nil {
+		problems = append(problems, "risk_score missing")
+	} else if *wire.Score < 0 || *wire.Score > 1 {
+		problems = append(problems, fmt.Sprintf("risk_score %v outside [0, 1]", *wire.Score))
+	}
+	if wire.Level == nil || *wire.Level == "" {
+		problems = append(problems, "risk_level missing")
+	}
+	if len(problems) > 0 {
+		return nil, errors.New("invalid analyzer response: " + strings.Join(problems, "; "))
+	}
+
+	r := &RiskResult{
+		Score:      *wire.Score,
+		Level:      *wire.Level,
+		Reasons:    wire.Reasons,
+		Confidence: wire.Confidence,
+		Labels:     wire.Labels,
+		Raw:        json.RawMessage(body),
+	}
+	if len(r.Reasons) == 0 && wire.Reasoning != "" {
+		r.Reasons = strings.Split(wire.Reasoning, " | ")
+	}
+	return r, nil
+}
+
+const (
+	defaultAnalyzerConcurrency = 4
+	defaultAnalyzerTimeout     = 30 // seconds
+
+	// maxAnalyzerResponseBytes caps how much of a response is read, so a
+	// misbehaving analyzer cannot exhaust memory.
+	maxAnalyzerResponseBytes = 1 << 20
+)
+
+var analyzerInFlight = metrics.NewGauge("blocksentinel_analyzer_in_flight", "Analyzer requests currently in flight.")
+
+// analyzerSlots bounds in-flight analyzer requests across all chains. Sends
+// beyond the limit wait for a free slot.
+var analyzerSlots = make(chan struct{}, defaultAnalyzerConcurrency)
+
+// setAnalyzerConcurrency resizes the analyzer semaphore. It must be called
+// before any scanner starts.
+func setAnalyzerConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	analyzerSlots = make(chan struct{}, n)
+}
+
+// signatureHeader carries the HMAC of the request body as "sha256=<hex>".
+const signatureHeader = "X-Signature"
+
+// signBody returns the signatureHeader value for body under secret.
+func signBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendToAnalyzers posts p to every configured analyzer concurrently and
+// returns the verdicts keyed by analyzer URL. One analyzer failing does not
+// hold back the others: the error joins every failure, and is returned
+// alongside whatever verdicts did arrive.
+func sendToAnalyzers(ctx context.Context, cfg *Config, p *TxPayload) (map[string]*RiskResult, error) {
+	jsonData, err := encodePayload(p, cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp, err := http.Post(analyzerURL+"/analyze", "application/json", bytes.NewBuffer(jsonData))
+	urls := cfg.live().AnalyzerURLs
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]*RiskResult, len(urls))
+		errs    []error
+	)
+	for _, base := range urls {
+		wg.Add(1)
+		go func(base string) {
+			defer wg.Done()
+			result, err := sendToAIAnalyzer(ctx, cfg, base, jsonData)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", base, err))
+				return
+			}
+			results[base] = result
+		}(base)
+	}
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
+
+// highestRisk picks the verdict that drives alerts: the highest score, ties
+// going to the analyzer listed first.
+func highestRisk(cfg *Config, results map[string]*RiskResult) *RiskResult {
+	var top *RiskResult
+	for _, base := range cfg.live().AnalyzerURLs {
+		if r := results[base]; r != nil && (top == nil || r.Score > top.Score) {
+			top = r
+		}
+	}
+	return top
+}
+
+// analyzerGzip records, per analyzer URL, whether its last response
+// advertised "Accept-Encoding: gzip"; see Config.AnalyzerCompress.
+var analyzerGzip sync.Map
+
+// sendToAIAnalyzer posts an encoded payload to the analyzer at base and
+// returns its validated response. A configured AnalyzerSigningSecret signs the
+// body so the analyzer can reject requests that did not come from the
+// listener. Unless the analyzer's breaker is open it waits for a free
+// concurrency slot, giving up when ctx ends, and the request itself is bounded
+// by AnalyzerTimeout.
+func sendToAIAnalyzer(ctx context.Context, cfg *Config, base string, jsonData []byte) (*RiskResult, error) {
+	endpoint, err := url.JoinPath(base, "analyze")
+	if err != nil {
+		return nil, fmt.Errorf("invalid analyzer URL: %w", err)
+	}
+	accepted, _ := analyzerGzip.Load(base)
+	compress := cfg.AnalyzerCompress && accepted == true
+
+	// An open breaker fails fast rather than queueing for a slot behind
+	// sends to healthy analyzers
+	b := analyzerBreaker(base)
+	if !b.allow() {
+		return nil, errAnalyzerUnavailable
+	}
+	slots := analyzerSlots
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		b.abandon()
+		return nil, ctx.Err()
+	}
+	analyzerInFlight.Add(1)
+	defer func() {
+		analyzerInFlight.Add(-1)
+		<-slots
+	}()
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.AnalyzerTimeout)*time.Second)
+	defer cancel()
+	result, err := postAndDecode(reqCtx, cfg, base, endpoint, jsonData, compress)
+	// A send abandoned by the caller says nothing about the analyzer, but
+	// one that timed out counts against it
+	if ctx.Err() != nil {
+		b.abandon()
+	} else {
+		b.record(err)
+	}
+	return result, err
+}
+
+// postAndDecode sends one analyzer request and validates the response.
+func postAndDecode(ctx context.Context, cfg *Config, base, endpoint string, jsonData []byte, compress bool) (*RiskResult, error) {
+	resp, err := postToAnalyzer(ctx, cfg, endpoint, jsonData, compress)
+	if err == nil && compress && resp.StatusCode == http.StatusUnsupportedMediaType {
+		// It no longer takes gzip; resend plain until it advertises it again
+		resp.Body.Close()
+		analyzerGzip.Store(base, false)
+		resp, err = postToAnalyzer(ctx, cfg, endpoint, jsonData, false)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	if cfg.AnalyzerCompress {
+		analyzerGzip.Store(base, strings.Contains(strings.ToLower(resp.Header.Get("Accept-Encoding")), "gzip"))
+	}
 
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAnalyzerResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxAnalyzerResponseBytes {
+		return nil, fmt.Errorf("AI analyzer response exceeds %d bytes", maxAnalyzerResponseBytes)
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("AI analyzer error: %s", string(body))
+		return nil, fmt.Errorf("AI analyzer error: %s", string(body))
+	}
+
+	result, err := decodeRiskResult(body)
+	if err != nil {
+		return nil, err
 	}
+	riskLogs.logVerdict(ctx, cfg, base, result)
 
-	var result map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	log.Printf("Risk Analysis: %+v", result)
+	return result, nil
+}
 
-	return nil
+// postToAnalyzer sends one analyzer request, gzipping the body when compress
+// is set. The signature always covers the uncompressed JSON.
+func postToAnalyzer(ctx context.Context, cfg *Config, endpoint string, jsonData []byte, compress bool) (*http.Response, error) {
+	body := jsonData
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(jsonData); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if id := correlationFrom(ctx); id != "" {
+		req.Header.Set(correlationHeader, id)
+	}
+	if cfg.AnalyzerSigningSecret != "" {
+		req.Header.Set(signatureHeader, signBody(cfg.AnalyzerSigningSecret, jsonData))
+	}
+	return http.DefaultClient.Do(req)
 }
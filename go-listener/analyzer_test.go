@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendToAIAnalyzerURL(t *testing.T) {
+	paths := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		w.Write([]byte(`{"risk_score": 0.1, "risk_level": "low"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		path string // appended to the server's http://h
+		want string
+	}{
+		{"bare host", "", "/analyze"},
+		{"trailing slash", "/", "/analyze"},
+		{"path prefix", "/prefix/", "/prefix/analyze"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.AIAnalyzerURL = srv.URL + tt.path
+			cfg.normalize()
+			if len(cfg.AnalyzerURLs) != 1 {
+				t.Fatalf("analyzer URLs = %v, want one", cfg.AnalyzerURLs)
+			}
+
+			if _, err := sendToAIAnalyzer(context.Background(), cfg, cfg.AnalyzerURLs[0], []byte(`{}`)); err != nil {
+				t.Fatalf("sendToAIAnalyzer: %v", err)
+			}
+			if got := <-paths; got != tt.want {
+				t.Errorf("request path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// withBreakers enables analyzer breakers that open on the first failure.
+func withBreakers(t *testing.T) {
+	t.Helper()
+	threshold, cooldown := breakerThreshold, breakerCooldown
+	breakerThreshold, breakerCooldown = 1, time.Minute
+	t.Cleanup(func() { breakerThreshold, breakerCooldown = threshold, cooldown })
+}
+
+// stalledAnalyzer is an analyzer that never answers until the test ends.
+func stalledAnalyzer(t *testing.T) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+	return srv
+}
+
+func TestSendToAIAnalyzerTimeoutTripsBreaker(t *testing.T) {
+	withBreakers(t)
+	srv := stalledAnalyzer(t)
+
+	cfg := testConfig()
+	cfg.AnalyzerTimeout = 1
+	start := time.Now()
+	_, err := sendToAIAnalyzer(context.Background(), cfg, srv.URL, []byte(`{}`))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %s despite a 1s timeout", elapsed)
+	}
+	if _, err := sendToAIAnalyzer(context.Background(), cfg, srv.URL, []byte(`{}`)); !errors.Is(err, errAnalyzerUnavailable) {
+		t.Errorf("after a timeout err = %v, want the breaker open", err)
+	}
+}
+
+func TestSendToAIAnalyzerCallerCancelSparesBreaker(t *testing.T) {
+	withBreakers(t)
+	srv := stalledAnalyzer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := sendToAIAnalyzer(ctx, testConfig(), srv.URL, []byte(`{}`)); err == nil {
+		t.Fatal("sendToAIAnalyzer succeeded after the caller gave up")
+	}
+	if !analyzerBreaker(srv.URL).allow() {
+		t.Error("breaker opened for a send the caller abandoned")
+	}
+}
+
+func TestSendToAIAnalyzerOpenBreakerSkipsSlotWait(t *testing.T) {
+	withBreakers(t)
+	const base = "http://analyzer.invalid"
+	analyzerBreaker(base).record(errors.New("connection refused"))
+
+	slots := analyzerSlots
+	setAnalyzerConcurrency(1)
+	analyzerSlots <- struct{}{}
+	t.Cleanup(func() { analyzerSlots = slots })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := sendToAIAnalyzer(ctx, testConfig(), base, []byte(`{}`)); !errors.Is(err, errAnalyzerUnavailable) {
+		t.Errorf("err = %v, want errAnalyzerUnavailable without waiting for a slot", err)
+	}
+}
+
+func TestSendToAIAnalyzerRejectsOversizedResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"risk_score": 0.1, "risk_level": "low", "reasons": ["`))
+		w.Write(bytes.Repeat([]byte("x"), maxAnalyzerResponseBytes))
+		w.Write([]byte(`"]}`))
+	}))
+	defer srv.Close()
+
+	_, err := sendToAIAnalyzer(context.Background(), testConfig(), srv.URL, []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("err = %v, want the response rejected as too large", err)
+	}
+}
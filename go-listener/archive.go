@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// archiveTimeout bounds each block upload so a slow store never stalls scanning.
+const archiveTimeout = 30 * time.Second
+
+const (
+	archiveFormatJSON = "json"
+	archiveFormatRLP  = "rlp"
+)
+
+// ArchiveConfig selects where raw blocks containing matches are archived.
+// An empty Type disables archiving.
+type ArchiveConfig struct {
+	Type   string `yaml:"type,omitempty"`   // "file" or "s3"
+	Format string `yaml:"format,omitempty"` // "json" (default) or "rlp"
+
+	Dir string `yaml:"dir,omitempty"` // file: root directory
+
+	// s3: any S3-compatible endpoint, addressed path-style
+	Endpoint        string `yaml:"endpoint,omitempty"`
+	Region          string `yaml:"region,omitempty"`
+	Bucket          string `yaml:"bucket,omitempty"`
+	Prefix          string `yaml:"prefix,omitempty"`
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+}
+
+// Archiver stores an archived block under a key. Keys are deterministic, so
+// archiving the same block again overwrites it with identical content.
+type Archiver interface {
+	Name() string
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// newArchiver builds the configured archiver, or nil when archiving is off.
+func newArchiver(c ArchiveConfig) (Archiver, error) {
+	switch c.Type {
+	case "":
+		return nil, nil
+	case "file":
+		return &fileArchiver{dir: c.Dir}, nil
+	case "s3":
+		endpoint, err := url.Parse(strings.TrimRight(c.Endpoint, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("archive endpoint: %w", err)
+		}
+		return &s3Archiver{
+			endpoint:  endpoint,
+			region:    c.Region,
+			bucket:    c.Bucket,
+			prefix:    strings.Trim(c.Prefix, "/"),
+			accessKey: c.AccessKeyID,
+			secretKey: c.SecretAccessKey,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown archive type %q", c.Type)
+	}
+}
+
+// archivedBlock is the JSON form of an archived block. Header and transaction
+// fields use the same encoding as the JSON-RPC API.
+type archivedBlock struct {
+	Hash         string               `json:"hash"`
+	Header       *types.Header        `json:"header"`
+	Transactions []*types.Transaction `json:"transactions"`
+	Uncles       []*types.Header      `json:"uncles"`
+	Withdrawals  types.Withdrawals    `json:"withdrawals,omitempty"`
+}
+
+// archiveKey returns the object key for a block, e.g. "1/19000000.json".
+func archiveKey(chainID, blockNum uint64, format string) string {
+	return fmt.Sprintf("%d/%d.%s", chainID, blockNum, format)
+}
+
+// archiveBlock uploads the raw block for later forensic replay. Failures are
+// logged but never stop the scan.
+func (s *Scanner) archiveBlock(ctx context.Context, chainID uint64, block *types.Block) {
+	if s.archiver == nil || s.cfg.DryRun {
+		return
+	}
+	format := s.cfg.Archive.Format
+	if format == "" {
+		format = archiveFormatJSON
+	}
+
+	var data []byte
+	var err error
+	if format == archiveFormatRLP {
+		data, err = rlp.EncodeToBytes(block)
+	} else {
+		data, err = json.Marshal(archivedBlock{
+			Hash:         block.Hash().Hex(),
+			Header:       block.Header(),
+			Transactions: block.Transactions(),
+			Uncles:       block.Uncles(),
+			Withdrawals:  block.Withdrawals(),
+		})
+	}
+	if err != nil {
+		s.logf("Error encoding block %d for archive: %v", block.NumberU64(), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, archiveTimeout)
+	defer cancel()
+	key := archiveKey(chainID, block.NumberU64(), format)
+	if err := s.archiver.Put(ctx, key, data); err != nil {
+		s.logf("Error archiving block %d to %s: %v", block.NumberU64(), s.archiver.Name(), err)
+		return
+	}
+	s.printf("🗄️  Archived block %d as %s\n", block.NumberU64(), key)
+}
+
+// fileArchiver writes blocks beneath a local directory.
+type fileArchiver struct {
+	dir string
+}
+
+func (a *fileArchiver) Name() string { return "file" }
+
+func (a *fileArchiver) Put(ctx context.Context, key string, data []byte) error {
+	dst := filepath.Join(a.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	// Write then rename so a crash never leaves a partial block behind
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// s3Archiver PUTs blocks to an S3-compatible bucket, signing requests with
+// AWS Signature Version 4.
+type s3Archiver struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+}
+
+func (a *s3Archiver) Name() string { return "s3" }
+
+func (a *s3Archiver) Put(ctx context.Context, key string, data []byte) error {
+	if a.prefix != "" {
+		key = a.prefix + "/" + key
+	}
+	u := *a.endpoint
+	u.Path = path.Join("/", u.Path, a.bucket, key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	a.sign(req, data, time.Now().UTC())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
+
+// sign adds SigV4 headers for a single-chunk upload of body.
+func (a *s3Archiver) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{
+		"content-type":         req.Header.Get("Content-Type"),
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(values[h]) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + a.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+a.secretKey), date)
+	key = hmacSHA256(key, a.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
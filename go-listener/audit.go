@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+const defaultAuditFile = "scanned_blocks.jsonl"
+
+// auditFileMu serializes appends from concurrent chain scanners.
+var auditFileMu sync.Mutex
+
+// auditBlock records that block was examined, matches or not, to the
+// scanned_blocks table when Postgres is available and to an append-only JSON
+// lines file otherwise. Failures are logged but never stop the scan.
+func (s *Scanner) auditBlock(ctx context.Context, chainID uint64, block *types.Block, matchCount int) {
+	if !s.cfg.AuditBlocks || s.cfg.DryRun {
+		return
+	}
+	rec := dbpkg.ScannedBlock{
+		ChainID:    chainID,
+		BlockNum:   block.NumberU64(),
+		BlockHash:  block.Hash().Hex(),
+		TxCount:    len(block.Transactions()),
+		MatchCount: matchCount,
+		ScannedAt:  time.Now().UTC(),
+	}
+	if s.pool != nil {
+		err := s.writes.write(ctx, fmt.Sprintf("scanned block %d", rec.BlockNum), func(ctx context.Context, pool *pgxpool.Pool) error {
+			return dbpkg.RecordScannedBlock(ctx, pool, rec)
+		})
+		if err != nil {
+			s.logf("Error recording scanned block %d: %v", rec.BlockNum, err)
+		}
+		return
+	}
+	if err := appendAuditLine(s.cfg.AuditFile, rec); err != nil {
+		s.logf("Error writing block audit log: %v", err)
+	}
+}
+
+func appendAuditLine(path string, rec dbpkg.ScannedBlock) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	auditFileMu.Lock()
+	defer auditFileMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
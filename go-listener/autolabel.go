@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+const autoLabelTimeout = 10 * time.Second
+
+// AutoLabelConfig labels a monitored wallet in the addresses table once the
+// analyzer scores one of its transactions at MinScore or above, so rules,
+// groups and watchlists keyed on the label pick it up. Wallets missing from
+// the table, such as ones only listed in the config file, are left alone.
+// Empty Label disables it.
+type AutoLabelConfig struct {
+	Label    string  `yaml:"label,omitempty"`
+	MinScore float64 `yaml:"min_score,omitempty"` // 0 uses the wallet's risk threshold
+}
+
+// autoLabeler applies AutoLabelConfig for one chain. Each wallet is labelled
+// at most once per process; removing the label through the API sticks until
+// a restart.
+type autoLabeler struct {
+	cfg   AutoLabelConfig
+	store dbpkg.Store
+
+	mu      sync.Mutex
+	labeled map[common.Address]bool
+}
+
+func newAutoLabeler(cfg AutoLabelConfig, store dbpkg.Store) *autoLabeler {
+	if cfg.Label == "" || store == nil {
+		return nil
+	}
+	return &autoLabeler{cfg: cfg, store: store, labeled: map[common.Address]bool{}}
+}
+
+// autoLabel adds the configured label to wallet when result reaches the
+// auto-label score, logging every label it adds.
+func (s *Scanner) autoLabel(ctx context.Context, wallet common.Address, p *TxPayload, result *RiskResult) {
+	a := s.autoLabels
+	if a == nil || s.cfg.DryRun {
+		return
+	}
+	min := a.cfg.MinScore
+	if min == 0 {
+		min = s.riskThresholdFor(wallet)
+	}
+	if result.Score < min {
+		return
+	}
+	a.mu.Lock()
+	done := a.labeled[wallet]
+	a.labeled[wallet] = true
+	a.mu.Unlock()
+	if done {
+		return
+	}
+
+	writeCtx, cancel := context.WithTimeout(ctx, autoLabelTimeout)
+	defer cancel()
+	_, _, err := a.store.PatchAddress(writeCtx, wallet.Hex(), []string{a.cfg.Label}, nil, false, nil)
+	switch {
+	case errors.Is(err, dbpkg.ErrNotFound):
+		s.txLogf(ctx, "Not auto-labelling %s: not in the addresses table", wallet.Hex())
+		return
+	case err != nil:
+		// Let a later verdict retry
+		a.mu.Lock()
+		delete(a.labeled, wallet)
+		a.mu.Unlock()
+		s.txLogf(ctx, "Error auto-labelling %s: %v", wallet.Hex(), err)
+		return
+	}
+	// Labels can put an address on or off a watchlist
+	invalidateWalletCaches()
+	if err := a.store.NotifyAddressesChanged(writeCtx); err != nil {
+		s.txLogf(ctx, "Error notifying address change: %v", err)
+	}
+	s.txLogf(ctx, "🏷️  Auto-labelled %s %q: %s scored %.2f (%s)", wallet.Hex(), a.cfg.Label, p.Hash, result.Score, result.Level)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// minedGasPrice returns what a transaction mined under baseFee paid per gas.
+// Dynamic-fee and later types pay the base fee plus their effective tip, not
+// their fee cap; legacy and access-list types, and blocks before London,
+// pay their gas price.
+func minedGasPrice(tx *types.Transaction, baseFee *big.Int) string {
+	if tx.Type() < types.DynamicFeeTxType || baseFee == nil {
+		return bigOrZero(tx.GasPrice())
+	}
+	tip, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		// Cannot be mined under baseFee; report what it offered
+		return bigOrZero(tx.GasPrice())
+	}
+	return new(big.Int).Add(baseFee, tip).String()
+}
+
+// setBlobFields adds the EIP-4844 fields of a blob transaction to p. receipt
+// may be nil, as for pending transactions: the blob gas used follows from the
+// blob count, but the blob gas price is only known from a receipt.
+func setBlobFields(p *TxPayload, tx *types.Transaction, receipt *types.Receipt) {
+	if tx.Type() != types.BlobTxType {
+		return
+	}
+	hashes := tx.BlobHashes()
+	p.BlobVersionedHashes = make([]string, len(hashes))
+	for i, h := range hashes {
+		p.BlobVersionedHashes[i] = h.Hex()
+	}
+	p.MaxFeePerBlobGas = bigOrZero(tx.BlobGasFeeCap())
+	blobGas := tx.BlobGas()
+	p.BlobGasUsed = &blobGas
+	if receipt != nil && receipt.BlobGasPrice != nil {
+		p.BlobGasUsed = &receipt.BlobGasUsed
+		p.BlobGasPrice = receipt.BlobGasPrice.String()
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+// What happens to a transaction no analyzer would take because their
+// breakers are open.
+const (
+	breakerActionQueue = "queue" // retry it later through the analysis queue
+	breakerActionDrop  = "drop"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 // seconds
+)
+
+// Breaker states, as exported by blocksentinel_analyzer_breaker_state.
+const (
+	breakerClosed   = 0
+	breakerOpen     = 1
+	breakerHalfOpen = 2
+)
+
+var (
+	breakerState = metrics.NewGaugeVec("blocksentinel_analyzer_breaker_state", "Analyzer circuit breaker state: 0 closed, 1 open, 2 half-open.", "analyzer")
+	breakerTrips = metrics.NewCounterVec("blocksentinel_analyzer_breaker_trips_total", "Times an analyzer circuit breaker opened.", "analyzer")
+)
+
+// errAnalyzerUnavailable is returned without a request while an analyzer's
+// breaker is open.
+var errAnalyzerUnavailable = errors.New("circuit breaker open")
+
+// breaker stops requests to an analyzer after threshold consecutive
+// failures. Once cooldown has passed, one request goes through as a probe:
+// success closes the breaker, failure opens it for another cooldown.
+type breaker struct {
+	name      string // redacted analyzer URL, for logs and metrics
+	threshold int    // 0 disables the breaker
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool // the half-open probe is in flight
+}
+
+// analyzerBreakers holds one breaker per analyzer URL, created on first use
+// since the settings table can add analyzers at runtime.
+var (
+	breakersMu       sync.Mutex
+	analyzerBreakers = map[string]*breaker{}
+	breakerThreshold int
+	breakerCooldown  time.Duration
+)
+
+// setAnalyzerBreakers configures the breakers. It must be called before any
+// scanner starts; until then breakers are disabled.
+func setAnalyzerBreakers(cfg *Config) {
+	breakerThreshold = cfg.AnalyzerBreakerThreshold
+	breakerCooldown = time.Duration(cfg.AnalyzerBreakerCooldown) * time.Second
+	for _, base := range cfg.AnalyzerURLs {
+		analyzerBreaker(base)
+	}
+}
+
+// analyzerBreaker returns the breaker for an analyzer URL.
+func analyzerBreaker(base string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := analyzerBreakers[base]
+	if !ok {
+		b = &breaker{name: redactURL(base, false), threshold: breakerThreshold, cooldown: breakerCooldown}
+		breakerState.With(b.name).Set(breakerClosed)
+		analyzerBreakers[base] = b
+	}
+	return b
+}
+
+// allow reports whether a request may be sent now.
+func (b *breaker) allow() bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		// Everything else waits on the probe's outcome
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record counts the outcome of an allowed request.
+func (b *breaker) record(err error) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == nil {
+		if b.state != breakerClosed {
+			log.Printf("✅ Analyzer %s answered again; circuit breaker closed", b.name)
+		}
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		if b.state == breakerClosed {
+			breakerTrips.With(b.name).Inc()
+			log.Printf("⚠️  Analyzer %s failed %d times in a row; circuit breaker open for %s", b.name, b.failures, b.cooldown)
+		}
+		b.openedAt = time.Now()
+		b.setState(breakerOpen)
+	}
+}
+
+// abandon releases a probe slot taken by allow without counting an outcome.
+func (b *breaker) abandon() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// onlyBreakerErrors reports whether every analyzer in a sendToAnalyzers error
+// was skipped by its breaker rather than tried.
+func onlyBreakerErrors(err error) bool {
+	if err == nil {
+		return false
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return errors.Is(err, errAnalyzerUnavailable)
+	}
+	for _, e := range joined.Unwrap() {
+		if !errors.Is(e, errAnalyzerUnavailable) {
+			return false
+		}
+	}
+	return true
+}
+
+// setState updates the state and its gauge. b.mu must be held.
+func (b *breaker) setState(state int) {
+	b.state = state
+	breakerState.With(b.name).Set(float64(state))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// referencedWallets returns the monitored wallets found in the ABI words of
+// data, the 32-byte slots after the 4-byte method selector. A word counts as
+// an address when its top 12 bytes are zero, so routers, multisends and token
+// transfers that name the real recipient as an argument are caught.
+//
+// This is a heuristic: any uint256 argument that happens to equal a monitored
+// address matches too, and tightly packed encodings (such as Gnosis MultiSend
+// payloads) or addresses in dynamic data that is not word-aligned are missed.
+func referencedWallets(data []byte, walletSet map[common.Address]bool) []common.Address {
+	const selectorLen, wordLen, addrOffset = 4, 32, 12
+	if len(data) < selectorLen+wordLen || len(walletSet) == 0 {
+		return nil
+	}
+	var found []common.Address
+	seen := make(map[common.Address]bool)
+	for off := selectorLen; off+wordLen <= len(data); off += wordLen {
+		word := data[off : off+wordLen]
+		if !allZero(word[:addrOffset]) {
+			continue
+		}
+		addr := common.BytesToAddress(word[addrOffset:])
+		if walletSet[addr] && !seen[addr] {
+			seen[addr] = true
+			found = append(found, addr)
+		}
+	}
+	return found
+}
+
+func allZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hexAddresses formats addrs as checksummed hex, or nil when empty.
+func hexAddresses(addrs []common.Address) []string {
+	if len(addrs) == 0 {
+		return nil
+	}
+	out := make([]string, len(addrs))
+	for i, a := range addrs {
+		out[i] = a.Hex()
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+// runChain connects to one chain's RPC node and runs its monitoring loop forever.
+func runChain(cfg *Config, chain ChainConfig, dbpool *pgxpool.Pool, dbstore dbpkg.Store, writes *writeBuffer, queue analysisQueue, store StateStore, jobs *jobQueue) {
+	client, err := dialReconnecting(context.Background(), chain.RPCURL, chain.Name, cfg.RPCMaxReconnects)
+	if err != nil {
+		log.Fatalf("[%s] Failed to connect to RPC: %v", chain.Name, err)
+	}
+	defer client.Close()
+
+	scanner, err := newScanner(client, cfg, chain, dbpool)
+	if err != nil {
+		log.Fatalf("[%s] Failed to set up scanner: %v", chain.Name, err)
+	}
+	scanner.writes = writes
+	scanner.queue = queue
+	scanner.state = store
+
+	chainID, err := scanner.loadChainID(context.Background())
+	if err != nil {
+		log.Fatalf("[%s] Failed to determine chain ID: %v", chain.Name, err)
+	}
+	stateKey := chain.StateKey
+	if stateKey == "" && !chain.legacyState {
+		stateKey = chainID.String()
+	}
+
+	scanner.printf("✅ Connected to RPC node (chain ID %s)\n", chainID)
+	scanner.printf("👛 Monitoring wallets: %v\n", chain.Wallets)
+	if cfg.ScanMode == scanModeLogs {
+		scanner.printf("📜 Log scan mode: watching NFT transfer events only\n")
+	}
+	if len(scanner.notifiers) > 0 {
+		scanner.printf("🔔 %d notifier(s) configured (risk threshold %.2f)\n", len(scanner.notifiers), cfg.RiskThreshold)
+	}
+
+	refresh := time.Duration(cfg.WalletRefreshInterval) * time.Second
+	wallets := newWalletCache(dbstore, *chain.MonitorLabel, chain.Wallets, refresh)
+	if ens := newENSResolver(scanner.client, chain.Name, chain.Wallets); ens != nil {
+		ens.resolve(context.Background())
+		if cfg.ENSRefreshInterval > 0 {
+			go ens.run(time.Duration(cfg.ENSRefreshInterval) * time.Second)
+		}
+		wallets.ens = ens
+	}
+	scanner.wallets = wallets
+	scanner.counterparties = newCounterpartyCache(dbpool, refresh)
+	scanner.groups = newGroupCache(dbpool, refresh)
+	scanner.spamTokens = newSpamTokenCache(dbstore, cfg.SpamLabel, cfg.SpamTokens, refresh)
+	scanner.autoLabels = newAutoLabeler(cfg.AutoLabelOnRisk, dbstore)
+	jobs.register(scanner)
+
+	if cfg.ToBlock != nil {
+		if err := scanner.backfill(wallets.Set(context.Background()), *cfg.FromBlock, *cfg.ToBlock); err != nil {
+			scanner.logf("❌ %v", err)
+		}
+		return
+	}
+
+	if cfg.DryRun {
+		scanner.printf("🧪 Dry run: nothing is sent to the analyzer or notifiers and no transactions are stored\n")
+	} else {
+		go scanner.retryPendingAnalyses(chainID.Uint64())
+	}
+	if cfg.WatchMempool {
+		go scanner.watchMempool(client.RPC, chainID, wallets)
+	}
+
+	// Load last processed block from state
+	lastBlock, err := store.Load(context.Background(), stateKey)
+	if err != nil {
+		scanner.logf("Error loading state, starting from block 0: %v", err)
+		lastBlock = 0
+	}
+
+	if cfg.StartBlock != nil {
+		// -from-block names the first block to scan; state holds the last one scanned
+		lastBlock = 0
+		if *cfg.StartBlock > 0 {
+			lastBlock = *cfg.StartBlock - 1
+		}
+		scanner.printf("⏩ Overriding stored state with -from-block %d\n", *cfg.StartBlock)
+	}
+
+	scanner.printf("Starting from block %d\n", lastBlock)
+	if !cfg.Once {
+		go scanner.watchForStalls()
+	}
+
+	// Main monitoring loop
+	failures := 0
+	var idleSince, lastIdleLog time.Time
+	for {
+		// Wallets come from DB addresses carrying the monitor label (cached), falling back to config
+		newLastBlock, err := scanner.fetchNewTransactions(wallets.Set(context.Background()), lastBlock)
+		if errors.Is(err, errRPCReconnectFailed) {
+			log.Fatalf("[%s] ❌ %v", chain.Name, err)
+		}
+		if err != nil {
+			failures++
+			scanner.logf("Error fetching transactions: %v", err)
+		} else if newLastBlock != lastBlock {
+			// Save state if we processed new blocks, or rewound past a reorg
+			// A one-off dry run previews a range without moving the cursor
+			if !(cfg.DryRun && cfg.Once) {
+				err = store.Save(context.Background(), stateKey, scanner.resumePoint(newLastBlock))
+				if err != nil {
+					scanner.logf("Error saving state: %v", err)
+				}
+			}
+			lastBlock = newLastBlock
+			idleSince = time.Time{}
+			scanner.printf("✅ Updated last processed block to %d\n", lastBlock)
+		} else if idleSince.IsZero() {
+			// Log the switch to idle, then only every idleLogInterval while it lasts
+			idleSince, lastIdleLog = time.Now(), time.Now()
+			scanner.printf("⏳ No new blocks to process\n")
+		} else if time.Since(lastIdleLog) >= idleLogInterval {
+			lastIdleLog = time.Now()
+			scanner.printf("⏳ Still no new blocks (idle for %s)\n", time.Since(idleSince).Round(time.Second))
+		}
+
+		if cfg.Once {
+			scanner.printf("🏁 Single scan complete (-once), exiting at block %d\n", lastBlock)
+			return
+		}
+
+		if err != nil {
+			delay := pollBackoff(time.Duration(cfg.live().PollInterval)*time.Second, failures)
+			scanner.printf("🔁 Scan failed %d time(s) in a row, retrying in %s\n", failures, delay.Round(time.Second))
+			time.Sleep(delay)
+			continue
+		}
+		failures = 0
+
+		interval := cfg.live().PollInterval
+		if idleSince.IsZero() {
+			scanner.printf("💤 Sleeping for %d seconds...\n", interval)
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
+
+// idleLogInterval spaces out "no new blocks" lines while a chain stays idle.
+const idleLogInterval = time.Minute
+
+// maxPollBackoff caps the wait between scans while the RPC keeps failing.
+const maxPollBackoff = 5 * time.Minute
+
+// pollBackoff returns the wait after the given number of consecutive failed
+// scans: the poll interval doubled per failure, capped at maxPollBackoff, with
+// the upper half randomized so replicas don't retry in lockstep.
+func pollBackoff(interval time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 1; i < failures && wait < maxPollBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxPollBackoff {
+		wait = maxPollBackoff
+	}
+	half := wait / 2
+	return half + rand.N(half+1)
+}
@@ -1,55 +1,423 @@
 package main
 
 import (
+	"crypto/tls"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/routes"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	RPCURL        string   `yaml:"rpc_url"`
-	Wallets       []string `yaml:"wallets"`
-	PollInterval  int      `yaml:"poll_interval"`
-	AIAnalyzerURL string   `yaml:"ai_analyzer_url,omitempty"`
-	DatabaseURL   string   `yaml:"database_url,omitempty"`
+	RPCURL            string   `yaml:"rpc_url"`
+	Wallets           []string `yaml:"wallets"`
+	PollInterval      int      `yaml:"poll_interval"`
+	AIAnalyzerURL     string   `yaml:"ai_analyzer_url,omitempty"`
+	DatabaseURL       string   `yaml:"database_url,omitempty"`
+	MaxBulkAddresses  int      `yaml:"max_bulk_addresses,omitempty"`
+	MonitorLabel      string   `yaml:"monitor_label"`
+	MaxBlocksPerBatch int      `yaml:"max_blocks_per_batch"`
+	Confirmations     int      `yaml:"confirmations"`    // base depth; confirmation_tiers can require more
+	StartupLookback   int      `yaml:"startup_lookback"` // blocks behind the head when there is no saved position; 0 = genesis, < 0 = head
+
+	// Transfers of at least a tier's value wait for its deeper confirmations
+	ConfirmationTiers []ConfirmationTier `yaml:"confirmation_tiers,omitempty"`
+
+	DBMaxConns          int `yaml:"db_max_conns,omitempty"`
+	DBMinConns          int `yaml:"db_min_conns,omitempty"`
+	DBMaxConnLifetime   int `yaml:"db_max_conn_lifetime,omitempty"`   // seconds
+	DBHealthCheckPeriod int `yaml:"db_health_check_period,omitempty"` // seconds
+
+	// Scanner writes made while Postgres is unreachable are held in memory and
+	// replayed when it recovers; 0 disables buffering
+	DBWriteBufferSize     int    `yaml:"db_write_buffer_size"`
+	DBWriteBufferOverflow string `yaml:"db_write_buffer_overflow"` // "drop_oldest" or "drop_newest"
+
+	AutoMigrate   bool   `yaml:"auto_migrate"`
+	MigrationsDir string `yaml:"migrations_dir,omitempty"` // SQL files overriding the migrations built into the binary
+
+	// Where matched transactions are written, besides the analyzer
+	Sinks []SinkConfig `yaml:"sinks,omitempty"`
+
+	Notifiers     []NotifierConfig `yaml:"notifiers,omitempty"`
+	RiskThreshold float64          `yaml:"risk_threshold"`
+	MinValueWei   string           `yaml:"min_value_wei,omitempty"`
+
+	NotificationDigest DigestConfig `yaml:"notification_digest,omitempty"`
+
+	// Only this fraction of matches is sent to the analyzer, picked by
+	// transaction hash; matches worth at least sample_above_wei always are
+	SampleRate     float64 `yaml:"sample_rate"`
+	SampleAboveWei string  `yaml:"sample_above_wei,omitempty"`
+
+	RPCMaxReconnects     int     `yaml:"rpc_max_reconnects"`
+	RPCRequestsPerSecond float64 `yaml:"rpc_requests_per_second,omitempty"`
+
+	Chains []ChainConfig `yaml:"chains,omitempty"`
+
+	EnableTracing bool `yaml:"enable_tracing,omitempty"`
+	SkipReverted  bool `yaml:"skip_reverted,omitempty"`
+	EnableERC721  bool `yaml:"enable_erc721,omitempty"`
+	EnableERC1155 bool `yaml:"enable_erc1155,omitempty"`
+	WatchMempool  bool `yaml:"watch_mempool,omitempty"`
+	DryRun        bool `yaml:"dry_run,omitempty"`
+
+	// Also match contract calls whose calldata names a monitored wallet, for
+	// funds routed through routers and multisends. Heuristic: any argument
+	// equal to a monitored address matches, so expect some false positives
+	ScanCalldataForAddresses bool `yaml:"scan_calldata_for_addresses,omitempty"`
+
+	// "blocks" downloads every block to match native transfers as well as
+	// NFT events; "logs" watches NFT events only, letting the node filter
+	// them by wallet and downloading just the blocks that contain a match
+	ScanMode string `yaml:"scan_mode"`
+
+	// Token spam: transfers of spam_tokens or of stored addresses labelled
+	// spam_label are never alerted; spam_heuristic also drops unsolicited
+	// zero-value transfers of tokens without a contract_abis entry
+	SpamTokens    []string `yaml:"spam_tokens,omitempty"`
+	SpamLabel     string   `yaml:"spam_label"`
+	SpamHeuristic bool     `yaml:"spam_heuristic,omitempty"`
+
+	// Label wallets whose transactions the analyzer scores as high risk
+	AutoLabelOnRisk AutoLabelConfig `yaml:"auto_label_on_risk,omitempty"`
+
+	// Matched transactions are tagged with every rule they satisfy; with
+	// rules_only set, those satisfying none are dropped
+	Rules     []RuleConfig `yaml:"rules,omitempty"`
+	RulesOnly bool         `yaml:"rules_only,omitempty"`
+
+	BlockRetryAttempts int    `yaml:"block_retry_attempts"`
+	OnBlockFailure     string `yaml:"on_block_failure"` // "halt" or "skip"
+
+	// A reorg that replaces scanned blocks is walked back to the common
+	// ancestor and rescanned from there, but never more than MaxReorgDepth
+	// blocks; beyond that ReorgAction either halts the scanner or resyncs
+	// from startup_lookback
+	MaxReorgDepth int    `yaml:"max_reorg_depth"`
+	ReorgAction   string `yaml:"reorg_action"` // "halt" or "resync"
+
+	StallWindow int `yaml:"stall_window"` // seconds without progress before alerting; 0 disables
+
+	Direction string `yaml:"direction"` // "incoming", "outgoing" or "both"
+
+	// USD conversion; price_feed_asset is the CoinGecko coin id or the
+	// Chainlink aggregator address for the native token
+	PriceFeedType     string `yaml:"price_feed_type,omitempty"` // "coingecko" or "chainlink"
+	PriceFeedURL      string `yaml:"price_feed_url,omitempty"`
+	PriceFeedAsset    string `yaml:"price_feed_asset,omitempty"`
+	PriceCacheSeconds int    `yaml:"price_cache_seconds,omitempty"`
+
+	// Labels for addresses written through the API, from an external provider
+	Enrichment EnrichmentConfig `yaml:"enrichment,omitempty"`
+
+	HTTPAddr string `yaml:"http_addr"` // "host:port" or ":port"; empty disables the API
+
+	// PEM files; when both are set the API is served over TLS 1.2+ (with
+	// HTTP/2) instead of plaintext
+	TLSCert string `yaml:"tls_cert,omitempty"`
+	TLSKey  string `yaml:"tls_key,omitempty"`
+
+	APIKeys []string `yaml:"api_keys,omitempty"`
+
+	AllowedLabels []string `yaml:"allowed_labels,omitempty"` // empty allows any label
+
+	// Mutating API calls are limited to write_allowlist (CIDRs or IPs) when
+	// set; CORS headers are only sent when cors_allowed_origins is set
+	WriteAllowlist     []string `yaml:"write_allowlist,omitempty"`
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins,omitempty"`
+	CORSAllowedMethods []string `yaml:"cors_allowed_methods,omitempty"`
+	CORSAllowedHeaders []string `yaml:"cors_allowed_headers,omitempty"`
+
+	MaxRequestBodyBytes int `yaml:"max_request_body_bytes"`
+	IdempotencyTTL      int `yaml:"idempotency_ttl"` // seconds an Idempotency-Key response is replayed
+
+	MaxInputBytes int `yaml:"max_input_bytes"` // calldata bytes per alert; 0 sends it all
+
+	// Contract address to ABI JSON file, for decoding calls to watched contracts
+	ContractABIs map[string]string `yaml:"contract_abis,omitempty"`
+
+	AuditBlocks bool   `yaml:"audit_blocks"`
+	AuditFile   string `yaml:"audit_file"` // used when Postgres is unavailable
+
+	LogLevel string `yaml:"log_level"` // "info" or "debug"
+
+	// Seconds between summaries of repeated low-risk analyzer verdicts, which
+	// are counted instead of logged; 0 logs every verdict
+	RiskLogSummaryInterval int `yaml:"risk_log_summary_interval"`
+
+	// Raw copies of blocks containing matches, for forensic replay
+	Archive ArchiveConfig `yaml:"archive,omitempty"`
+
+	// Every matched transaction is sent to each analyzer concurrently;
+	// ai_analyzer_url, when set, is the first entry
+	AnalyzerURLs []string `yaml:"analyzer_urls,omitempty"`
+
+	AnalyzerSigningSecret string `yaml:"analyzer_signing_secret,omitempty"` // HMAC key for X-Signature; empty sends requests unsigned
+	PayloadNaming         string `yaml:"payload_naming"`                    // analyzer request keys: "camel" or "snake"
+
+	// Gzip analyzer requests to analyzers whose responses advertise
+	// "Accept-Encoding: gzip"; the first request to each is always plain
+	AnalyzerCompress bool `yaml:"analyzer_compress,omitempty"`
+
+	// What the analyzer sees, for operators who must not share calldata or
+	// addresses with a third party
+	AnalyzerFields      []string `yaml:"analyzer_fields,omitempty"` // camelCase payload keys to send; empty sends all
+	AnalyzerAddressMode string   `yaml:"analyzer_address_mode"`     // "full", "hash" or "truncate"
+
+	AnalyzerConcurrency int    `yaml:"analyzer_concurrency"`
+	AnalyzerTimeout     int    `yaml:"analyzer_timeout"`       // seconds per request, including reading the response
+	AnalysisRetryMaxAge int    `yaml:"analysis_retry_max_age"` // seconds
+	PendingQueueFile    string `yaml:"pending_queue_file"`
+
+	// Circuit breaker per analyzer: after AnalyzerBreakerThreshold consecutive
+	// failures, stop sending to it for AnalyzerBreakerCooldown, then probe
+	AnalyzerBreakerThreshold int    `yaml:"analyzer_breaker_threshold"` // 0 disables the breaker
+	AnalyzerBreakerCooldown  int    `yaml:"analyzer_breaker_cooldown"`  // seconds
+	AnalyzerBreakerAction    string `yaml:"analyzer_breaker_action"`    // "queue" or "drop" transactions skipped while open
+
+	WalletRefreshInterval int `yaml:"wallet_refresh_interval"` // seconds
+	ENSRefreshInterval    int `yaml:"ens_refresh_interval"`    // seconds between re-resolving ENS names in wallets; 0 resolves once
+
+	// Seconds between re-reading the Postgres settings table, whose rows
+	// override this config; see settingKeys
+	SettingsRefreshInterval int `yaml:"settings_refresh_interval"`
+
+	SeenCacheSize int    `yaml:"seen_cache_size"`
+	DedupWindow   int    `yaml:"dedup_window"`  // seconds; 0 re-forwards every re-scan
+	StateBackend  string `yaml:"state_backend"` // "file", "postgres" or "redis"
+	RedisURL      string `yaml:"redis_url,omitempty"`
+
+	// Backfill mode: when ToBlock is set, scan exactly [FromBlock, ToBlock] and
+	// exit without touching the live-tail state
+	FromBlock *uint64 `yaml:"from_block,omitempty"`
+	ToBlock   *uint64 `yaml:"to_block,omitempty"`
+
+	// Runtime-only settings, set from command-line flags
+	StartBlock *uint64 `yaml:"-"`
+	Once       bool    `yaml:"-"`
 }
 
-func loadConfig() (*Config, error) {
-	// First try environment variables
-	rpcURL := os.Getenv("RPC_URL")
-	aiAnalyzerURL := os.Getenv("AI_ANALYZER_URL")
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		dbURL = os.Getenv("POSTGRES_DSN")
-	}
+// ChainConfig describes one chain scanned by its own goroutine. Wallets and
+// MonitorLabel fall back to the top-level values when unset.
+type ChainConfig struct {
+	Name         string   `yaml:"name"`
+	RPCURL       string   `yaml:"rpc_url"`
+	Wallets      []string `yaml:"wallets,omitempty"`
+	MonitorLabel *string  `yaml:"monitor_label,omitempty"`
+	// StateKey keys this chain's position in the state file; defaults to the chain ID.
+	StateKey string `yaml:"state_key,omitempty"`
+	// Signer recovers transaction senders: "latest" (the default), "eip155",
+	// "homestead" or "frontier". Older signers are always tried as fallbacks
+	// for legacy transactions; typed ones always use the latest signer.
+	Signer string `yaml:"signer,omitempty"`
 
-	if rpcURL != "" {
-		// Use environment variables
-		wallets := strings.Split(os.Getenv("WALLETS"), ",")
-		if len(wallets) == 0 {
-			wallets = []string{"0x1234567890abcdef1234567890abcdef12345678"}
-		}
+	// legacyState marks the chain normalized from the single-chain fields,
+	// which keeps reading and writing the top-level last_block position.
+	legacyState bool
+}
 
-		pollInterval := 15
-		if pi := os.Getenv("POLL_INTERVAL"); pi != "" {
-			if piVal, err := strconv.Atoi(pi); err == nil {
-				pollInterval = piVal
-			}
+// chainConfigs returns the chains to scan. The single-chain fields are normalized
+// into a one-element list when no chains are configured, and per-chain fields
+// inherit the top-level defaults.
+func (c *Config) chainConfigs() []ChainConfig {
+	if len(c.Chains) == 0 {
+		label := c.MonitorLabel
+		return []ChainConfig{{
+			Name:         "default",
+			RPCURL:       c.RPCURL,
+			Wallets:      c.Wallets,
+			MonitorLabel: &label,
+			legacyState:  true,
+		}}
+	}
+	out := make([]ChainConfig, len(c.Chains))
+	for i, ch := range c.Chains {
+		if len(ch.Wallets) == 0 {
+			ch.Wallets = c.Wallets
 		}
+		if ch.MonitorLabel == nil {
+			label := c.MonitorLabel
+			ch.MonitorLabel = &label
+		}
+		out[i] = ch
+	}
+	return out
+}
+
+// Log levels.
+const (
+	logLevelInfo  = "info"
+	logLevelDebug = "debug" // also logs suppressed alerts
+)
+
+const (
+	defaultMaxBlocksPerBatch = 500
+	defaultRiskThreshold     = 0.7
+	defaultAnalysisMaxAge    = 24 * 60 * 60
+	defaultPendingQueueFile  = "pending_analysis.json"
+	defaultWalletRefresh     = 30
+	defaultENSRefresh        = 3600
+	defaultDBWriteBuffer     = 10000
+	defaultBlockRetries      = 3
+	defaultConfirmations     = 6
+	defaultStartupLookback   = 1000
+	defaultSeenCacheSize     = 10000
+	defaultDedupWindow       = 3600
+	defaultHTTPAddr          = ":8080"
+	defaultMaxInputBytes     = 4096
+)
+
+// defaultConfig returns the built-in defaults every other source overrides.
+func defaultConfig() *Config {
+	return &Config{
+		PollInterval:      15,
+		MonitorLabel:      dbpkg.DefaultMonitorLabel,
+		MaxBlocksPerBatch: defaultMaxBlocksPerBatch,
+		Confirmations:     defaultConfirmations,
+		StartupLookback:   defaultStartupLookback,
+		RiskThreshold:     defaultRiskThreshold,
+		SampleRate:        1,
+		RPCMaxReconnects:  defaultRPCMaxReconnects,
+		AutoMigrate:       true,
+
+		DBWriteBufferSize:     defaultDBWriteBuffer,
+		DBWriteBufferOverflow: overflowDropOldest,
+
+		NotificationDigest: DigestConfig{
+			ImmediateThreshold: defaultDigestImmediateThreshold,
+			TopCounterparties:  defaultDigestTopCounterparties,
+		},
+
+		AuditFile: defaultAuditFile,
+
+		Enrichment: EnrichmentConfig{
+			RequestsPerSecond: defaultEnrichmentRate,
+			CacheTTL:          defaultEnrichmentCacheTTL,
+		},
+
+		SpamLabel: defaultSpamLabel,
+		LogLevel:  logLevelInfo,
+
+		RiskLogSummaryInterval: defaultRiskLogSummary,
 
-		return &Config{
-			RPCURL:        rpcURL,
-			Wallets:       wallets,
-			PollInterval:  pollInterval,
-			AIAnalyzerURL: aiAnalyzerURL,
-			DatabaseURL:   dbURL,
-		}, nil
+		MaxInputBytes: defaultMaxInputBytes,
+
+		PayloadNaming:       payloadNamingCamel,
+		AnalyzerAddressMode: addressModeFull,
+		AnalyzerConcurrency: defaultAnalyzerConcurrency,
+		AnalyzerTimeout:     defaultAnalyzerTimeout,
+		AnalysisRetryMaxAge: defaultAnalysisMaxAge,
+		PendingQueueFile:    defaultPendingQueueFile,
+
+		AnalyzerBreakerThreshold: defaultBreakerThreshold,
+		AnalyzerBreakerCooldown:  defaultBreakerCooldown,
+		AnalyzerBreakerAction:    breakerActionQueue,
+
+		WalletRefreshInterval: defaultWalletRefresh,
+		ENSRefreshInterval:    defaultENSRefresh,
+
+		SettingsRefreshInterval: defaultSettingsRefresh,
+
+		SeenCacheSize: defaultSeenCacheSize,
+		DedupWindow:   defaultDedupWindow,
+		StateBackend:  stateBackendFile,
+
+		BlockRetryAttempts: defaultBlockRetries,
+		OnBlockFailure:     blockFailureHalt,
+		ScanMode:           scanModeBlocks,
+
+		MaxReorgDepth: defaultMaxReorgDepth,
+		ReorgAction:   reorgActionHalt,
+
+		StallWindow: defaultStallWindow,
+
+		Direction: directionBoth,
+
+		HTTPAddr:            defaultHTTPAddr,
+		MaxRequestBodyBytes: routes.DefaultMaxBodyBytes,
+		IdempotencyTTL:      int(routes.DefaultIdempotencyTTL.Seconds()),
+	}
+}
+
+// loadConfig builds the effective config. Sources are layered, lowest to
+// highest precedence:
+//
+//  1. built-in defaults (defaultConfig)
+//  2. the YAML config file at path, if present
+//  3. variables from an optional .env file
+//  4. process environment variables
+//  5. command-line flags (applied by main via cliFlags.apply)
+//
+// Each layer only overrides the keys it actually sets, so a partial env
+// configuration (e.g. only DATABASE_URL) still inherits everything else from
+// the file. Variables in .env never override ones already in the environment.
+func loadConfig(path string) (*Config, error) {
+	if err := loadDotEnv(".env"); err != nil {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	cfg, err := loadConfigFromFile(path)
+	if os.IsNotExist(err) {
+		cfg, err = defaultConfig(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	applyEnv(cfg)
+	cfg.normalize()
+	return cfg, nil
+}
+
+// normalize canonicalizes values that are equivalent as written, so the rest
+// of the code can build on them directly.
+func (c *Config) normalize() {
+	c.normalizeAnalyzerURLs()
+
+	// Sources disagree on case and the 0x prefix; invalid entries are left
+	// as written for Validate to report
+	normalizeWallets(c.Wallets)
+	for i := range c.Chains {
+		normalizeWallets(c.Chains[i].Wallets)
+	}
+}
+
+// normalizeAnalyzerURLs folds AIAnalyzerURL into AnalyzerURLs, without
+// duplicates.
+func (c *Config) normalizeAnalyzerURLs() {
+	// Endpoints are joined onto the analyzer base; drop trailing slashes so
+	// "http://host/" and "http://host" behave the same
+	c.AIAnalyzerURL = strings.TrimRight(strings.TrimSpace(c.AIAnalyzerURL), "/")
+	urls := make([]string, 0, len(c.AnalyzerURLs)+1)
+	for _, u := range append([]string{c.AIAnalyzerURL}, c.AnalyzerURLs...) {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u != "" && !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
 	}
+	c.AnalyzerURLs = urls
+}
 
-	// Fall back to config file
-	return loadConfigFromFile("config.yaml")
+// normalizeWallets rewrites every valid address in wallets to canonical form,
+// and ENS names to lowercase.
+func normalizeWallets(wallets []string) {
+	for i, w := range wallets {
+		if canonical, ok := normalizeAddress(w); ok {
+			wallets[i] = canonical
+		} else if isENSName(w) {
+			wallets[i] = strings.ToLower(strings.TrimSpace(w))
+		}
+	}
 }
 
 func loadConfigFromFile(path string) (*Config, error) {
@@ -57,7 +425,549 @@ func loadConfigFromFile(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	var cfg Config
-	err = yaml.Unmarshal(data, &cfg)
-	return &cfg, err
+	// Defaults for keys the file may omit
+	cfg := defaultConfig()
+	err = yaml.Unmarshal(data, cfg)
+	return cfg, err
+}
+
+// applyEnv overlays every environment variable that is set onto cfg.
+func applyEnv(cfg *Config) {
+	envString(&cfg.RPCURL, "RPC_URL")
+	envList(&cfg.Wallets, "WALLETS")
+	envInt(&cfg.PollInterval, "POLL_INTERVAL")
+	envString(&cfg.AIAnalyzerURL, "AI_ANALYZER_URL")
+	envList(&cfg.AnalyzerURLs, "ANALYZER_URLS")
+	envString(&cfg.AnalyzerSigningSecret, "ANALYZER_SIGNING_SECRET")
+	envBool(&cfg.AnalyzerCompress, "ANALYZER_COMPRESS")
+	envString(&cfg.PayloadNaming, "PAYLOAD_NAMING")
+	envList(&cfg.AnalyzerFields, "ANALYZER_FIELDS")
+	envString(&cfg.AnalyzerAddressMode, "ANALYZER_ADDRESS_MODE")
+	envString(&cfg.DatabaseURL, "POSTGRES_DSN")
+	envString(&cfg.DatabaseURL, "DATABASE_URL")
+	envInt(&cfg.DBMaxConns, "DB_MAX_CONNS")
+	envInt(&cfg.DBMinConns, "DB_MIN_CONNS")
+	envInt(&cfg.DBMaxConnLifetime, "DB_MAX_CONN_LIFETIME")
+	envInt(&cfg.DBHealthCheckPeriod, "DB_HEALTH_CHECK_PERIOD")
+	envInt(&cfg.DBWriteBufferSize, "DB_WRITE_BUFFER_SIZE")
+	envString(&cfg.DBWriteBufferOverflow, "DB_WRITE_BUFFER_OVERFLOW")
+	envBool(&cfg.AutoMigrate, "AUTO_MIGRATE")
+	envString(&cfg.MigrationsDir, "MIGRATIONS_DIR")
+	envInt(&cfg.MaxBulkAddresses, "MAX_BULK_ADDRESSES")
+	// MONITOR_LABEL may be set to an empty string to watch every stored address
+	if ml, ok := os.LookupEnv("MONITOR_LABEL"); ok {
+		cfg.MonitorLabel = ml
+	}
+	envInt(&cfg.MaxBlocksPerBatch, "MAX_BLOCKS_PER_BATCH")
+	envInt(&cfg.Confirmations, "CONFIRMATIONS")
+	envInt(&cfg.StartupLookback, "STARTUP_LOOKBACK")
+
+	if u := os.Getenv("NOTIFY_WEBHOOK_URL"); u != "" {
+		cfg.Notifiers = append(cfg.Notifiers, NotifierConfig{Type: "webhook", URL: u})
+	}
+	if u := os.Getenv("NOTIFY_SLACK_WEBHOOK_URL"); u != "" {
+		cfg.Notifiers = append(cfg.Notifiers, NotifierConfig{Type: "slack", URL: u})
+	}
+	envFloat(&cfg.RiskThreshold, "RISK_THRESHOLD")
+	envString(&cfg.MinValueWei, "MIN_VALUE_WEI")
+	envFloat(&cfg.SampleRate, "SAMPLE_RATE")
+	envString(&cfg.SampleAboveWei, "SAMPLE_ABOVE_WEI")
+	envInt(&cfg.NotificationDigest.Window, "NOTIFY_DIGEST_WINDOW")
+	envFloat(&cfg.NotificationDigest.ImmediateThreshold, "NOTIFY_DIGEST_IMMEDIATE_THRESHOLD")
+	envInt(&cfg.NotificationDigest.TopCounterparties, "NOTIFY_DIGEST_TOP_COUNTERPARTIES")
+
+	envInt(&cfg.RPCMaxReconnects, "RPC_MAX_RECONNECTS")
+	envFloat(&cfg.RPCRequestsPerSecond, "RPC_REQUESTS_PER_SECOND")
+	envBool(&cfg.EnableTracing, "ENABLE_TRACING")
+	envBool(&cfg.SkipReverted, "SKIP_REVERTED")
+	envBool(&cfg.EnableERC721, "ENABLE_ERC721")
+	envBool(&cfg.EnableERC1155, "ENABLE_ERC1155")
+	envBool(&cfg.WatchMempool, "WATCH_MEMPOOL")
+	envBool(&cfg.DryRun, "DRY_RUN")
+	envBool(&cfg.ScanCalldataForAddresses, "SCAN_CALLDATA_FOR_ADDRESSES")
+	envBool(&cfg.RulesOnly, "RULES_ONLY")
+	envList(&cfg.SpamTokens, "SPAM_TOKENS")
+	envString(&cfg.SpamLabel, "SPAM_LABEL")
+	envString(&cfg.AutoLabelOnRisk.Label, "AUTO_LABEL_ON_RISK")
+	envFloat(&cfg.AutoLabelOnRisk.MinScore, "AUTO_LABEL_MIN_SCORE")
+	envBool(&cfg.SpamHeuristic, "SPAM_HEURISTIC")
+	envInt(&cfg.BlockRetryAttempts, "BLOCK_RETRY_ATTEMPTS")
+	envString(&cfg.OnBlockFailure, "ON_BLOCK_FAILURE")
+	envInt(&cfg.MaxReorgDepth, "MAX_REORG_DEPTH")
+	envString(&cfg.ReorgAction, "REORG_ACTION")
+	envString(&cfg.ScanMode, "SCAN_MODE")
+	envInt(&cfg.StallWindow, "STALL_WINDOW")
+	envString(&cfg.Direction, "DIRECTION")
+	envString(&cfg.PriceFeedType, "PRICE_FEED_TYPE")
+	envString(&cfg.PriceFeedURL, "PRICE_FEED_URL")
+	envString(&cfg.PriceFeedAsset, "PRICE_FEED_ASSET")
+	envString(&cfg.Enrichment.URL, "ENRICHMENT_URL")
+	envString(&cfg.Enrichment.APIKey, "ENRICHMENT_API_KEY")
+	envFloat(&cfg.Enrichment.RequestsPerSecond, "ENRICHMENT_REQUESTS_PER_SECOND")
+	envInt(&cfg.Enrichment.CacheTTL, "ENRICHMENT_CACHE_TTL")
+	envInt(&cfg.PriceCacheSeconds, "PRICE_CACHE_SECONDS")
+	// HTTP_ADDR may be set to an empty string to run without the HTTP API
+	if addr, ok := os.LookupEnv("HTTP_ADDR"); ok {
+		cfg.HTTPAddr = addr
+	}
+	envString(&cfg.TLSCert, "TLS_CERT")
+	envString(&cfg.TLSKey, "TLS_KEY")
+	envList(&cfg.APIKeys, "API_KEYS")
+	envList(&cfg.AllowedLabels, "ALLOWED_LABELS")
+	envList(&cfg.WriteAllowlist, "WRITE_ALLOWLIST")
+	envList(&cfg.CORSAllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	envList(&cfg.CORSAllowedMethods, "CORS_ALLOWED_METHODS")
+	envList(&cfg.CORSAllowedHeaders, "CORS_ALLOWED_HEADERS")
+	envInt(&cfg.MaxRequestBodyBytes, "MAX_REQUEST_BODY_BYTES")
+	envInt(&cfg.IdempotencyTTL, "IDEMPOTENCY_TTL")
+	envInt(&cfg.MaxInputBytes, "MAX_INPUT_BYTES")
+	envBool(&cfg.AuditBlocks, "AUDIT_BLOCKS")
+	envString(&cfg.AuditFile, "AUDIT_FILE")
+	envString(&cfg.LogLevel, "LOG_LEVEL")
+	envInt(&cfg.RiskLogSummaryInterval, "RISK_LOG_SUMMARY_INTERVAL")
+	envString(&cfg.Archive.Type, "ARCHIVE_TYPE")
+	envString(&cfg.Archive.Format, "ARCHIVE_FORMAT")
+	envString(&cfg.Archive.Dir, "ARCHIVE_DIR")
+	envString(&cfg.Archive.Endpoint, "ARCHIVE_S3_ENDPOINT")
+	envString(&cfg.Archive.Region, "ARCHIVE_S3_REGION")
+	envString(&cfg.Archive.Bucket, "ARCHIVE_S3_BUCKET")
+	envString(&cfg.Archive.Prefix, "ARCHIVE_S3_PREFIX")
+	envString(&cfg.Archive.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	envString(&cfg.Archive.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	envInt(&cfg.AnalyzerConcurrency, "ANALYZER_CONCURRENCY")
+	envInt(&cfg.AnalyzerTimeout, "ANALYZER_TIMEOUT")
+	envInt(&cfg.AnalysisRetryMaxAge, "ANALYSIS_RETRY_MAX_AGE")
+	envString(&cfg.PendingQueueFile, "PENDING_QUEUE_FILE")
+	envInt(&cfg.AnalyzerBreakerThreshold, "ANALYZER_BREAKER_THRESHOLD")
+	envInt(&cfg.AnalyzerBreakerCooldown, "ANALYZER_BREAKER_COOLDOWN")
+	envString(&cfg.AnalyzerBreakerAction, "ANALYZER_BREAKER_ACTION")
+	envInt(&cfg.WalletRefreshInterval, "WALLET_REFRESH_INTERVAL")
+	envInt(&cfg.ENSRefreshInterval, "ENS_REFRESH_INTERVAL")
+	envInt(&cfg.SettingsRefreshInterval, "SETTINGS_REFRESH_INTERVAL")
+	envInt(&cfg.SeenCacheSize, "SEEN_CACHE_SIZE")
+	envInt(&cfg.DedupWindow, "DEDUP_WINDOW")
+	envString(&cfg.StateBackend, "STATE_BACKEND")
+	envString(&cfg.RedisURL, "REDIS_URL")
+}
+
+// envString sets dst from a non-empty environment variable.
+func envString(dst *string, key string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+// envInt sets dst from an integer environment variable, ignoring invalid values.
+func envInt(dst *int, key string) {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			*dst = n
+		}
+	}
+}
+
+// envFloat sets dst from a float environment variable, ignoring invalid values.
+func envFloat(dst *float64, key string) {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			*dst = f
+		}
+	}
+}
+
+// envBool sets dst from a boolean environment variable, ignoring invalid values.
+func envBool(dst *bool, key string) {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			*dst = b
+		}
+	}
+}
+
+// envList sets dst from a comma-separated environment variable, dropping blank entries.
+func envList(dst *[]string, key string) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	*dst = out
+}
+
+// Validate checks the config for common misconfigurations and reports every
+// problem at once so operators can fix them in a single pass.
+func (c *Config) Validate() error {
+	var problems []string
+
+	if len(c.Chains) == 0 && c.RPCURL == "" {
+		problems = append(problems, "rpc_url (RPC_URL) is required")
+	} else if c.RPCURL != "" {
+		if err := checkURL(c.RPCURL, "http", "https", "ws", "wss"); err != nil {
+			problems = append(problems, fmt.Sprintf("rpc_url %q: %v", c.RPCURL, err))
+		}
+		if len(c.Chains) > 0 {
+			problems = append(problems, "rpc_url (RPC_URL) is ignored when chains are configured; set rpc_url on each chain instead")
+		}
+	}
+
+	for i, w := range c.Wallets {
+		if !common.IsHexAddress(w) && !isENSName(w) {
+			problems = append(problems, fmt.Sprintf("wallets[%d] %q is not a valid hex address or ENS name", i, w))
+		}
+	}
+
+	for i, a := range c.SpamTokens {
+		if !common.IsHexAddress(a) {
+			problems = append(problems, fmt.Sprintf("spam_tokens[%d] %q is not a valid hex address", i, a))
+		}
+	}
+	if c.LogLevel != logLevelInfo && c.LogLevel != logLevelDebug {
+		problems = append(problems, fmt.Sprintf("log_level must be %q or %q, got %q", logLevelInfo, logLevelDebug, c.LogLevel))
+	}
+	if c.MaxBlocksPerBatch < 0 {
+		problems = append(problems, fmt.Sprintf("max_blocks_per_batch must not be negative, got %d", c.MaxBlocksPerBatch))
+	}
+	if c.BlockRetryAttempts < 0 {
+		problems = append(problems, fmt.Sprintf("block_retry_attempts must not be negative, got %d", c.BlockRetryAttempts))
+	}
+	if c.RiskLogSummaryInterval < 0 {
+		problems = append(problems, fmt.Sprintf("risk_log_summary_interval must not be negative, got %d", c.RiskLogSummaryInterval))
+	}
+
+	for addr := range c.ContractABIs {
+		if !common.IsHexAddress(addr) {
+			problems = append(problems, fmt.Sprintf("contract_abis key %q is not a valid hex address", addr))
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, ch := range c.Chains {
+		if ch.Name == "" {
+			problems = append(problems, fmt.Sprintf("chains[%d]: name is required", i))
+		} else if names[ch.Name] {
+			problems = append(problems, fmt.Sprintf("chains[%d]: duplicate name %q", i, ch.Name))
+		}
+		names[ch.Name] = true
+		switch ch.Signer {
+		case "", signerLatest, signerEIP155, signerHomestead, signerFrontier:
+		default:
+			problems = append(problems, fmt.Sprintf("chains[%d]: signer must be %s, %s, %s or %s, got %q", i, signerLatest, signerEIP155, signerHomestead, signerFrontier, ch.Signer))
+		}
+		if err := checkURL(ch.RPCURL, "http", "https", "ws", "wss"); err != nil {
+			problems = append(problems, fmt.Sprintf("chains[%d] rpc_url %q: %v", i, ch.RPCURL, err))
+		}
+		for j, w := range ch.Wallets {
+			if !common.IsHexAddress(w) && !isENSName(w) {
+				problems = append(problems, fmt.Sprintf("chains[%d].wallets[%d] %q is not a valid hex address or ENS name", i, j, w))
+			}
+		}
+	}
+
+	if err := (dbpkg.WalletSettings{MinValueWei: c.MinValueWei}).Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		problems = append(problems, fmt.Sprintf("sample_rate must be between 0 and 1, got %v", c.SampleRate))
+	}
+	if _, err := parseWei(c.SampleAboveWei); err != nil {
+		problems = append(problems, fmt.Sprintf("sample_above_wei: %v", err))
+	}
+
+	if c.NotificationDigest.Window < 0 {
+		problems = append(problems, "notification_digest.window must not be negative")
+	}
+	if t := c.NotificationDigest.ImmediateThreshold; t < 0 || t > 1 {
+		problems = append(problems, "notification_digest.immediate_threshold must be between 0 and 1")
+	}
+	if c.StallWindow < 0 {
+		problems = append(problems, "stall_window must not be negative")
+	}
+	if c.Confirmations < 0 {
+		problems = append(problems, "confirmations must not be negative")
+	}
+	if len(c.AllowedLabels) > 0 {
+		// Otherwise nothing could ever be tagged for monitoring
+		monitorLabels := []string{c.MonitorLabel}
+		for _, ch := range c.Chains {
+			if ch.MonitorLabel != nil {
+				monitorLabels = append(monitorLabels, *ch.MonitorLabel)
+			}
+		}
+		for _, l := range monitorLabels {
+			if l != "" && !slices.Contains(c.AllowedLabels, l) {
+				problems = append(problems, fmt.Sprintf("monitor label %q is not in allowed_labels", l))
+			}
+		}
+	}
+	if l := c.AutoLabelOnRisk.Label; l != "" {
+		if strings.TrimSpace(l) != l {
+			problems = append(problems, fmt.Sprintf("auto_label_on_risk.label %q must not have surrounding spaces", l))
+		}
+		if len(c.AllowedLabels) > 0 && !slices.Contains(c.AllowedLabels, l) {
+			problems = append(problems, fmt.Sprintf("auto_label_on_risk.label %q is not in allowed_labels", l))
+		}
+		if c.DatabaseURL == "" {
+			problems = append(problems, "auto_label_on_risk requires database_url")
+		}
+	}
+	if s := c.AutoLabelOnRisk.MinScore; s < 0 || s > 1 {
+		problems = append(problems, fmt.Sprintf("auto_label_on_risk.min_score must be between 0 and 1, got %v", s))
+	}
+	if _, err := routes.ParseCIDRs(c.WriteAllowlist); err != nil {
+		problems = append(problems, "write_allowlist: "+err.Error())
+	}
+	if c.HTTPAddr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPAddr); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid http_addr %q: %v", c.HTTPAddr, err))
+		}
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		problems = append(problems, "tls_cert and tls_key must be set together")
+	} else if c.TLSCert != "" {
+		if _, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey); err != nil {
+			problems = append(problems, fmt.Sprintf("tls_cert/tls_key: %v", err))
+		}
+	}
+	if c.RPCMaxReconnects < 1 {
+		problems = append(problems, "rpc_max_reconnects must be at least 1")
+	}
+	if c.MaxInputBytes < 0 {
+		problems = append(problems, "max_input_bytes must not be negative")
+	}
+	switch c.Archive.Type {
+	case "":
+	case "file":
+		if c.Archive.Dir == "" {
+			problems = append(problems, "archive type file requires archive.dir")
+		}
+	case "s3":
+		if err := checkURL(c.Archive.Endpoint, "http", "https"); err != nil {
+			problems = append(problems, fmt.Sprintf("archive.endpoint %q: %v", c.Archive.Endpoint, err))
+		}
+		if c.Archive.Bucket == "" || c.Archive.Region == "" {
+			problems = append(problems, "archive type s3 requires archive.bucket and archive.region")
+		}
+		if c.Archive.AccessKeyID == "" || c.Archive.SecretAccessKey == "" {
+			problems = append(problems, "archive type s3 requires credentials (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY)")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("archive.type must be file or s3, got %q", c.Archive.Type))
+	}
+	switch c.Archive.Format {
+	case "", archiveFormatJSON, archiveFormatRLP:
+	default:
+		problems = append(problems, fmt.Sprintf("archive.format must be json or rlp, got %q", c.Archive.Format))
+	}
+	if c.PayloadNaming != payloadNamingCamel && c.PayloadNaming != payloadNamingSnake {
+		problems = append(problems, fmt.Sprintf("payload_naming must be camel or snake, got %q", c.PayloadNaming))
+	}
+	known := payloadFields()
+	for _, f := range c.AnalyzerFields {
+		if !known[f] {
+			problems = append(problems, fmt.Sprintf("analyzer_fields: unknown field %q", f))
+		}
+	}
+	switch c.AnalyzerAddressMode {
+	case addressModeFull, addressModeHash, addressModeTruncate:
+	default:
+		problems = append(problems, fmt.Sprintf("analyzer_address_mode must be %s, %s or %s, got %q", addressModeFull, addressModeHash, addressModeTruncate, c.AnalyzerAddressMode))
+	}
+	if c.AnalyzerConcurrency < 1 {
+		problems = append(problems, "analyzer_concurrency must be at least 1")
+	}
+	if c.AnalyzerTimeout < 1 {
+		problems = append(problems, "analyzer_timeout must be at least 1 second")
+	}
+	if c.AnalyzerBreakerThreshold < 0 {
+		problems = append(problems, "analyzer_breaker_threshold must not be negative")
+	}
+	if c.AnalyzerBreakerThreshold > 0 && c.AnalyzerBreakerCooldown < 1 {
+		problems = append(problems, "analyzer_breaker_cooldown must be at least 1 second")
+	}
+	switch c.AnalyzerBreakerAction {
+	case breakerActionQueue, breakerActionDrop:
+	default:
+		problems = append(problems, fmt.Sprintf("analyzer_breaker_action must be %s or %s, got %q", breakerActionQueue, breakerActionDrop, c.AnalyzerBreakerAction))
+	}
+	if c.SeenCacheSize < 0 {
+		problems = append(problems, "seen_cache_size must not be negative")
+	}
+	switch c.StateBackend {
+	case stateBackendFile:
+	case stateBackendPostgres:
+		if c.DatabaseURL == "" {
+			problems = append(problems, "state_backend postgres requires database_url")
+		}
+	case stateBackendRedis:
+		if c.RedisURL == "" {
+			problems = append(problems, "state_backend redis requires redis_url")
+		} else if _, err := newRedisClient(c.RedisURL); err != nil {
+			problems = append(problems, err.Error())
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("state_backend must be %q, %q or %q", stateBackendFile, stateBackendPostgres, stateBackendRedis))
+	}
+
+	if c.DBMaxConns > 0 && c.DBMinConns > c.DBMaxConns {
+		problems = append(problems, fmt.Sprintf("db_min_conns (%d) must not exceed db_max_conns (%d)", c.DBMinConns, c.DBMaxConns))
+	}
+	if c.DBWriteBufferSize < 0 {
+		problems = append(problems, fmt.Sprintf("db_write_buffer_size must not be negative, got %d", c.DBWriteBufferSize))
+	}
+	if c.DBWriteBufferOverflow != overflowDropOldest && c.DBWriteBufferOverflow != overflowDropNewest {
+		problems = append(problems, fmt.Sprintf("db_write_buffer_overflow must be %q or %q, got %q", overflowDropOldest, overflowDropNewest, c.DBWriteBufferOverflow))
+	}
+
+	switch c.Direction {
+	case directionBoth, directionIncoming, directionOutgoing:
+	default:
+		problems = append(problems, fmt.Sprintf("direction must be %q, %q or %q, got %q", directionBoth, directionIncoming, directionOutgoing, c.Direction))
+	}
+
+	if c.Enrichment.URL != "" {
+		if err := checkURL(c.Enrichment.URL, "http", "https"); err != nil {
+			problems = append(problems, fmt.Sprintf("enrichment.url %q: %v", c.Enrichment.URL, err))
+		}
+		if c.Enrichment.RequestsPerSecond <= 0 {
+			problems = append(problems, "enrichment.requests_per_second must be positive")
+		}
+		if c.Enrichment.CacheTTL < 0 {
+			problems = append(problems, "enrichment.cache_ttl must not be negative")
+		}
+	}
+
+	switch strings.ToLower(c.PriceFeedType) {
+	case "", "coingecko":
+		if c.PriceFeedURL != "" {
+			if err := checkURL(c.PriceFeedURL, "http", "https"); err != nil {
+				problems = append(problems, fmt.Sprintf("price_feed_url: %v", err))
+			}
+		}
+	case "chainlink":
+		if !common.IsHexAddress(c.PriceFeedAsset) {
+			problems = append(problems, "price_feed_asset must be the Chainlink aggregator address")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("price_feed_type %q is not supported (use coingecko or chainlink)", c.PriceFeedType))
+	}
+
+	if c.OnBlockFailure != blockFailureHalt && c.OnBlockFailure != blockFailureSkip {
+		problems = append(problems, fmt.Sprintf("on_block_failure must be %q or %q, got %q", blockFailureHalt, blockFailureSkip, c.OnBlockFailure))
+	}
+	if c.MaxReorgDepth < 1 {
+		problems = append(problems, "max_reorg_depth must be at least 1")
+	}
+	if c.ReorgAction != reorgActionHalt && c.ReorgAction != reorgActionResync {
+		problems = append(problems, fmt.Sprintf("reorg_action must be %q or %q, got %q", reorgActionHalt, reorgActionResync, c.ReorgAction))
+	}
+	switch c.ScanMode {
+	case scanModeBlocks:
+	case scanModeLogs:
+		if !c.EnableERC721 && !c.EnableERC1155 {
+			problems = append(problems, "scan_mode logs needs enable_erc721 or enable_erc1155")
+		}
+		if c.ScanCalldataForAddresses {
+			problems = append(problems, "scan_calldata_for_addresses needs scan_mode blocks")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("scan_mode must be %q or %q, got %q", scanModeBlocks, scanModeLogs, c.ScanMode))
+	}
+
+	if c.ToBlock != nil {
+		if c.FromBlock == nil {
+			problems = append(problems, "from_block is required when to_block is set")
+		} else if *c.FromBlock > *c.ToBlock {
+			problems = append(problems, fmt.Sprintf("from_block %d is after to_block %d", *c.FromBlock, *c.ToBlock))
+		}
+	}
+
+	if c.PollInterval <= 0 {
+		problems = append(problems, fmt.Sprintf("poll_interval must be positive, got %d", c.PollInterval))
+	}
+
+	for _, u := range c.AnalyzerURLs {
+		if err := checkURL(u, "http", "https"); err != nil {
+			problems = append(problems, fmt.Sprintf("analyzer url %q: %v", u, err))
+		}
+	}
+
+	if c.ENSRefreshInterval < 0 {
+		problems = append(problems, fmt.Sprintf("ens_refresh_interval must not be negative, got %d", c.ENSRefreshInterval))
+	}
+	if c.SettingsRefreshInterval < 1 {
+		problems = append(problems, fmt.Sprintf("settings_refresh_interval must be at least 1, got %d", c.SettingsRefreshInterval))
+	}
+
+	if _, err := compileConfirmationTiers(c.ConfirmationTiers); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if _, err := compileRules(c.Rules); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.RulesOnly && len(c.Rules) == 0 {
+		problems = append(problems, "rules_only is set but no rules are configured")
+	}
+
+	for i, s := range c.Sinks {
+		switch s.Type {
+		case sinkStdout, sinkDatabase:
+		case sinkFile:
+			if s.Path == "" {
+				problems = append(problems, fmt.Sprintf("sinks[%d]: path is required for the file sink", i))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("sinks[%d]: unknown type %q (want %s, %s or %s)", i, s.Type, sinkStdout, sinkFile, sinkDatabase))
+		}
+		if s.MaxSizeMB < 0 || s.MaxBackups < 0 {
+			problems = append(problems, fmt.Sprintf("sinks[%d]: max_size_mb and max_backups must not be negative", i))
+		}
+	}
+
+	for i, n := range c.Notifiers {
+		if n.Type != "webhook" && n.Type != "slack" {
+			problems = append(problems, fmt.Sprintf("notifiers[%d]: unknown type %q (want webhook or slack)", i, n.Type))
+		}
+		if err := checkURL(n.URL, "http", "https"); err != nil {
+			problems = append(problems, fmt.Sprintf("notifiers[%d] url %q: %v", i, n.URL, err))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// checkURL verifies raw is an absolute URL with a host and one of the given schemes.
+func checkURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("scheme must be one of %s", strings.Join(schemes, ", "))
+}
+
+// sampleAbove returns SampleAboveWei parsed, or nil when unset or invalid.
+func (c *Config) sampleAbove() *big.Int {
+	v, _ := parseWei(c.SampleAboveWei)
+	return v
+}
+
+// minValue returns MinValueWei parsed, or nil when unset.
+func (c *Config) minValue() *big.Int {
+	return dbpkg.WalletSettings{MinValueWei: c.live().MinValueWei}.MinValue()
 }
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+// clearEnv unsets keys for the test and restores them afterwards, including
+// values loadDotEnv sets behind the test's back.
+func clearEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigLayering(t *testing.T) {
+	const yamlFile = `
+rpc_url: http://file:8545
+poll_interval: 20
+risk_threshold: 0.5
+max_blocks_per_batch: 120
+`
+	tests := []struct {
+		name   string
+		yaml   string // config.yaml contents; empty writes no file
+		dotEnv string // .env contents; empty writes no file
+		env    map[string]string
+		flags  cliFlags
+
+		wantRPC   string
+		wantPoll  int
+		wantRisk  float64
+		wantBatch int
+	}{
+		{
+			name:     "defaults only",
+			wantPoll: 15, wantRisk: defaultRiskThreshold, wantBatch: defaultMaxBlocksPerBatch,
+		},
+		{
+			name:    "file only",
+			yaml:    yamlFile,
+			wantRPC: "http://file:8545", wantPoll: 20, wantRisk: 0.5, wantBatch: 120,
+		},
+		{
+			name:    "env overrides file",
+			yaml:    yamlFile,
+			env:     map[string]string{"POLL_INTERVAL": "30", "RPC_URL": "http://env:8545"},
+			wantRPC: "http://env:8545", wantPoll: 30, wantRisk: 0.5, wantBatch: 120,
+		},
+		{
+			name:    ".env overrides file",
+			yaml:    yamlFile,
+			dotEnv:  "POLL_INTERVAL=25\nRISK_THRESHOLD=0.6\n",
+			wantRPC: "http://file:8545", wantPoll: 25, wantRisk: 0.6, wantBatch: 120,
+		},
+		{
+			name:    "env overrides .env",
+			yaml:    yamlFile,
+			dotEnv:  "POLL_INTERVAL=25\nRISK_THRESHOLD=0.6\n",
+			env:     map[string]string{"POLL_INTERVAL": "30"},
+			wantRPC: "http://file:8545", wantPoll: 30, wantRisk: 0.6, wantBatch: 120,
+		},
+		{
+			name:    "flags override everything",
+			yaml:    yamlFile,
+			dotEnv:  "POLL_INTERVAL=25\n",
+			env:     map[string]string{"POLL_INTERVAL": "30", "RPC_URL": "http://env:8545"},
+			flags:   cliFlags{pollInterval: 40, rpcURL: "http://flag:8545", fromBlock: -1},
+			wantRPC: "http://flag:8545", wantPoll: 40, wantRisk: 0.5, wantBatch: 120,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			clearEnv(t, "RPC_URL", "POLL_INTERVAL", "RISK_THRESHOLD", "MAX_BLOCKS_PER_BATCH", "WALLET_REFRESH_INTERVAL")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if tt.yaml != "" {
+				writeFile(t, filepath.Join(dir, "config.yaml"), tt.yaml)
+			}
+			if tt.dotEnv != "" {
+				writeFile(t, filepath.Join(dir, ".env"), tt.dotEnv)
+			}
+
+			cfg, err := loadConfig(filepath.Join(dir, "config.yaml"))
+			if err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+			if tt.flags != (cliFlags{}) {
+				tt.flags.apply(cfg)
+			}
+
+			if cfg.RPCURL != tt.wantRPC {
+				t.Errorf("rpc_url = %q, want %q", cfg.RPCURL, tt.wantRPC)
+			}
+			if cfg.PollInterval != tt.wantPoll {
+				t.Errorf("poll_interval = %d, want %d", cfg.PollInterval, tt.wantPoll)
+			}
+			if cfg.RiskThreshold != tt.wantRisk {
+				t.Errorf("risk_threshold = %v, want %v", cfg.RiskThreshold, tt.wantRisk)
+			}
+			if cfg.MaxBlocksPerBatch != tt.wantBatch {
+				t.Errorf("max_blocks_per_batch = %d, want %d", cfg.MaxBlocksPerBatch, tt.wantBatch)
+			}
+			if cfg.WalletRefreshInterval != defaultWalletRefresh {
+				t.Errorf("wallet_refresh_interval = %d, want the default %d", cfg.WalletRefreshInterval, defaultWalletRefresh)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsMalformedDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFile(t, filepath.Join(dir, ".env"), "NOT A PAIR\n")
+	if _, err := loadConfig(filepath.Join(dir, "config.yaml")); err == nil {
+		t.Fatal("loadConfig accepted a malformed .env")
+	}
+}
+
+func TestNormalizeWallets(t *testing.T) {
+	const canonical = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"checksummed", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", canonical},
+		{"lower case", canonical, canonical},
+		{"upper case", "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", canonical},
+		{"no prefix", "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", canonical},
+		{"upper-case prefix", "0X5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", canonical},
+		{"surrounding space", "  0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed\n", canonical},
+		{"ens name", "Vitalik.ETH", "vitalik.eth"},
+		{"invalid left for Validate", "0x1234", "0x1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallets := []string{tt.in}
+			normalizeWallets(wallets)
+			if wallets[0] != tt.want {
+				t.Errorf("normalizeWallets(%q) = %q, want %q", tt.in, wallets[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestWalletCacheMatchesUnnormalizedAddresses(t *testing.T) {
+	want := common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{"checksummed", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{"lower case without prefix", "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{"upper case without prefix", "5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := newWalletCache(nil, "", []string{tt.address, "not-an-address"}, time.Minute).Set(context.Background())
+			if !set[want] || len(set) != 1 {
+				t.Errorf("wallet set = %v, want only %s", set, want.Hex())
+			}
+		})
+	}
+}
+
+// flakyStore serves wallets until failing is set, counting fetches.
+type flakyStore struct {
+	dbpkg.Store
+	failing bool
+	fetches int
+}
+
+func (f *flakyStore) FetchMonitoredWallets(ctx context.Context, label string) ([]dbpkg.MonitoredWallet, error) {
+	f.fetches++
+	if f.failing {
+		return nil, errors.New("connection refused")
+	}
+	return []dbpkg.MonitoredWallet{{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}}, nil
+}
+
+func TestWalletCacheBacksOffAfterStoreErrors(t *testing.T) {
+	store := &flakyStore{}
+	// A zero interval reloads on every call while the store answers
+	cache := newWalletCache(store, "monitor", nil, 0)
+	if len(cache.Set(context.Background())) != 1 {
+		t.Fatal("first load did not return the stored wallet")
+	}
+
+	store.failing = true
+	for i := 0; i < 3; i++ {
+		if len(cache.Set(context.Background())) != 1 {
+			t.Fatal("last good list not served through the store error")
+		}
+	}
+	if store.fetches != 2 {
+		t.Errorf("store fetched %d times, want 2: failed reloads must wait cacheRetryWait", store.fetches)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"negative batch size", func(c *Config) { c.MaxBlocksPerBatch = -1 }, "max_blocks_per_batch"},
+		{"negative retries", func(c *Config) { c.BlockRetryAttempts = -1 }, "block_retry_attempts"},
+		{"rpc_url beside chains", func(c *Config) {
+			c.Chains = []ChainConfig{{Name: "base", RPCURL: "http://base:8545"}}
+		}, "rpc_url (RPC_URL) is ignored"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.RPCURL = "http://localhost:8545"
+			tt.modify(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error about %s", err, tt.want)
+			}
+		})
+	}
+}
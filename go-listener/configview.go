@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"gopkg.in/yaml.v2"
+)
+
+// redactedValue replaces every secret in the effective configuration.
+const redactedValue = "[redacted]"
+
+// redacted returns a copy of c that is safe to show: keys and secrets are
+// replaced whole, and URLs keep only their scheme and host, since RPC and
+// webhook providers commonly put tokens in the path or query. Database URLs
+// also keep their path, which names the database or SQLite file.
+func (c *Config) redacted() *Config {
+	out := *c
+	out.RPCURL = redactURL(c.RPCURL, false)
+	out.AIAnalyzerURL = redactURL(c.AIAnalyzerURL, false)
+	out.AnalyzerURLs = make([]string, len(c.AnalyzerURLs))
+	for i, u := range c.AnalyzerURLs {
+		out.AnalyzerURLs[i] = redactURL(u, false)
+	}
+	out.DatabaseURL = redactURL(c.DatabaseURL, true)
+	out.RedisURL = redactURL(c.RedisURL, false)
+	out.PriceFeedURL = redactURL(c.PriceFeedURL, false)
+	out.Enrichment.URL = redactURL(c.Enrichment.URL, false)
+	out.Enrichment.APIKey = redactSecret(c.Enrichment.APIKey)
+	out.AnalyzerSigningSecret = redactSecret(c.AnalyzerSigningSecret)
+
+	out.APIKeys = make([]string, len(c.APIKeys))
+	for i, k := range c.APIKeys {
+		out.APIKeys[i] = redactSecret(k)
+	}
+	out.Notifiers = make([]NotifierConfig, len(c.Notifiers))
+	for i, n := range c.Notifiers {
+		n.URL = redactURL(n.URL, false)
+		out.Notifiers[i] = n
+	}
+	out.Chains = make([]ChainConfig, len(c.Chains))
+	for i, ch := range c.Chains {
+		ch.RPCURL = redactURL(ch.RPCURL, false)
+		out.Chains[i] = ch
+	}
+
+	out.Archive.Endpoint = redactURL(c.Archive.Endpoint, false)
+	out.Archive.AccessKeyID = redactSecret(c.Archive.AccessKeyID)
+	out.Archive.SecretAccessKey = redactSecret(c.Archive.SecretAccessKey)
+	return &out
+}
+
+// effectiveConfig renders the redacted config keyed by its YAML names, so
+// GET /config reads like the config file.
+func (c *Config) effectiveConfig() (interface{}, error) {
+	data, err := yaml.Marshal(c.redacted())
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return jsonCompatible(doc), nil
+}
+
+// jsonCompatible converts the map[interface{}]interface{} values yaml.v2
+// produces into string-keyed maps encoding/json accepts.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[fmt.Sprint(k)] = jsonCompatible(val)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonCompatible(v[i])
+		}
+	}
+	return v
+}
+
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL keeps the scheme and host of raw, plus the path when keepPath is
+// set. Credentials, the query and any other path are replaced. Anything that
+// does not parse as an absolute URL, such as a key=value Postgres DSN, is
+// redacted whole.
+func redactURL(raw string, keepPath bool) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		return redactedValue
+	}
+	out := u.Scheme + "://"
+	if u.User != nil {
+		// A bare username is often an API token, so it goes too
+		out += redactedValue + "@"
+	}
+	out += u.Host
+	switch {
+	case keepPath:
+		out += u.EscapedPath()
+	case u.Path != "" && u.Path != "/":
+		out += "/" + redactedValue
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		out += "?" + redactedValue
+	}
+	return out
+}
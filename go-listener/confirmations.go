@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+// ConfirmationTier holds back alerts for transfers of at least MinValueWei
+// until their block is Confirmations deep, so a reorg cannot produce a false
+// high-value alert. Transfers below every tier alert at the base
+// confirmations depth.
+type ConfirmationTier struct {
+	MinValueWei   string `yaml:"min_value_wei"`
+	Confirmations int    `yaml:"confirmations"`
+}
+
+type confirmationTier struct {
+	minValue      *big.Int
+	confirmations uint64
+}
+
+// compileConfirmationTiers parses tiers, reporting every invalid one at once.
+func compileConfirmationTiers(tiers []ConfirmationTier) ([]confirmationTier, error) {
+	out := make([]confirmationTier, 0, len(tiers))
+	var problems []string
+	for i, t := range tiers {
+		min, err := parseWei(t.MinValueWei)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("confirmation_tiers[%d] min_value_wei: %v", i, err))
+		case min == nil:
+			problems = append(problems, fmt.Sprintf("confirmation_tiers[%d]: min_value_wei is required", i))
+		}
+		if t.Confirmations < 0 {
+			problems = append(problems, fmt.Sprintf("confirmation_tiers[%d]: confirmations must not be negative, got %d", i, t.Confirmations))
+		}
+		out = append(out, confirmationTier{minValue: min, confirmations: uint64(t.Confirmations)})
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return out, nil
+}
+
+var heldAlertsGauge = metrics.NewGaugeVec("blocksentinel_held_alerts", "Matched transactions waiting for their confirmation tier.", "chain")
+
+// heldMatch is a matched transaction waiting for its block to reach depth.
+type heldMatch struct {
+	block *types.Block
+	m     matchedTx
+	depth uint64
+}
+
+// heldMatches buffers matches whose tier needs more confirmations than the
+// scanner waits for. It lives in memory only; the scan position saved to the
+// state store stays behind the oldest held block, so a restart re-scans from
+// there rather than losing it.
+type heldMatches struct {
+	mu    sync.Mutex
+	head  uint64 // chain head at the last poll; 0 until the first
+	items []heldMatch
+}
+
+// requiredConfirmations returns the depth a transfer of value must reach
+// before it alerts.
+func (s *Scanner) requiredConfirmations(value *big.Int) uint64 {
+	depth := uint64(s.cfg.Confirmations)
+	for _, t := range s.tiers {
+		if value.Cmp(t.minValue) >= 0 && t.confirmations > depth {
+			depth = t.confirmations
+		}
+	}
+	return depth
+}
+
+// holdUntilConfirmed buffers m and reports true when its tier needs block to
+// be deeper than it is. Until the head has been read once nothing is held.
+func (s *Scanner) holdUntilConfirmed(block *types.Block, m matchedTx) bool {
+	if len(s.tiers) == 0 {
+		return false
+	}
+	depth := s.requiredConfirmations(m.tx.Value())
+	s.held.mu.Lock()
+	defer s.held.mu.Unlock()
+	head := s.held.head
+	if head == 0 || head < block.NumberU64() || head-block.NumberU64() >= depth {
+		return false
+	}
+	s.held.items = append(s.held.items, heldMatch{block: block, m: m, depth: depth})
+	heldAlertsGauge.With(s.chain.Name).Set(float64(len(s.held.items)))
+	s.printf("⏳ Holding %s (value %s wei) until block %d has %d confirmations\n", m.tx.Hash().Hex(), m.tx.Value(), block.NumberU64(), depth)
+	return true
+}
+
+// releaseConfirmed records head and processes every held match that is now
+// deep enough, after checking it is still included where it was. Matches
+// reorged into another block wait out their tier again from there; those
+// dropped from the chain are discarded.
+func (s *Scanner) releaseConfirmed(ctx context.Context, head uint64, walletSet map[common.Address]bool) {
+	s.held.mu.Lock()
+	s.held.head = head
+	var due, waiting []heldMatch
+	for _, h := range s.held.items {
+		if head >= h.block.NumberU64() && head-h.block.NumberU64() >= h.depth {
+			due = append(due, h)
+		} else {
+			waiting = append(waiting, h)
+		}
+	}
+	s.held.items = waiting
+	s.held.mu.Unlock()
+
+	var retry []heldMatch
+	for _, h := range due {
+		hash := h.m.tx.Hash()
+		receipt, err := s.client.TransactionReceipt(ctx, hash)
+		if errors.Is(err, ethereum.NotFound) {
+			s.printf("🔀 Dropping held transaction %s: no longer on chain after a reorg\n", hash.Hex())
+			continue
+		}
+		if err != nil {
+			s.logf("Error confirming held transaction %s: %v", hash.Hex(), err)
+			retry = append(retry, h)
+			continue
+		}
+		if receipt.BlockHash != h.block.Hash() {
+			block, err := s.client.BlockByNumber(ctx, receipt.BlockNumber)
+			if err != nil || block.Hash() != receipt.BlockHash {
+				retry = append(retry, h)
+				continue
+			}
+			s.printf("🔀 Held transaction %s moved to block %d after a reorg\n", hash.Hex(), block.NumberU64())
+			h.block = block
+			h.m.index = receipt.TransactionIndex
+			if head < block.NumberU64() || head-block.NumberU64() < h.depth {
+				retry = append(retry, h)
+				continue
+			}
+		}
+		s.processMatch(ctx, h.block, s.chainID.Uint64(), h.m, receipt, walletSet)
+	}
+
+	s.held.mu.Lock()
+	s.held.items = append(s.held.items, retry...)
+	heldAlertsGauge.With(s.chain.Name).Set(float64(len(s.held.items)))
+	s.held.mu.Unlock()
+}
+
+// resumePoint returns the block to save as scanned: lastBlock, or just
+// before the oldest held match so a restart does not lose it.
+func (s *Scanner) resumePoint(lastBlock uint64) uint64 {
+	s.held.mu.Lock()
+	defer s.held.mu.Unlock()
+	for _, h := range s.held.items {
+		if n := h.block.NumberU64(); n > 0 && n-1 < lastBlock {
+			lastBlock = n - 1
+		}
+	}
+	return lastBlock
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// correlationHeader carries a transaction's correlation ID on requests to
+// the analyzers and notifiers.
+const correlationHeader = "X-Correlation-ID"
+
+type correlationKey struct{}
+
+// correlationID names one transaction's trip through scanning, analysis and
+// notification: the chain ID and the first 16 hex digits of the hash. It is
+// derived, not random, so a re-scan, a retry or the pending and mined
+// sightings of a transaction all log under the same ID.
+func correlationID(chainID uint64, hash string) string {
+	h := strings.ToLower(strings.TrimPrefix(hash, "0x"))
+	if len(h) > 16 {
+		h = h[:16]
+	}
+	return fmt.Sprintf("%d-%s", chainID, h)
+}
+
+// withCorrelation returns ctx carrying id.
+func withCorrelation(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// correlationFrom returns the correlation ID in ctx, or "".
+func correlationFrom(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// correlationPrefix returns "[id] " for log lines about the transaction in
+// ctx, or "" outside one.
+func correlationPrefix(ctx context.Context) string {
+	if id := correlationFrom(ctx); id != "" {
+		return "[" + id + "] "
+	}
+	return ""
+}
+
+// logCorrelated is log.Printf tagged with ctx's correlation ID.
+func logCorrelated(ctx context.Context, format string, args ...interface{}) {
+	log.Printf(correlationPrefix(ctx)+format, args...)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+// counterpartyCache keeps the flagged-counterparty list in memory, reloading
+// it like the wallet caches: at most once per interval or after an API write.
+type counterpartyCache struct {
+	pool       *pgxpool.Pool
+	categories refreshingSet[map[common.Address]string]
+}
+
+func newCounterpartyCache(pool *pgxpool.Pool, interval time.Duration) *counterpartyCache {
+	if pool == nil {
+		return nil
+	}
+	return &counterpartyCache{pool: pool, categories: refreshingSet[map[common.Address]string]{interval: interval}}
+}
+
+// Category returns why addr is flagged, or "" when it is not.
+func (c *counterpartyCache) Category(ctx context.Context, addr common.Address) string {
+	if c == nil {
+		return ""
+	}
+	categories := c.categories.get(func() (map[common.Address]string, error) {
+		list, err := dbpkg.FetchCounterparties(ctx, c.pool)
+		categories := make(map[common.Address]string, len(list))
+		for _, cp := range list {
+			if canonical, ok := normalizeAddress(cp.Address); ok {
+				categories[common.HexToAddress(canonical)] = cp.Category
+			}
+		}
+		return categories, err
+	})
+	return categories[addr]
+}
+
+// flagCounterparty adds counterpartyRisk to p when the side of the
+// transaction opposite the watched wallet is a flagged counterparty.
+func (s *Scanner) flagCounterparty(ctx context.Context, p *TxPayload, wallet, from, to common.Address) {
+	other := to
+	if wallet == to {
+		other = from
+	}
+	if category := s.counterparties.Category(ctx, other); category != "" {
+		p.Counterparty = other.Hex()
+		p.CounterpartyRisk = category
+	}
+}
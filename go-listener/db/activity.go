@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TouchAddress records activity for addr observed at ts.
+func TouchAddress(ctx context.Context, pool *pgxpool.Pool, addr string, ts time.Time) error {
+	return TouchAddresses(ctx, pool, []string{addr}, ts)
+}
+
+// TouchAddresses moves last_seen forward (and first_seen back, or sets it when
+// null) for every stored address in addrs, in a single statement. Addresses are
+// matched ignoring case and the 0x prefix since stored values may be neither
+// checksummed nor prefixed.
+func TouchAddresses(ctx context.Context, pool *pgxpool.Pool, addrs []string, ts time.Time) error {
+	if len(addrs) == 0 {
+		return nil
+	}
+	lowered := make([]string, len(addrs))
+	for i, a := range addrs {
+		lowered[i] = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(a)), "0x")
+	}
+	_, err := pool.Exec(ctx,
+		`UPDATE addresses
+            SET first_seen = LEAST(COALESCE(first_seen, $2), $2),
+                last_seen  = GREATEST(COALESCE(last_seen, $2), $2),
+                updated_at = NOW()
+          WHERE regexp_replace(lower(trim(address)), '^0x', '') = ANY($1)`,
+		lowered, ts,
+	)
+	return err
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InsertAlert records one alert payload as sent to the sinks.
+func InsertAlert(ctx context.Context, pool *pgxpool.Pool, chainID uint64, txHash string, payload json.RawMessage) error {
+	_, err := pool.Exec(ctx,
+		`INSERT INTO alerts(chain_id, tx_hash, payload) VALUES ($1, $2, $3)`,
+		chainID, txHash, payload,
+	)
+	return err
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RecordSkippedBlock notes a block the scanner gave up on so it can be reprocessed later.
+func RecordSkippedBlock(ctx context.Context, pool *pgxpool.Pool, chainID, blockNum uint64, attempts int, lastErr string) error {
+	_, err := pool.Exec(ctx,
+		`INSERT INTO skipped_blocks(chain_id, block_num, last_error, attempts)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (chain_id, block_num) DO UPDATE SET last_error = EXCLUDED.last_error,
+                                                         attempts = EXCLUDED.attempts,
+                                                         skipped_at = NOW()`,
+		chainID, blockNum, lastErr, attempts,
+	)
+	return err
+}
+
+// ScannedBlock is one audit record of a block the scanner examined.
+type ScannedBlock struct {
+	ChainID    uint64    `json:"chain_id"`
+	BlockNum   uint64    `json:"block_num"`
+	BlockHash  string    `json:"block_hash"`
+	TxCount    int       `json:"tx_count"`
+	MatchCount int       `json:"match_count"`
+	ScannedAt  time.Time `json:"scanned_at"`
+}
+
+// RecordScannedBlock appends b to the scanned_blocks audit table. Re-scans add
+// rows rather than replacing them, so the table shows every pass.
+func RecordScannedBlock(ctx context.Context, pool *pgxpool.Pool, b ScannedBlock) error {
+	_, err := pool.Exec(ctx,
+		`INSERT INTO scanned_blocks(chain_id, block_num, block_hash, tx_count, match_count, scanned_at)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         ON CONFLICT DO NOTHING`,
+		b.ChainID, b.BlockNum, b.BlockHash, b.TxCount, b.MatchCount, b.ScannedAt,
+	)
+	return err
+}
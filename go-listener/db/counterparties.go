@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Counterparty is a flagged address and why it is flagged.
+type Counterparty struct {
+	Address  string
+	Category string
+}
+
+// FetchCounterparties returns every flagged counterparty.
+func FetchCounterparties(ctx context.Context, pool *pgxpool.Pool) ([]Counterparty, error) {
+	rows, err := pool.Query(ctx, `SELECT address, category FROM counterparties`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Counterparty
+	for rows.Next() {
+		var c Counterparty
+		if err := rows.Scan(&c.Address, &c.Category); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
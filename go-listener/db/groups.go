@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GroupMembership is one address's membership of a wallet group.
+type GroupMembership struct {
+	Address string
+	Group   string
+}
+
+// FetchGroupMemberships returns every group membership, ordered by group name.
+func FetchGroupMemberships(ctx context.Context, pool *pgxpool.Pool) ([]GroupMembership, error) {
+	rows, err := pool.Query(ctx, `SELECT address, group_name FROM wallet_group_members ORDER BY group_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []GroupMembership
+	for rows.Next() {
+		var m GroupMembership
+		if err := rows.Scan(&m.Address, &m.Group); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AddressesChannel is the NOTIFY channel signalled after every address write.
+const AddressesChannel = "blocksentinel_addresses"
+
+// NotifyAddressesChanged tells every listening process the watchlist changed.
+func NotifyAddressesChanged(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, `SELECT pg_notify($1, '')`, AddressesChannel)
+	return err
+}
+
+// ListenAddressChanges holds a dedicated connection LISTENing on
+// AddressesChannel and calls onChange for each notification. onListen is
+// called once the LISTEN is in place. It only returns when ctx is done or the
+// connection fails.
+func ListenAddressChanges(ctx context.Context, pool *pgxpool.Pool, onListen, onChange func()) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A connection left in LISTEN state must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+AddressesChannel); err != nil {
+		return err
+	}
+	onListen()
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		onChange()
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PendingAnalysis is a matched transaction whose analyzer send failed and
+// awaits retry.
+type PendingAnalysis struct {
+	ID          int64
+	ChainID     uint64
+	TxHash      string
+	Wallet      string
+	Payload     json.RawMessage
+	Attempts    int
+	LastError   string
+	FirstFailed time.Time
+	NextAttempt time.Time
+}
+
+// EnqueuePendingAnalysis adds p to the retry queue; re-enqueueing a queued
+// transaction keeps its original failure time and attempt count.
+func EnqueuePendingAnalysis(ctx context.Context, pool *pgxpool.Pool, p PendingAnalysis) error {
+	_, err := pool.Exec(ctx,
+		`INSERT INTO pending_analysis(chain_id, tx_hash, wallet, payload, last_error, next_attempt)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         ON CONFLICT (chain_id, tx_hash) DO UPDATE SET last_error = EXCLUDED.last_error`,
+		p.ChainID, p.TxHash, p.Wallet, p.Payload, p.LastError, p.NextAttempt,
+	)
+	return err
+}
+
+// DuePendingAnalyses returns up to limit queued items for chainID ready to retry at now.
+func DuePendingAnalyses(ctx context.Context, pool *pgxpool.Pool, chainID uint64, now time.Time, limit int) ([]PendingAnalysis, error) {
+	rows, err := pool.Query(ctx,
+		`SELECT id, chain_id, tx_hash, wallet, payload, attempts, COALESCE(last_error, ''), first_failed, next_attempt
+           FROM pending_analysis
+          WHERE chain_id = $1 AND next_attempt <= $2
+          ORDER BY next_attempt LIMIT $3`,
+		chainID, now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PendingAnalysis
+	for rows.Next() {
+		var p PendingAnalysis
+		if err := rows.Scan(&p.ID, &p.ChainID, &p.TxHash, &p.Wallet, &p.Payload, &p.Attempts, &p.LastError, &p.FirstFailed, &p.NextAttempt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// ReschedulePendingAnalysis records a failed retry and when to try next.
+func ReschedulePendingAnalysis(ctx context.Context, pool *pgxpool.Pool, id int64, attempts int, lastError string, next time.Time) error {
+	_, err := pool.Exec(ctx,
+		`UPDATE pending_analysis SET attempts = $2, last_error = $3, next_attempt = $4 WHERE id = $1`,
+		id, attempts, lastError, next,
+	)
+	return err
+}
+
+// DeletePendingAnalysis removes an item once delivered or expired.
+func DeletePendingAnalysis(ctx context.Context, pool *pgxpool.Pool, id int64) error {
+	_, err := pool.Exec(ctx, `DELETE FROM pending_analysis WHERE id = $1`, id)
+	return err
+}
+
+// CountPendingAnalyses returns the total queue depth across chains.
+func CountPendingAnalyses(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	var n int
+	err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM pending_analysis`).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RiskAssessment is one analyzer verdict for a stored transaction.
+type RiskAssessment struct {
+	ChainID   uint64
+	TxHash    string
+	Analyzer  string // endpoint that produced the verdict
+	RiskScore *float64
+	Category  string
+	Labels    []string
+	Raw       json.RawMessage
+}
+
+// InsertRiskAssessment records an analyzer result. The transaction must already
+// be stored, since assessments reference it by (chain_id, hash).
+func InsertRiskAssessment(ctx context.Context, pool *pgxpool.Pool, ra RiskAssessment) error {
+	_, err := pool.Exec(ctx,
+		`INSERT INTO risk_assessments(chain_id, tx_hash, analyzer, risk_score, category, labels, raw)
+         VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, $7)`,
+		ra.ChainID, ra.TxHash, ra.Analyzer, ra.RiskScore, ra.Category, ra.Labels, ra.Raw,
+	)
+	return err
+}
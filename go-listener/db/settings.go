@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// FetchSettings returns every stored config override, keyed by its YAML name.
+func FetchSettings(ctx context.Context, pool *pgxpool.Pool) (map[string]json.RawMessage, error) {
+	rows, err := pool.Query(ctx, `SELECT key, value FROM settings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]json.RawMessage{}
+	for rows.Next() {
+		var key string
+		var value json.RawMessage
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+	return out, rows.Err()
+}
+
+// ReplaceSettings makes settings the complete set of stored overrides in a
+// single transaction, keeping updated_at for values that did not change.
+func ReplaceSettings(ctx context.Context, pool *pgxpool.Pool, settings map[string]json.RawMessage) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	keys := make([]string, 0, len(settings))
+	for key, value := range settings {
+		keys = append(keys, key)
+		_, err := tx.Exec(ctx,
+			`INSERT INTO settings(key, value) VALUES ($1, $2)
+             ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()
+             WHERE settings.value IS DISTINCT FROM EXCLUDED.value`,
+			key, value,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM settings WHERE NOT (key = ANY($1))`, keys); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteScheme prefixes SQLite DSNs: sqlite://data/blocksentinel.db, or
+// sqlite:///var/lib/blocksentinel.db for an absolute path. Driver options
+// such as ?_busy_timeout=5000 are passed through.
+const sqliteScheme = "sqlite://"
+
+// sqliteSchema is applied on open; SQLite installs do not run the Postgres
+// migrations. Labels are stored as a JSON array.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS addresses (
+    address    TEXT PRIMARY KEY,
+    first_seen TIMESTAMP,
+    last_seen  TIMESTAMP,
+    labels     TEXT,
+    settings   TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS scan_state (
+    state_key  TEXT PRIMARY KEY,
+    last_block INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+const sqliteUpsertAddressSQL = `INSERT INTO addresses(address, first_seen, last_seen, labels)
+                 VALUES (?, ?, ?, ?)
+                 ON CONFLICT (address) DO UPDATE SET first_seen = COALESCE(excluded.first_seen, first_seen),
+                                             last_seen = COALESCE(excluded.last_seen, last_seen),
+                                             labels = COALESCE(excluded.labels, labels),
+                                             deleted_at = NULL,
+                                             updated_at = CURRENT_TIMESTAMP`
+
+// sqliteStore is the Store on a local SQLite file.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the database named by a sqlite:// DSN and
+// brings its schema up to date.
+func OpenSQLite(ctx context.Context, dsn string) (Store, error) {
+	path, ok := strings.CutPrefix(dsn, sqliteScheme)
+	if !ok || path == "" {
+		return nil, errors.New("sqlite DSN must look like sqlite://path/to/file.db")
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time; a single connection queues writes
+	// instead of failing them with "database is locked"
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Name() string { return "sqlite" }
+
+func (s *sqliteStore) FetchMonitoredWallets(ctx context.Context, label string) ([]MonitoredWallet, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT address, settings FROM addresses
+          WHERE deleted_at IS NULL AND (? = '' OR EXISTS (SELECT 1 FROM json_each(addresses.labels) WHERE value = ?))`,
+		label, label)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wallets []MonitoredWallet
+	for rows.Next() {
+		var w MonitoredWallet
+		var raw sql.NullString
+		if err := rows.Scan(&w.Address, &raw); err != nil {
+			return nil, err
+		}
+		if raw.Valid {
+			var ws WalletSettings
+			// Settings are validated on write; a bad row just falls back to the defaults
+			if json.Unmarshal([]byte(raw.String), &ws) == nil {
+				w.Settings = &ws
+			}
+		}
+		wallets = append(wallets, w)
+	}
+	return wallets, rows.Err()
+}
+
+func (s *sqliteStore) LoadScanState(ctx context.Context, key string) (uint64, error) {
+	var block uint64
+	err := s.db.QueryRowContext(ctx, `SELECT last_block FROM scan_state WHERE state_key = ?`, key).Scan(&block)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return block, err
+}
+
+func (s *sqliteStore) SaveScanState(ctx context.Context, key string, blockNum uint64) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO scan_state(state_key, last_block)
+         VALUES (?, ?)
+         ON CONFLICT (state_key) DO UPDATE SET last_block = excluded.last_block,
+                                               updated_at = CURRENT_TIMESTAMP`,
+		key, int64(blockNum),
+	)
+	return err
+}
+
+func (s *sqliteStore) GetAddress(ctx context.Context, addr string) (Address, error) {
+	var out Address
+	var labels, settings sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT address, first_seen, last_seen, labels, settings, created_at, updated_at
+         FROM addresses WHERE address = ? AND deleted_at IS NULL`, addr,
+	).Scan(&out.Address, &out.FirstSeen, &out.LastSeen, &labels, &settings, &out.CreatedAt, &out.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return out, ErrNotFound
+	}
+	if err != nil {
+		return out, err
+	}
+	if out.Labels, err = decodeLabels(labels); err != nil {
+		return out, err
+	}
+	if settings.Valid {
+		out.Settings = json.RawMessage(settings.String)
+	}
+	return out, nil
+}
+
+func (s *sqliteStore) UpsertAddress(ctx context.Context, a Address) error {
+	labels, err := encodeLabels(a.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, sqliteUpsertAddressSQL, a.Address, a.FirstSeen, a.LastSeen, labels)
+	return err
+}
+
+func (s *sqliteStore) UpdateAddress(ctx context.Context, a Address) error {
+	labels, err := encodeLabels(a.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE addresses SET first_seen=?, last_seen=?, labels=?, updated_at=CURRENT_TIMESTAMP WHERE address=? AND deleted_at IS NULL`,
+		a.FirstSeen, a.LastSeen, labels, a.Address,
+	)
+	return err
+}
+
+// PatchAddress reads, merges and writes back inside one transaction; the
+// single connection serializes concurrent patches.
+func (s *sqliteStore) PatchAddress(ctx context.Context, addr string, add, remove []string, setSettings bool, settings []byte) ([]string, []byte, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var rawLabels, rawSettings sql.NullString
+	err = tx.QueryRowContext(ctx,
+		`SELECT labels, settings FROM addresses WHERE address = ? AND deleted_at IS NULL`, addr,
+	).Scan(&rawLabels, &rawSettings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	labels, err := decodeLabels(rawLabels)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(add)+len(remove) > 0 {
+		labels = mergeLabels(labels, add, remove)
+	}
+	var stored []byte
+	if rawSettings.Valid {
+		stored = []byte(rawSettings.String)
+	}
+	if setSettings {
+		stored = settings
+	}
+
+	encoded, err := encodeLabels(labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	var settingsArg interface{}
+	if stored != nil {
+		settingsArg = string(stored)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE addresses SET labels=?, settings=?, updated_at=CURRENT_TIMESTAMP WHERE address=?`,
+		encoded, settingsArg, addr,
+	); err != nil {
+		return nil, nil, err
+	}
+	return labels, stored, tx.Commit()
+}
+
+func (s *sqliteStore) DeleteAddress(ctx context.Context, addr string, hard bool) error {
+	query := `UPDATE addresses SET deleted_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP WHERE address=? AND deleted_at IS NULL`
+	if hard {
+		query = `DELETE FROM addresses WHERE address=?`
+	}
+	_, err := s.db.ExecContext(ctx, query, addr)
+	return err
+}
+
+func (s *sqliteStore) ImportAddresses(ctx context.Context, batch []Address) (inserted, updated int, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	for _, a := range batch {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM addresses WHERE address = ?)`, a.Address).Scan(&exists); err != nil {
+			return 0, 0, err
+		}
+		labels, err := encodeLabels(a.Labels)
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, err := tx.ExecContext(ctx, sqliteUpsertAddressSQL, a.Address, a.FirstSeen, a.LastSeen, labels); err != nil {
+			return 0, 0, err
+		}
+		if exists {
+			updated++
+		} else {
+			inserted++
+		}
+	}
+	return inserted, updated, tx.Commit()
+}
+
+// NotifyAddressesChanged is a no-op: a SQLite file has a single writer
+// process, which already invalidates its own caches.
+func (s *sqliteStore) NotifyAddressesChanged(context.Context) error { return nil }
+
+func (s *sqliteStore) Close() { s.db.Close() }
+
+// encodeLabels returns labels as a JSON array, or NULL for nil so upserts
+// keep the stored labels.
+func encodeLabels(labels []string) (interface{}, error) {
+	if labels == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func decodeLabels(raw sql.NullString) ([]string, error) {
+	if !raw.Valid {
+		return nil, nil
+	}
+	var labels []string
+	err := json.Unmarshal([]byte(raw.String), &labels)
+	return labels, err
+}
+
+// mergeLabels matches patchAddressSQL: existing order is kept, added labels
+// are appended, removed ones dropped, and duplicates collapsed.
+func mergeLabels(labels, add, remove []string) []string {
+	drop := make(map[string]bool, len(remove))
+	for _, l := range remove {
+		drop[l] = true
+	}
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, l := range append(append([]string{}, labels...), add...) {
+		if drop[l] || seen[l] {
+			continue
+		}
+		seen[l] = true
+		out = append(out, l)
+	}
+	return out
+}
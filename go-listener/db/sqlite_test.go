@@ -0,0 +1,83 @@
+//go:build cgo
+
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func openTestSQLite(t *testing.T) Store {
+	t.Helper()
+	store, err := OpenSQLite(context.Background(), "sqlite://:memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+func TestSQLiteAddressRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := openTestSQLite(t)
+	const addr = "0x52908400098527886E0F7030069857D2E4169EE7"
+	firstSeen := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if err := store.UpsertAddress(ctx, Address{Address: addr, FirstSeen: &firstSeen, Labels: []string{"monitor", "exchange"}}); err != nil {
+		t.Fatalf("UpsertAddress: %v", err)
+	}
+	got, err := store.GetAddress(ctx, addr)
+	if err != nil {
+		t.Fatalf("GetAddress: %v", err)
+	}
+	if got.Address != addr {
+		t.Errorf("address = %q, want %q", got.Address, addr)
+	}
+	if got.FirstSeen == nil || !got.FirstSeen.Equal(firstSeen) {
+		t.Errorf("first_seen = %v, want %v", got.FirstSeen, firstSeen)
+	}
+	if !slices.Equal(got.Labels, []string{"monitor", "exchange"}) {
+		t.Errorf("labels = %v", got.Labels)
+	}
+
+	labels, _, err := store.PatchAddress(ctx, addr, []string{"flagged"}, []string{"exchange"}, false, nil)
+	if err != nil {
+		t.Fatalf("PatchAddress: %v", err)
+	}
+	if !slices.Equal(labels, []string{"monitor", "flagged"}) {
+		t.Errorf("patched labels = %v, want [monitor flagged]", labels)
+	}
+
+	wallets, err := store.FetchMonitoredWallets(ctx, "monitor")
+	if err != nil {
+		t.Fatalf("FetchMonitoredWallets: %v", err)
+	}
+	if len(wallets) != 1 || wallets[0].Address != addr {
+		t.Errorf("monitored wallets = %+v, want just %s", wallets, addr)
+	}
+
+	if err := store.DeleteAddress(ctx, addr, false); err != nil {
+		t.Fatalf("DeleteAddress: %v", err)
+	}
+	if _, err := store.GetAddress(ctx, addr); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetAddress after delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLiteScanStateRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := openTestSQLite(t)
+
+	if n, err := store.LoadScanState(ctx, "1"); err != nil || n != 0 {
+		t.Fatalf("LoadScanState before any save = (%d, %v), want (0, nil)", n, err)
+	}
+	if err := store.SaveScanState(ctx, "1", 1234); err != nil {
+		t.Fatalf("SaveScanState: %v", err)
+	}
+	if n, err := store.LoadScanState(ctx, "1"); err != nil || n != 1234 {
+		t.Errorf("LoadScanState = (%d, %v), want (1234, nil)", n, err)
+	}
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LoadScanState returns the last processed block stored under key, or 0 when
+// none has been saved yet.
+func LoadScanState(ctx context.Context, pool *pgxpool.Pool, key string) (uint64, error) {
+	var block uint64
+	err := pool.QueryRow(ctx, `SELECT last_block FROM scan_state WHERE state_key = $1`, key).Scan(&block)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return block, err
+}
+
+// SaveScanState records blockNum as the last processed block for key.
+func SaveScanState(ctx context.Context, pool *pgxpool.Pool, key string, blockNum uint64) error {
+	_, err := pool.Exec(ctx,
+		`INSERT INTO scan_state(state_key, last_block)
+         VALUES ($1, $2)
+         ON CONFLICT (state_key) DO UPDATE SET last_block = EXCLUDED.last_block,
+                                               updated_at = NOW()`,
+		key, blockNum,
+	)
+	return err
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNotFound is returned for an address that does not exist or is soft-deleted.
+var ErrNotFound = errors.New("not found")
+
+// Address is a stored address record.
+type Address struct {
+	Address   string          `json:"address"`
+	FirstSeen *time.Time      `json:"first_seen,omitempty"`
+	LastSeen  *time.Time      `json:"last_seen,omitempty"`
+	Labels    []string        `json:"labels,omitempty"`
+	Settings  json.RawMessage `json:"settings,omitempty"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+}
+
+// Store holds the address watchlist and scan positions. Postgres backs the
+// full feature set; SQLite covers just these for single-node setups.
+type Store interface {
+	Name() string
+
+	FetchMonitoredWallets(ctx context.Context, label string) ([]MonitoredWallet, error)
+	LoadScanState(ctx context.Context, key string) (uint64, error)
+	SaveScanState(ctx context.Context, key string, blockNum uint64) error
+
+	GetAddress(ctx context.Context, addr string) (Address, error)
+	// UpsertAddress creates a or revives it if soft-deleted. Nil fields keep
+	// their stored values.
+	UpsertAddress(ctx context.Context, a Address) error
+	// UpdateAddress replaces a live address's dates and labels.
+	UpdateAddress(ctx context.Context, a Address) error
+	// PatchAddress adds and removes labels and, when setSettings is true,
+	// replaces the settings, returning the stored result.
+	PatchAddress(ctx context.Context, addr string, add, remove []string, setSettings bool, settings []byte) (labels []string, stored []byte, err error)
+	// DeleteAddress soft-deletes addr, or purges it when hard is true.
+	DeleteAddress(ctx context.Context, addr string, hard bool) error
+	// ImportAddresses upserts the batch in a single transaction.
+	ImportAddresses(ctx context.Context, batch []Address) (inserted, updated int, err error)
+
+	// NotifyAddressesChanged tells other processes the watchlist changed.
+	NotifyAddressesChanged(ctx context.Context) error
+	Close()
+}
+
+// IsSQLiteURL reports whether dsn selects the SQLite store.
+func IsSQLiteURL(dsn string) bool {
+	return strings.HasPrefix(dsn, sqliteScheme)
+}
+
+// pgStore is the Store on a Postgres pool.
+type pgStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPGStore wraps pool as a Store. Closing the store closes the pool.
+func NewPGStore(pool *pgxpool.Pool) Store {
+	return &pgStore{pool: pool}
+}
+
+const upsertAddressSQL = `INSERT INTO addresses(address, first_seen, last_seen, labels)
+                 VALUES ($1, $2, $3, $4)
+                 ON CONFLICT (address) DO UPDATE SET first_seen = COALESCE(EXCLUDED.first_seen, addresses.first_seen),
+                                             last_seen = COALESCE(EXCLUDED.last_seen, addresses.last_seen),
+                                             labels = COALESCE(EXCLUDED.labels, addresses.labels),
+                                             deleted_at = NULL,
+                                             updated_at = NOW()`
+
+// patchAddressSQL adds and removes labels and optionally replaces settings in
+// one statement so concurrent callers never overwrite each other's tags.
+// Existing label order is kept, added labels are appended, and duplicates are
+// dropped.
+const patchAddressSQL = `UPDATE addresses SET labels = CASE WHEN cardinality($2::text[]) + cardinality($3::text[]) = 0 THEN labels ELSE ARRAY(
+                     SELECT l FROM unnest(array_cat(COALESCE(labels, '{}'::text[]), $2::text[])) WITH ORDINALITY AS t(l, i)
+                     WHERE NOT (l = ANY($3::text[]))
+                     GROUP BY l ORDER BY min(i)) END,
+                 settings = CASE WHEN $4::bool THEN $5::jsonb ELSE settings END,
+                 updated_at = NOW()
+                 WHERE address = $1 AND deleted_at IS NULL
+                 RETURNING labels, settings`
+
+func (s *pgStore) Name() string { return "postgres" }
+
+func (s *pgStore) FetchMonitoredWallets(ctx context.Context, label string) ([]MonitoredWallet, error) {
+	return FetchMonitoredWallets(ctx, s.pool, label)
+}
+
+func (s *pgStore) LoadScanState(ctx context.Context, key string) (uint64, error) {
+	return LoadScanState(ctx, s.pool, key)
+}
+
+func (s *pgStore) SaveScanState(ctx context.Context, key string, blockNum uint64) error {
+	return SaveScanState(ctx, s.pool, key, blockNum)
+}
+
+func (s *pgStore) GetAddress(ctx context.Context, addr string) (Address, error) {
+	var out Address
+	err := s.pool.QueryRow(ctx,
+		`SELECT address, first_seen, last_seen, labels, settings, created_at, updated_at
+         FROM addresses WHERE address = $1 AND deleted_at IS NULL`, addr,
+	).Scan(&out.Address, &out.FirstSeen, &out.LastSeen, &out.Labels, &out.Settings, &out.CreatedAt, &out.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return out, ErrNotFound
+	}
+	return out, err
+}
+
+func (s *pgStore) UpsertAddress(ctx context.Context, a Address) error {
+	_, err := s.pool.Exec(ctx, upsertAddressSQL, a.Address, a.FirstSeen, a.LastSeen, a.Labels)
+	return err
+}
+
+func (s *pgStore) UpdateAddress(ctx context.Context, a Address) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE addresses SET first_seen=$2, last_seen=$3, labels=$4, updated_at=NOW() WHERE address=$1 AND deleted_at IS NULL`,
+		a.Address, a.FirstSeen, a.LastSeen, a.Labels,
+	)
+	return err
+}
+
+func (s *pgStore) PatchAddress(ctx context.Context, addr string, add, remove []string, setSettings bool, settings []byte) ([]string, []byte, error) {
+	var labels []string
+	var stored []byte
+	err := s.pool.QueryRow(ctx, patchAddressSQL,
+		addr, nonNil(add), nonNil(remove), setSettings, settings,
+	).Scan(&labels, &stored)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	return labels, stored, err
+}
+
+func (s *pgStore) DeleteAddress(ctx context.Context, addr string, hard bool) error {
+	// Soft-delete by default to keep history
+	query := `UPDATE addresses SET deleted_at=NOW(), updated_at=NOW() WHERE address=$1 AND deleted_at IS NULL`
+	if hard {
+		query = `DELETE FROM addresses WHERE address=$1`
+	}
+	_, err := s.pool.Exec(ctx, query, addr)
+	return err
+}
+
+func (s *pgStore) ImportAddresses(ctx context.Context, batch []Address) (inserted, updated int, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	b := &pgx.Batch{}
+	for _, a := range batch {
+		// xmax is 0 only for freshly inserted rows, letting us tell inserts from updates.
+		b.Queue(upsertAddressSQL+` RETURNING (xmax = 0)`, a.Address, a.FirstSeen, a.LastSeen, a.Labels)
+	}
+	br := tx.SendBatch(ctx, b)
+	for range batch {
+		var fresh bool
+		if err := br.QueryRow().Scan(&fresh); err != nil {
+			br.Close()
+			return 0, 0, err
+		}
+		if fresh {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+	if err := br.Close(); err != nil {
+		return 0, 0, err
+	}
+	return inserted, updated, tx.Commit(ctx)
+}
+
+func (s *pgStore) NotifyAddressesChanged(ctx context.Context) error {
+	return NotifyAddressesChanged(ctx, s.pool)
+}
+
+func (s *pgStore) Close() { s.pool.Close() }
+
+// nonNil turns a nil slice into an empty one so it binds as '{}' rather than NULL.
+func nonNil(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Transaction is a matched transaction as stored in the transactions table.
+type Transaction struct {
+	ID             int64 // set when read back
+	ChainID        uint64
+	Hash           string
+	From           string
+	To             *string
+	ValueWei       string
+	GasUsed        *int64
+	GasPriceWei    string
+	BlockNum       uint64
+	BlockTimestamp uint64
+	InputHex       string
+	MethodSelector string // "0x" and the first 4 bytes of input; empty for creations
+	MethodName     string // decoded name of MethodSelector, when known
+}
+
+// InsertTransaction stores tx, ignoring transactions already recorded for its
+// chain. It reports whether the row was new and when the transaction was
+// first stored, so callers can tell a re-scan from a first sighting.
+func InsertTransaction(ctx context.Context, pool *pgxpool.Pool, tx Transaction) (firstSeen time.Time, inserted bool, err error) {
+	// The fallback SELECT sees the pre-statement snapshot, so on conflict it
+	// returns the existing row and on insert it returns nothing
+	err = pool.QueryRow(ctx,
+		`WITH ins AS (
+             INSERT INTO transactions(chain_id, hash, from_address, to_address, value_wei, gas_used,
+                                      gas_price_wei, block_num, block_timestamp, input_hex,
+                                      method_selector, method_name)
+             VALUES ($1, $2, $3, $4, $5::numeric, $6, $7::numeric, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''))
+             ON CONFLICT (chain_id, hash) DO NOTHING
+             RETURNING created_at
+         )
+         SELECT created_at, true FROM ins
+         UNION ALL
+         SELECT created_at, false FROM transactions WHERE chain_id = $1 AND hash = $2
+         LIMIT 1`,
+		tx.ChainID, tx.Hash, tx.From, tx.To, tx.ValueWei, tx.GasUsed,
+		tx.GasPriceWei, tx.BlockNum, tx.BlockTimestamp, tx.InputHex,
+		tx.MethodSelector, tx.MethodName,
+	).Scan(&firstSeen, &inserted)
+	return firstSeen, inserted, err
+}
+
+// TransactionFilter selects one chain's stored transactions by block number
+// and block time; nil bounds are open and set bounds are inclusive.
+type TransactionFilter struct {
+	ChainID   uint64
+	FromBlock *uint64
+	ToBlock   *uint64
+	Since     *time.Time
+	Until     *time.Time
+}
+
+func (f TransactionFilter) args() []interface{} {
+	unix := func(t *time.Time) *int64 {
+		if t == nil {
+			return nil
+		}
+		v := t.Unix()
+		return &v
+	}
+	return []interface{}{f.ChainID, f.FromBlock, f.ToBlock, unix(f.Since), unix(f.Until)}
+}
+
+const transactionFilterWhere = `chain_id = $1
+   AND ($2::bigint IS NULL OR block_num >= $2) AND ($3::bigint IS NULL OR block_num <= $3)
+   AND ($4::bigint IS NULL OR block_timestamp >= $4) AND ($5::bigint IS NULL OR block_timestamp <= $5)`
+
+// CountTransactions returns how many stored transactions match f.
+func CountTransactions(ctx context.Context, pool *pgxpool.Pool, f TransactionFilter) (int, error) {
+	var n int
+	err := pool.QueryRow(ctx, `SELECT count(*) FROM transactions WHERE `+transactionFilterWhere, f.args()...).Scan(&n)
+	return n, err
+}
+
+// ListTransactions returns up to limit stored transactions matching f with
+// IDs above afterID, in ID order, for paging through large ranges.
+func ListTransactions(ctx context.Context, pool *pgxpool.Pool, f TransactionFilter, afterID int64, limit int) ([]Transaction, error) {
+	rows, err := pool.Query(ctx,
+		`SELECT id, chain_id, hash, from_address, to_address, value_wei::text, gas_used,
+                COALESCE(gas_price_wei::text, '0'), block_num, block_timestamp, COALESCE(input_hex, '')
+           FROM transactions
+          WHERE `+transactionFilterWhere+` AND id > $6
+          ORDER BY id LIMIT $7`,
+		append(f.args(), afterID, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Transaction
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.ID, &t.ChainID, &t.Hash, &t.From, &t.To, &t.ValueWei, &t.GasUsed,
+			&t.GasPriceWei, &t.BlockNum, &t.BlockTimestamp, &t.InputHex); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
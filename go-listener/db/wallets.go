@@ -2,27 +2,87 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// DefaultMonitorLabel is the label an address must carry to be actively watched.
+const DefaultMonitorLabel = "monitored"
+
+// WalletSettings are per-address alert overrides stored in addresses.settings.
+// Unset fields fall back to the global configuration.
+type WalletSettings struct {
+	MinValueWei  string   `json:"min_value_wei,omitempty"`  // decimal wei; smaller transfers are ignored
+	MinRiskScore *float64 `json:"min_risk_score,omitempty"` // notify at or above this score
+	Direction    string   `json:"direction,omitempty"`      // "incoming", "outgoing" or "both"
+}
+
+// Validate reports the first invalid field, if any.
+func (s WalletSettings) Validate() error {
+	if s.MinValueWei != "" {
+		if v, ok := new(big.Int).SetString(s.MinValueWei, 10); !ok || v.Sign() < 0 {
+			return fmt.Errorf("min_value_wei must be a non-negative decimal integer")
+		}
+	}
+	if s.MinRiskScore != nil && (*s.MinRiskScore < 0 || *s.MinRiskScore > 1) {
+		return fmt.Errorf("min_risk_score must be between 0 and 1")
+	}
+	switch s.Direction {
+	case "", "incoming", "outgoing", "both":
+	default:
+		return fmt.Errorf("direction must be incoming, outgoing or both")
+	}
+	return nil
+}
+
+// MinValue returns MinValueWei parsed, or nil when unset.
+func (s WalletSettings) MinValue() *big.Int {
+	if s.MinValueWei == "" {
+		return nil
+	}
+	v, ok := new(big.Int).SetString(s.MinValueWei, 10)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// MonitoredWallet is a watched address with its optional settings.
+type MonitoredWallet struct {
+	Address  string
+	Settings *WalletSettings
+}
+
 // FetchMonitoredWallets returns the list of wallet addresses to monitor.
-// MVP: returns all addresses present in the addresses table.
-// You can later scope this to a specific label (e.g., addresses where 'monitored' is in labels).
-func FetchMonitoredWallets(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
-	rows, err := pool.Query(ctx, `SELECT address FROM addresses`)
+// Only addresses whose labels contain label are returned, so operators can
+// store many addresses but watch a subset. An empty label returns every address.
+// Soft-deleted addresses are never returned.
+func FetchMonitoredWallets(ctx context.Context, pool *pgxpool.Pool, label string) ([]MonitoredWallet, error) {
+	rows, err := pool.Query(ctx, `SELECT address, settings FROM addresses
+          WHERE deleted_at IS NULL AND ($1::text = '' OR $1::text = ANY(labels))`, label)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var wallets []string
+	var wallets []MonitoredWallet
 	for rows.Next() {
-		var addr string
-		if scanErr := rows.Scan(&addr); scanErr != nil {
+		var w MonitoredWallet
+		var raw []byte
+		if scanErr := rows.Scan(&w.Address, &raw); scanErr != nil {
 			return nil, scanErr
 		}
-		wallets = append(wallets, addr)
+		if len(raw) > 0 {
+			var s WalletSettings
+			// Settings are validated on write; a bad row just falls back to the defaults
+			if json.Unmarshal(raw, &s) == nil {
+				w.Settings = &s
+			}
+		}
+		wallets = append(wallets, w)
 	}
 	return wallets, rows.Err()
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+// poolStatsInterval is how often connection pool stats are sampled.
+const poolStatsInterval = 15 * time.Second
+
+var (
+	dbPoolAcquiredConns   = metrics.NewGauge("blocksentinel_db_pool_acquired_conns", "Postgres connections currently checked out of the pool.")
+	dbPoolIdleConns       = metrics.NewGauge("blocksentinel_db_pool_idle_conns", "Idle Postgres connections in the pool.")
+	dbPoolTotalConns      = metrics.NewGauge("blocksentinel_db_pool_total_conns", "Postgres connections open in the pool.")
+	dbPoolMaxConns        = metrics.NewGauge("blocksentinel_db_pool_max_conns", "Maximum size of the Postgres pool.")
+	dbPoolAcquireCount    = metrics.NewGauge("blocksentinel_db_pool_acquire_count", "Cumulative successful connection acquires.")
+	dbPoolEmptyAcquires   = metrics.NewGauge("blocksentinel_db_pool_empty_acquire_count", "Cumulative acquires that had to wait because the pool had no idle connection.")
+	dbPoolAcquireDuration = metrics.NewGauge("blocksentinel_db_pool_acquire_duration_seconds", "Cumulative time spent waiting to acquire connections.")
+)
+
+// watchPoolStats samples pool.Stat() into the pool gauges until the process
+// exits. A rising empty acquire count with acquired == max signals saturation.
+func watchPoolStats(pool *pgxpool.Pool) {
+	for {
+		st := pool.Stat()
+		dbPoolAcquiredConns.Set(float64(st.AcquiredConns()))
+		dbPoolIdleConns.Set(float64(st.IdleConns()))
+		dbPoolTotalConns.Set(float64(st.TotalConns()))
+		dbPoolMaxConns.Set(float64(st.MaxConns()))
+		dbPoolAcquireCount.Set(float64(st.AcquireCount()))
+		dbPoolEmptyAcquires.Set(float64(st.EmptyAcquireCount()))
+		dbPoolAcquireDuration.Set(st.AcquireDuration().Seconds())
+		time.Sleep(poolStatsInterval)
+	}
+}
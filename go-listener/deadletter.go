@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+const (
+	analysisRetryInterval = 30 * time.Second
+	analysisRetryBaseWait = 30 * time.Second
+	analysisRetryMaxWait  = 30 * time.Minute
+)
+
+var analysisQueueDepth = metrics.NewGauge("blocksentinel_analysis_queue_depth", "Matched transactions waiting to be re-sent to the analyzer.")
+
+// analysisQueue is a dead-letter queue for analyzer sends that failed, backed
+// by Postgres when available and a local JSON file otherwise.
+type analysisQueue interface {
+	Enqueue(ctx context.Context, p dbpkg.PendingAnalysis) error
+	Due(ctx context.Context, chainID uint64, now time.Time, limit int) ([]dbpkg.PendingAnalysis, error)
+	Reschedule(ctx context.Context, id int64, attempts int, lastError string, next time.Time) error
+	Delete(ctx context.Context, id int64) error
+	Depth(ctx context.Context) (int, error)
+}
+
+func newAnalysisQueue(pool *pgxpool.Pool, path string) analysisQueue {
+	if pool != nil {
+		return &pgAnalysisQueue{pool: pool}
+	}
+	return &fileAnalysisQueue{path: path}
+}
+
+// retryBackoff returns the wait before retry number attempts (1-based).
+func retryBackoff(attempts int) time.Duration {
+	wait := analysisRetryBaseWait
+	for i := 1; i < attempts && wait < analysisRetryMaxWait; i++ {
+		wait *= 2
+	}
+	if wait > analysisRetryMaxWait {
+		wait = analysisRetryMaxWait
+	}
+	return wait
+}
+
+func refreshQueueDepth(ctx context.Context, q analysisQueue) {
+	if n, err := q.Depth(ctx); err == nil {
+		analysisQueueDepth.Set(float64(n))
+	}
+}
+
+// enqueueFailedAnalysis dead-letters a transaction the analyzer did not accept.
+func (s *Scanner) enqueueFailedAnalysis(ctx context.Context, chainID uint64, wallet string, tx *TxPayload, sendErr error) {
+	if s.queue == nil {
+		return
+	}
+	payload, err := json.Marshal(tx)
+	if err != nil {
+		s.txLogf(ctx, "Error encoding transaction for retry queue: %v", err)
+		return
+	}
+	now := time.Now().UTC()
+	p := dbpkg.PendingAnalysis{
+		ChainID:     chainID,
+		TxHash:      tx.Hash,
+		Wallet:      wallet,
+		Payload:     payload,
+		LastError:   sendErr.Error(),
+		FirstFailed: now,
+		NextAttempt: now.Add(retryBackoff(1)),
+	}
+	if err := s.queue.Enqueue(ctx, p); err != nil {
+		s.txLogf(ctx, "Error queueing %s for analyzer retry: %v", p.TxHash, err)
+		return
+	}
+	refreshQueueDepth(ctx, s.queue)
+}
+
+// retryPendingAnalyses periodically re-sends this chain's dead-lettered
+// transactions until an analyzer accepts them or they exceed the max age.
+// Analyzers that fail while another answers are not retried.
+func (s *Scanner) retryPendingAnalyses(chainID uint64) {
+	if s.queue == nil || len(s.cfg.live().AnalyzerURLs) == 0 {
+		return
+	}
+	maxAge := time.Duration(s.cfg.AnalysisRetryMaxAge) * time.Second
+	ticker := time.NewTicker(analysisRetryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx := context.Background()
+		now := time.Now().UTC()
+		items, err := s.queue.Due(ctx, chainID, now, 100)
+		if err != nil {
+			s.logf("Error reading analyzer retry queue: %v", err)
+			continue
+		}
+		for _, p := range items {
+			if maxAge > 0 && now.Sub(p.FirstFailed) > maxAge {
+				s.logf("⚠️  Dropping %s from analyzer retry queue after %d attempts: older than %s", p.TxHash, p.Attempts, maxAge)
+				_ = s.queue.Delete(ctx, p.ID)
+				continue
+			}
+			var tx TxPayload
+			if err := json.Unmarshal(p.Payload, &tx); err != nil {
+				s.logf("Dropping unreadable queued transaction %s: %v", p.TxHash, err)
+				_ = s.queue.Delete(ctx, p.ID)
+				continue
+			}
+			// Entries queued before correlation IDs existed lack one
+			tx.CorrelationID = correlationID(p.ChainID, p.TxHash)
+			txCtx := withCorrelation(ctx, tx.CorrelationID)
+			results, err := sendToAnalyzers(txCtx, s.cfg, &tx)
+			if len(results) == 0 {
+				attempts := p.Attempts + 1
+				_ = s.queue.Reschedule(ctx, p.ID, attempts, err.Error(), now.Add(retryBackoff(attempts+1)))
+				continue
+			}
+			s.txPrintf(txCtx, "♻️  Delivered queued transaction %s to analyzer after %d retries\n", p.TxHash, p.Attempts+1)
+			_ = s.queue.Delete(ctx, p.ID)
+			s.handleAnalysisResults(txCtx, p.ChainID, p.Wallet, &tx, results)
+		}
+		refreshQueueDepth(ctx, s.queue)
+	}
+}
+
+// pgAnalysisQueue stores the queue in the pending_analysis table.
+type pgAnalysisQueue struct {
+	pool *pgxpool.Pool
+}
+
+func (q *pgAnalysisQueue) Enqueue(ctx context.Context, p dbpkg.PendingAnalysis) error {
+	return dbpkg.EnqueuePendingAnalysis(ctx, q.pool, p)
+}
+
+func (q *pgAnalysisQueue) Due(ctx context.Context, chainID uint64, now time.Time, limit int) ([]dbpkg.PendingAnalysis, error) {
+	return dbpkg.DuePendingAnalyses(ctx, q.pool, chainID, now, limit)
+}
+
+func (q *pgAnalysisQueue) Reschedule(ctx context.Context, id int64, attempts int, lastError string, next time.Time) error {
+	return dbpkg.ReschedulePendingAnalysis(ctx, q.pool, id, attempts, lastError, next)
+}
+
+func (q *pgAnalysisQueue) Delete(ctx context.Context, id int64) error {
+	return dbpkg.DeletePendingAnalysis(ctx, q.pool, id)
+}
+
+func (q *pgAnalysisQueue) Depth(ctx context.Context) (int, error) {
+	return dbpkg.CountPendingAnalyses(ctx, q.pool)
+}
+
+// fileAnalysisQueue keeps the queue in a local JSON file for DB-less deployments.
+type fileAnalysisQueue struct {
+	path string
+	mu   sync.Mutex
+}
+
+type fileQueueState struct {
+	NextID int64                   `json:"next_id"`
+	Items  []dbpkg.PendingAnalysis `json:"items"`
+}
+
+func (q *fileAnalysisQueue) load() (fileQueueState, error) {
+	var st fileQueueState
+	data, err := readFileIfExists(q.path)
+	if err != nil || len(data) == 0 {
+		return st, err
+	}
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+func (q *fileAnalysisQueue) save(st fileQueueState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(q.path, data, 0644)
+}
+
+// update runs fn on the loaded queue and persists the result.
+func (q *fileAnalysisQueue) update(fn func(st *fileQueueState)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st, err := q.load()
+	if err != nil {
+		return err
+	}
+	fn(&st)
+	return q.save(st)
+}
+
+func (q *fileAnalysisQueue) Enqueue(ctx context.Context, p dbpkg.PendingAnalysis) error {
+	return q.update(func(st *fileQueueState) {
+		for i := range st.Items {
+			if st.Items[i].ChainID == p.ChainID && st.Items[i].TxHash == p.TxHash {
+				st.Items[i].LastError = p.LastError
+				return
+			}
+		}
+		st.NextID++
+		p.ID = st.NextID
+		st.Items = append(st.Items, p)
+	})
+}
+
+func (q *fileAnalysisQueue) Due(ctx context.Context, chainID uint64, now time.Time, limit int) ([]dbpkg.PendingAnalysis, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	var out []dbpkg.PendingAnalysis
+	for _, p := range st.Items {
+		if p.ChainID == chainID && !p.NextAttempt.After(now) {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NextAttempt.Before(out[j].NextAttempt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (q *fileAnalysisQueue) Reschedule(ctx context.Context, id int64, attempts int, lastError string, next time.Time) error {
+	return q.update(func(st *fileQueueState) {
+		for i := range st.Items {
+			if st.Items[i].ID == id {
+				st.Items[i].Attempts = attempts
+				st.Items[i].LastError = lastError
+				st.Items[i].NextAttempt = next
+			}
+		}
+	})
+}
+
+func (q *fileAnalysisQueue) Delete(ctx context.Context, id int64) error {
+	return q.update(func(st *fileQueueState) {
+		kept := st.Items[:0]
+		for _, p := range st.Items {
+			if p.ID != id {
+				kept = append(kept, p)
+			}
+		}
+		st.Items = kept
+	})
+}
+
+func (q *fileAnalysisQueue) Depth(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st, err := q.load()
+	return len(st.Items), err
+}
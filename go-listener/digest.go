@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDigestImmediateThreshold = 0.9
+	defaultDigestTopCounterparties  = 3
+)
+
+// DigestConfig batches notifications per wallet during bursts. A zero Window
+// sends every notification immediately.
+type DigestConfig struct {
+	Window             int     `yaml:"window"`              // seconds
+	ImmediateThreshold float64 `yaml:"immediate_threshold"` // scores at or above this bypass the digest
+	TopCounterparties  int     `yaml:"top_counterparties"`
+}
+
+// Digest summarizes the notifications batched for one wallet over a window.
+type Digest struct {
+	Wallet            string              `json:"wallet"`
+	Count             int                 `json:"count"`
+	TotalValue        string              `json:"total_value"` // wei
+	MaxRiskScore      float64             `json:"max_risk_score"`
+	MaxRiskLevel      string              `json:"max_risk_level,omitempty"`
+	TopCounterparties []CounterpartyCount `json:"top_counterparties,omitempty"`
+	TxHashes          []string            `json:"tx_hashes"`
+	WindowStart       time.Time           `json:"window_start"`
+	WindowEnd         time.Time           `json:"window_end"`
+}
+
+// CounterpartyCount is how many batched transactions involved one counterparty.
+type CounterpartyCount struct {
+	Address string `json:"address"`
+	Count   int    `json:"count"`
+}
+
+// digester collects notifications per wallet and sends one Digest when the
+// wallet's window closes. The window opens on the wallet's first notification.
+type digester struct {
+	notifiers []Notifier
+	window    time.Duration
+	top       int
+
+	mu      sync.Mutex
+	pending map[string]*pendingDigest // by lowercase wallet
+}
+
+type pendingDigest struct {
+	digest         Digest
+	total          *big.Int
+	seen           map[string]bool // tx hashes, so re-analyses are counted once
+	counterparties map[string]int
+}
+
+// newDigester returns nil when digests are disabled.
+func newDigester(notifiers []Notifier, cfg DigestConfig) *digester {
+	if cfg.Window <= 0 || len(notifiers) == 0 {
+		return nil
+	}
+	return &digester{
+		notifiers: notifiers,
+		window:    time.Duration(cfg.Window) * time.Second,
+		top:       cfg.TopCounterparties,
+		pending:   make(map[string]*pendingDigest),
+	}
+}
+
+// add batches n into its wallet's open digest, opening one if needed.
+func (d *digester) add(n Notification) {
+	key := strings.ToLower(n.Wallet)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.pending[key]
+	if !ok {
+		p = &pendingDigest{
+			digest:         Digest{Wallet: n.Wallet, WindowStart: time.Now().UTC()},
+			total:          new(big.Int),
+			seen:           make(map[string]bool),
+			counterparties: make(map[string]int),
+		}
+		d.pending[key] = p
+		time.AfterFunc(d.window, func() { d.flush(key) })
+	}
+	if p.seen[n.TxHash] {
+		return
+	}
+	p.seen[n.TxHash] = true
+
+	p.digest.Count++
+	p.digest.TxHashes = append(p.digest.TxHashes, n.TxHash)
+	if v, ok := new(big.Int).SetString(n.Value, 10); ok {
+		p.total.Add(p.total, v)
+	}
+	if n.RiskScore > p.digest.MaxRiskScore || p.digest.Count == 1 {
+		p.digest.MaxRiskScore = n.RiskScore
+		p.digest.MaxRiskLevel = n.RiskLevel
+	}
+	counterparty := n.To
+	if strings.EqualFold(n.To, n.Wallet) {
+		counterparty = n.From
+	}
+	p.counterparties[counterparty]++
+}
+
+// flush sends and forgets the wallet's digest.
+func (d *digester) flush(key string) {
+	d.mu.Lock()
+	p, ok := d.pending[key]
+	delete(d.pending, key)
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	digest := p.digest
+	digest.TotalValue = p.total.String()
+	digest.WindowEnd = time.Now().UTC()
+	for addr, count := range p.counterparties {
+		digest.TopCounterparties = append(digest.TopCounterparties, CounterpartyCount{Address: addr, Count: count})
+	}
+	sort.Slice(digest.TopCounterparties, func(i, j int) bool {
+		a, b := digest.TopCounterparties[i], digest.TopCounterparties[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Address < b.Address
+	})
+	if d.top > 0 && len(digest.TopCounterparties) > d.top {
+		digest.TopCounterparties = digest.TopCounterparties[:d.top]
+	}
+
+	for _, nt := range d.notifiers {
+		go func(nt Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := nt.NotifyDigest(ctx, digest); err != nil {
+				log.Printf("Error sending %s digest for %s: %v", nt.Name(), digest.Wallet, err)
+			}
+		}(nt)
+	}
+}
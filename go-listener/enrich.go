@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultEnrichmentRate     = 1     // provider requests per second
+	defaultEnrichmentCacheTTL = 86400 // seconds
+	enrichmentQueueSize       = 1000
+	enrichmentTimeout         = 10 * time.Second
+)
+
+var enrichmentLookups = metrics.NewCounterVec("blocksentinel_enrichment_lookups_total", "Label provider lookups by result: hit (cached), fetched, failed or dropped (queue full).", "result")
+
+// EnrichmentConfig points at an external label provider. Each address
+// written through POST /addresses is looked up with GET {url}/{address},
+// which answers {"labels": [...]} or 404, and the labels are merged into the
+// address's own. Empty url disables enrichment.
+type EnrichmentConfig struct {
+	URL               string  `yaml:"url,omitempty"`
+	APIKey            string  `yaml:"api_key,omitempty"`             // sent as a bearer token
+	RequestsPerSecond float64 `yaml:"requests_per_second,omitempty"` // default 1
+	CacheTTL          int     `yaml:"cache_ttl,omitempty"`           // seconds a lookup is reused; default one day
+}
+
+// enricher runs lookups one at a time in the background, so the API never
+// waits on the provider. Results are cached per address.
+type enricher struct {
+	cfg     EnrichmentConfig
+	store   dbpkg.Store
+	allowed map[string]bool // label taxonomy; nil allows any label
+	limiter *rate.Limiter
+	ttl     time.Duration
+	queue   chan string
+
+	mu    sync.Mutex
+	cache map[string]cachedLabels
+}
+
+type cachedLabels struct {
+	labels    []string
+	fetchedAt time.Time
+}
+
+func newEnricher(cfg *Config, store dbpkg.Store) *enricher {
+	e := &enricher{
+		cfg:     cfg.Enrichment,
+		store:   store,
+		limiter: rate.NewLimiter(rate.Limit(cfg.Enrichment.RequestsPerSecond), 1),
+		ttl:     time.Duration(cfg.Enrichment.CacheTTL) * time.Second,
+		queue:   make(chan string, enrichmentQueueSize),
+		cache:   make(map[string]cachedLabels),
+	}
+	if len(cfg.AllowedLabels) > 0 {
+		e.allowed = make(map[string]bool, len(cfg.AllowedLabels))
+		for _, l := range cfg.AllowedLabels {
+			e.allowed[l] = true
+		}
+	}
+	return e
+}
+
+// Enqueue queues addr for enrichment without waiting; it is dropped when the
+// queue is full.
+func (e *enricher) Enqueue(addr string) {
+	select {
+	case e.queue <- addr:
+	default:
+		enrichmentLookups.With("dropped").Inc()
+	}
+}
+
+// run enriches queued addresses until the process exits.
+func (e *enricher) run() {
+	for addr := range e.queue {
+		e.enrich(addr)
+	}
+}
+
+// enrich merges the provider's labels for addr, keeping only labels in the
+// taxonomy when one is configured.
+func (e *enricher) enrich(addr string) {
+	labels, err := e.lookup(addr)
+	if err != nil {
+		enrichmentLookups.With("failed").Inc()
+		log.Printf("Error looking up labels for %s: %v", addr, err)
+		return
+	}
+	kept := labels[:0:0]
+	for _, l := range labels {
+		if l = strings.TrimSpace(l); l != "" && (e.allowed == nil || e.allowed[l]) {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), enrichmentTimeout)
+	defer cancel()
+	if _, _, err := e.store.PatchAddress(ctx, addr, kept, nil, false, nil); err != nil {
+		log.Printf("Error adding enriched labels to %s: %v", addr, err)
+		return
+	}
+	// Labels can put an address on or off a watchlist
+	invalidateWalletCaches()
+	if err := e.store.NotifyAddressesChanged(ctx); err != nil {
+		log.Printf("Error notifying address change: %v", err)
+	}
+	log.Printf("🏷️  Enriched %s with labels %v", addr, kept)
+}
+
+// lookup returns the provider's labels for addr, from the cache when fresh.
+func (e *enricher) lookup(addr string) ([]string, error) {
+	key := strings.ToLower(addr)
+	e.mu.Lock()
+	c, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && time.Since(c.fetchedAt) < e.ttl {
+		enrichmentLookups.With("hit").Inc()
+		return c.labels, nil
+	}
+
+	if err := e.limiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), enrichmentTimeout)
+	defer cancel()
+	endpoint, err := url.JoinPath(e.cfg.URL, addr)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if e.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.APIKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Labels []string `json:"labels"`
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return nil, fmt.Errorf("decoding label provider response: %w", err)
+		}
+	case http.StatusNotFound:
+		// Unknown to the provider; cache that too
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("label provider returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	enrichmentLookups.With("fetched").Inc()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for k, v := range e.cache {
+		if time.Since(v.fetchedAt) >= e.ttl {
+			delete(e.cache, k)
+		}
+	}
+	e.cache[key] = cachedLabels{labels: out.Labels, fetchedAt: time.Now()}
+	return out.Labels, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ensRegistry is the ENS registry, at the same address on mainnet and the
+// public testnets. Chains without it fail resolution and skip their names.
+var ensRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+var (
+	ensResolverSelector = crypto.Keccak256([]byte("resolver(bytes32)"))[:4]
+	ensAddrSelector     = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+)
+
+const ensLookupTimeout = 10 * time.Second
+
+// isENSName reports whether s looks like an ENS name ("vitalik.eth") rather
+// than a hex address.
+func isENSName(s string) bool {
+	s = strings.TrimSpace(s)
+	if common.IsHexAddress(s) || !strings.Contains(s, ".") || strings.ContainsAny(s, " /") {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ensNamehash implements the EIP-137 namehash of a normalized name.
+func ensNamehash(name string) common.Hash {
+	var node common.Hash
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node[:], crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// resolveENS looks up the address name points to: the registry names the
+// resolver, and the resolver's addr record holds the address.
+func resolveENS(ctx context.Context, client EthClient, name string) (common.Address, error) {
+	node := ensNamehash(name)
+	resolver, err := ensCallAddress(ctx, client, ensRegistry, ensResolverSelector, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("registry lookup: %w", err)
+	}
+	if resolver == (common.Address{}) {
+		return common.Address{}, errors.New("no resolver set")
+	}
+	addr, err := ensCallAddress(ctx, client, resolver, ensAddrSelector, node)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("resolver lookup: %w", err)
+	}
+	if addr == (common.Address{}) {
+		return common.Address{}, errors.New("no address record")
+	}
+	return addr, nil
+}
+
+// ensCallAddress calls a (bytes32) -> address view function on contract.
+func ensCallAddress(ctx context.Context, client EthClient, contract common.Address, selector []byte, node common.Hash) (common.Address, error) {
+	data := append(append([]byte{}, selector...), node[:]...)
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(out) < 32 {
+		// No contract at that address answers with empty output
+		return common.Address{}, nil
+	}
+	return common.BytesToAddress(out[12:32]), nil
+}
+
+// ensResolver keeps the configured ENS names of one chain resolved. Names
+// that fail to resolve are skipped with a warning; one that resolved before
+// keeps its last address until a lookup succeeds again.
+type ensResolver struct {
+	client EthClient
+	chain  string
+	names  []string
+
+	mu    sync.RWMutex
+	addrs map[string]common.Address
+}
+
+// newENSResolver returns a resolver for the ENS names among wallets, or nil
+// when there are none.
+func newENSResolver(client EthClient, chain string, wallets []string) *ensResolver {
+	var names []string
+	for _, w := range wallets {
+		if isENSName(w) {
+			names = append(names, w)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return &ensResolver{client: client, chain: chain, names: names, addrs: make(map[string]common.Address)}
+}
+
+// resolve looks every name up again and reports whether any address changed.
+func (r *ensResolver) resolve(ctx context.Context) bool {
+	changed := false
+	for _, name := range r.names {
+		lookupCtx, cancel := context.WithTimeout(ctx, ensLookupTimeout)
+		addr, err := resolveENS(lookupCtx, r.client, name)
+		cancel()
+		if err != nil {
+			log.Printf("[%s] ⚠️  Could not resolve ENS name %s: %v", r.chain, name, err)
+			continue
+		}
+		r.mu.Lock()
+		if prev, ok := r.addrs[name]; !ok || prev != addr {
+			log.Printf("[%s] 🏷️  ENS name %s resolves to %s", r.chain, name, addr.Hex())
+			r.addrs[name] = addr
+			changed = true
+		}
+		r.mu.Unlock()
+	}
+	return changed
+}
+
+// Lookup returns the last address name resolved to.
+func (r *ensResolver) Lookup(name string) (common.Address, bool) {
+	if r == nil {
+		return common.Address{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	addr, ok := r.addrs[name]
+	return addr, ok
+}
+
+// run re-resolves every interval, reloading the wallet caches when a record
+// changed. It runs until the process exits.
+func (r *ensResolver) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if r.resolve(context.Background()) {
+			invalidateWalletCaches()
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+)
+
+// cliFlags holds command-line overrides, which take precedence over env and file config.
+type cliFlags struct {
+	configPath   string
+	rpcURL       string
+	fromBlock    int64
+	toBlock      int64
+	pollInterval int
+	once         bool
+	dryRun       bool
+}
+
+func parseFlags() cliFlags {
+	var f cliFlags
+	flag.StringVar(&f.configPath, "config", "config.yaml", "path to the YAML config file")
+	flag.StringVar(&f.rpcURL, "rpc-url", "", "RPC endpoint, overriding rpc_url / RPC_URL")
+	flag.Int64Var(&f.fromBlock, "from-block", -1, "first block to scan, overriding stored state (for backfills)")
+	flag.Int64Var(&f.toBlock, "to-block", -1, "with -from-block, backfill exactly that closed range and exit without touching state")
+	flag.IntVar(&f.pollInterval, "poll-interval", 0, "seconds between scans, overriding poll_interval / POLL_INTERVAL")
+	flag.BoolVar(&f.once, "once", false, "scan a single range then exit (for cron-driven batch scans)")
+	flag.BoolVar(&f.dryRun, "dry-run", false, "log what would be sent without calling the analyzer, notifiers or writing transactions")
+	flag.Parse()
+	return f
+}
+
+// apply overlays the flags that were set onto cfg.
+func (f cliFlags) apply(cfg *Config) {
+	if f.rpcURL != "" {
+		cfg.RPCURL = f.rpcURL
+	}
+	if f.pollInterval > 0 {
+		cfg.PollInterval = f.pollInterval
+	}
+	if f.fromBlock >= 0 && f.toBlock >= 0 {
+		from, to := uint64(f.fromBlock), uint64(f.toBlock)
+		cfg.FromBlock, cfg.ToBlock = &from, &to
+	} else if f.fromBlock >= 0 {
+		start := uint64(f.fromBlock)
+		cfg.StartBlock = &start
+	}
+	cfg.Once = f.once
+	if f.dryRun {
+		cfg.DryRun = true
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+// groupCache keeps wallet group memberships in memory, reloading them like the
+// wallet caches: at most once per interval or after an API write.
+type groupCache struct {
+	pool   *pgxpool.Pool
+	groups refreshingSet[map[common.Address][]string]
+}
+
+func newGroupCache(pool *pgxpool.Pool, interval time.Duration) *groupCache {
+	if pool == nil {
+		return nil
+	}
+	return &groupCache{pool: pool, groups: refreshingSet[map[common.Address][]string]{interval: interval}}
+}
+
+// Groups returns the names of the groups addr belongs to, sorted.
+func (c *groupCache) Groups(ctx context.Context, addr common.Address) []string {
+	if c == nil {
+		return nil
+	}
+	groups := c.groups.get(func() (map[common.Address][]string, error) {
+		list, err := dbpkg.FetchGroupMemberships(ctx, c.pool)
+		groups := make(map[common.Address][]string)
+		for _, m := range list {
+			if canonical, ok := normalizeAddress(m.Address); ok {
+				addr := common.HexToAddress(canonical)
+				groups[addr] = append(groups[addr], m.Group)
+			}
+		}
+		return groups, err
+	})
+	return groups[addr]
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nidhish1/BlockSentinel/go-listener/routes"
+)
+
+const (
+	maxQueuedJobs   = 100
+	maxRetainedJobs = 1000
+)
+
+// jobQueue runs on-demand address backfills and analyzer replays from the API
+// on a single worker goroutine, so ad-hoc work never competes with itself for
+// the RPC node or the analyzers. Job history lives in memory only.
+type jobQueue struct {
+	defaultChain string // the only chain scanned; empty with several
+	pending      chan queuedJob
+
+	mu       sync.Mutex
+	scanners map[string]*Scanner
+	jobs     map[string]*routes.Job
+	order    []string // job IDs, oldest first
+}
+
+// queuedJob is a job waiting for the worker; run does the work.
+type queuedJob struct {
+	id  string
+	run func() error
+}
+
+type backfillJob struct {
+	id      string
+	address common.Address
+	req     routes.BackfillRequest
+}
+
+// newJobQueue returns a queue for jobs on chains. A job that names no chain
+// runs on the only one configured; with several it must name its chain.
+func newJobQueue(chains []ChainConfig) *jobQueue {
+	q := &jobQueue{
+		pending:  make(chan queuedJob, maxQueuedJobs),
+		scanners: make(map[string]*Scanner),
+		jobs:     make(map[string]*routes.Job),
+	}
+	if len(chains) == 1 {
+		q.defaultChain = chains[0].Name
+	}
+	return q
+}
+
+// errChainRequired rejects a job that names no chain while several are scanned.
+var errChainRequired = errors.New("chain is required when more than one chain is scanned")
+
+// jobChain returns the chain a job runs on.
+func (q *jobQueue) jobChain(chain string) (string, error) {
+	if chain != "" {
+		return chain, nil
+	}
+	if q.defaultChain == "" {
+		return "", errChainRequired
+	}
+	return q.defaultChain, nil
+}
+
+// register makes a chain's scanner available to backfill jobs.
+func (q *jobQueue) register(s *Scanner) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.scanners[s.chain.Name] = s
+}
+
+func (q *jobQueue) EnqueueBackfill(address string, req routes.BackfillRequest) (routes.Job, error) {
+	chain, err := q.jobChain(req.Chain)
+	if err != nil {
+		return routes.Job{}, err
+	}
+	req.Chain = chain
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.scanners[req.Chain] == nil {
+		return routes.Job{}, fmt.Errorf("chain %q is not being scanned", req.Chain)
+	}
+
+	job := &routes.Job{
+		Kind:      "backfill",
+		Address:   common.HexToAddress(address).Hex(),
+		Chain:     req.Chain,
+		FromBlock: req.FromBlock,
+		ToBlock:   req.ToBlock,
+	}
+	return q.enqueue(job, func(id string) error {
+		from, to, err := q.backfill(backfillJob{id: id, address: common.HexToAddress(address), req: req})
+		q.update(id, func(job *routes.Job) {
+			job.FromBlock, job.ToBlock = from, to
+		})
+		return err
+	})
+}
+
+func (q *jobQueue) EnqueueReplay(req routes.ReplayRequest) (routes.Job, error) {
+	chain, err := q.jobChain(req.Chain)
+	if err != nil {
+		return routes.Job{}, err
+	}
+	req.Chain = chain
+	if req.Concurrency == 0 {
+		req.Concurrency = defaultReplayConcurrency
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := q.scanners[req.Chain]
+	switch {
+	case s == nil:
+		return routes.Job{}, fmt.Errorf("chain %q is not being scanned", req.Chain)
+	case s.pool == nil:
+		return routes.Job{}, errors.New("replay needs Postgres")
+	case len(s.cfg.live().AnalyzerURLs) == 0:
+		return routes.Job{}, errors.New("no analyzer is configured")
+	}
+
+	job := &routes.Job{
+		Kind:      "replay",
+		Chain:     req.Chain,
+		FromBlock: req.FromBlock,
+		ToBlock:   req.ToBlock,
+		Since:     req.Since,
+		Until:     req.Until,
+		Progress:  &routes.JobProgress{},
+	}
+	return q.enqueue(job, func(id string) error {
+		s.printf("🔁 Replay job %s\n", id)
+		return s.replay(context.Background(), replayFilter(s, req), req.Concurrency, func(p routes.JobProgress) {
+			q.update(id, func(job *routes.Job) { *job.Progress = p })
+		})
+	})
+}
+
+// enqueue queues job, which run performs given the job's ID. q.mu must be held.
+func (q *jobQueue) enqueue(job *routes.Job, run func(id string) error) (routes.Job, error) {
+	var raw [8]byte
+	_, _ = rand.Read(raw[:])
+	job.ID = hex.EncodeToString(raw[:])
+	job.Status = "queued"
+	job.CreatedAt = time.Now().UTC()
+	id := job.ID
+	select {
+	case q.pending <- queuedJob{id: id, run: func() error { return run(id) }}:
+	default:
+		return routes.Job{}, routes.ErrJobQueueFull
+	}
+	q.jobs[job.ID] = job
+	q.order = append(q.order, job.ID)
+	q.evict()
+	return snapshot(job), nil
+}
+
+// snapshot copies job so it can be read without q.mu.
+func snapshot(job *routes.Job) routes.Job {
+	out := *job
+	if job.Progress != nil {
+		progress := *job.Progress
+		out.Progress = &progress
+	}
+	return out
+}
+
+// evict drops the oldest finished jobs beyond maxRetainedJobs.
+func (q *jobQueue) evict() {
+	for i := 0; len(q.jobs) > maxRetainedJobs && i < len(q.order); {
+		id := q.order[i]
+		if j := q.jobs[id]; j.Status == "done" || j.Status == "failed" {
+			delete(q.jobs, id)
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			continue
+		}
+		i++
+	}
+}
+
+func (q *jobQueue) Job(id string) (routes.Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return routes.Job{}, false
+	}
+	return snapshot(job), true
+}
+
+// update applies fn to a job under the lock.
+func (q *jobQueue) update(id string, fn func(*routes.Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job, ok := q.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// run processes queued jobs until the process exits.
+func (q *jobQueue) run() {
+	for j := range q.pending {
+		now := time.Now().UTC()
+		q.update(j.id, func(job *routes.Job) {
+			job.Status = "running"
+			job.StartedAt = &now
+		})
+
+		err := j.run()
+
+		done := time.Now().UTC()
+		q.update(j.id, func(job *routes.Job) {
+			job.FinishedAt = &done
+			job.Status = "done"
+			if err != nil {
+				job.Status = "failed"
+				job.Error = err.Error()
+			}
+		})
+	}
+}
+
+// backfill resolves the job's block range against the confirmed head and
+// scans it for the one address on a scanner of its own, since the chain's
+// live loop keeps running meanwhile.
+func (q *jobQueue) backfill(j backfillJob) (from, to *uint64, err error) {
+	q.mu.Lock()
+	s := q.scanners[j.req.Chain]
+	q.mu.Unlock()
+
+	start, end := j.req.FromBlock, j.req.ToBlock
+	if end == nil || j.req.Lookback != nil {
+		header, err := s.client.HeaderByNumber(context.Background(), nil)
+		if err != nil {
+			return start, end, fmt.Errorf("reading chain head: %w", err)
+		}
+		if header == nil || header.Number == nil {
+			return start, end, errEmptyHeader
+		}
+		head := header.Number.Uint64()
+		if c := uint64(s.cfg.Confirmations); head > c {
+			head -= c
+		} else {
+			head = 0
+		}
+		end = &head
+		if j.req.Lookback != nil {
+			first := uint64(0)
+			if head >= *j.req.Lookback {
+				first = head - *j.req.Lookback + 1
+			}
+			start = &first
+		}
+	}
+	if *start > *end {
+		return start, end, fmt.Errorf("from_block %d is past the confirmed head %d", *start, *end)
+	}
+	if *end-*start >= routes.MaxBackfillBlocks {
+		return start, end, fmt.Errorf("blocks %d-%d exceed the %d-block backfill limit; give a to_block", *start, *end, routes.MaxBackfillBlocks)
+	}
+
+	s.printf("🗂️  Backfill job %s for %s\n", j.id, j.address.Hex())
+	return start, end, s.backfillScanner().backfill(map[common.Address]bool{j.address: true}, *start, *end)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/nidhish1/BlockSentinel/go-listener/routes"
+)
+
+func registeredQueue(t *testing.T, names ...string) *jobQueue {
+	t.Helper()
+	chains := make([]ChainConfig, len(names))
+	for i, name := range names {
+		chains[i] = ChainConfig{Name: name}
+	}
+	q := newJobQueue(chains)
+	for _, name := range names {
+		s := newTestScanner(t, &fakeClient{head: headAt(100)}, testConfig())
+		s.chain.Name = name
+		q.register(s)
+	}
+	return q
+}
+
+func TestEnqueueBackfillChain(t *testing.T) {
+	const addr = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	from := uint64(1)
+	tests := []struct {
+		name      string
+		chains    []string
+		chain     string
+		wantChain string
+		wantErr   error
+	}{
+		{name: "single chain by default", chains: []string{"mainnet"}, wantChain: "mainnet"},
+		{name: "named chain", chains: []string{"mainnet", "base"}, chain: "base", wantChain: "base"},
+		{name: "several chains need a name", chains: []string{"mainnet", "base"}, wantErr: errChainRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := registeredQueue(t, tt.chains...)
+			job, err := q.EnqueueBackfill(addr, routes.BackfillRequest{Chain: tt.chain, FromBlock: &from})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if job.Chain != tt.wantChain {
+				t.Errorf("job chain = %q, want %q", job.Chain, tt.wantChain)
+			}
+		})
+	}
+}
+
+func TestEnqueueRejectsUnknownChain(t *testing.T) {
+	from := uint64(1)
+	q := registeredQueue(t, "mainnet", "base")
+	if _, err := q.EnqueueBackfill("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", routes.BackfillRequest{Chain: "optimism", FromBlock: &from}); err == nil {
+		t.Error("backfill accepted for a chain that is not scanned")
+	}
+	if _, err := q.EnqueueReplay(routes.ReplayRequest{FromBlock: &from}); !errors.Is(err, errChainRequired) {
+		t.Errorf("replay err = %v, want errChainRequired", err)
+	}
+}
+
+func TestBackfillRunsOffTheLiveScanner(t *testing.T) {
+	q := newJobQueue([]ChainConfig{{Name: "test"}})
+	client := &fakeClient{head: headAt(3), blocks: map[uint64]*types.Block{1: testBlock(1), 2: testBlock(2), 3: testBlock(3)}}
+	live := newTestScanner(t, client, testConfig())
+	q.register(live)
+
+	from := uint64(1)
+	if _, _, err := q.backfill(backfillJob{req: routes.BackfillRequest{Chain: "test", FromBlock: &from}}); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if _, ok := live.scanned.hash(3); ok {
+		t.Error("backfill recorded its blocks on the live scanner")
+	}
+}
+
+func TestBackfillRangeCap(t *testing.T) {
+	q := newJobQueue([]ChainConfig{{Name: "test"}})
+	q.register(newTestScanner(t, &fakeClient{head: headAt(routes.MaxBackfillBlocks + 10)}, testConfig()))
+
+	from := uint64(0)
+	if _, _, err := q.backfill(backfillJob{req: routes.BackfillRequest{Chain: "test", FromBlock: &from}}); err == nil {
+		t.Error("backfill from block 0 to the head was not rejected")
+	}
+}
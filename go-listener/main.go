@@ -1,104 +1,263 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"context"
+	"encoding/json"
+	"net"
 	"net/http"
 
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/jackc/pgx/v5/pgxpool"
 	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
 	routes "github.com/nidhish1/BlockSentinel/go-listener/routes"
 	utilpkg "github.com/nidhish1/BlockSentinel/go-listener/util"
 )
 
+// HTTP server timeouts, so slow or stalled clients cannot pin connections open.
+const (
+	httpReadHeaderTimeout = 5 * time.Second
+	httpReadTimeout       = 30 * time.Second
+	httpWriteTimeout      = 30 * time.Second
+	httpIdleTimeout       = 120 * time.Second
+)
+
+// applyMigrations brings the schema up to date, or with auto_migrate off only
+// checks it and refuses to start while migrations are pending.
+func applyMigrations(cfg *Config) {
+	current, target, err := utilpkg.MigrationStatus(cfg.DatabaseURL, cfg.MigrationsDir)
+	if err != nil {
+		log.Printf("⚠️  Could not read migration status: %v", err)
+	} else {
+		log.Printf("🗄️  Schema version %d, latest available %d (%s)", current, target, migrationSource(cfg))
+	}
+
+	if !cfg.AutoMigrate {
+		if err == nil && current < target {
+			log.Fatalf("❌ %d migration(s) pending (schema %d, latest %d) and auto_migrate is off; apply them before starting", target-current, current, target)
+		}
+		return
+	}
+
+	if err := utilpkg.RunMigrations(cfg.DatabaseURL, cfg.MigrationsDir); err != nil {
+		log.Printf("⚠️  Migrations failed: %v", err)
+		return
+	}
+	if after, _, err := utilpkg.MigrationStatus(cfg.DatabaseURL, cfg.MigrationsDir); err == nil && after != current {
+		log.Printf("✅ Database migrations applied (version %d -> %d)", current, after)
+	} else {
+		log.Printf("✅ Database migrations applied")
+	}
+}
+
+// serveHTTP starts the API in the background unless http_addr is empty. pool
+// is nil on SQLite, which serves only the address routes; settings is nil
+// without Postgres, which disables GET/PUT /settings.
+func serveHTTP(cfg *Config, pool *pgxpool.Pool, store dbpkg.Store, jobs *jobQueue, settings *settingsLoader) {
+	// Validate has already rejected malformed entries
+	writeAllowlist, _ := routes.ParseCIDRs(cfg.WriteAllowlist)
+	effective, err := cfg.effectiveConfig()
+	if err != nil {
+		log.Printf("⚠️  Could not render effective config for /config: %v", err)
+	}
+	var enrich func(string)
+	if cfg.Enrichment.URL != "" {
+		e := newEnricher(cfg, store)
+		go e.run()
+		enrich = e.Enqueue
+		log.Printf("🏷️  Enriching new addresses from %s", redactURL(cfg.Enrichment.URL, false))
+	}
+	var checkSettings func(map[string]json.RawMessage) error
+	var onSettingsChanged func()
+	if settings != nil {
+		checkSettings, onSettingsChanged = settings.Check, settings.Reload
+	}
+	handler := routes.Handler(pool, routes.Options{
+		MaxBulkAddresses: cfg.MaxBulkAddresses,
+		APIKeys:          cfg.APIKeys,
+		MaxBodyBytes:     int64(cfg.MaxRequestBodyBytes),
+		IdempotencyTTL:   time.Duration(cfg.IdempotencyTTL) * time.Second,
+		AllowedLabels:    cfg.AllowedLabels,
+		Jobs:             jobs,
+		Alerts:           liveAlerts,
+		Readiness:        readinessProblems,
+		Config:           effective,
+		WriteAllowlist:   writeAllowlist,
+		CORSOrigins:      cfg.CORSAllowedOrigins,
+		CORSMethods:      cfg.CORSAllowedMethods,
+		CORSHeaders:      cfg.CORSAllowedHeaders,
+		Store:            store,
+
+		OnAddressesChanged: invalidateWalletCaches,
+		Enrich:             enrich,
+		CheckSettings:      checkSettings,
+		OnSettingsChanged:  onSettingsChanged,
+	})
+	if len(cfg.APIKeys) > 0 {
+		log.Printf("🔐 API key authentication enabled (%d key(s))", len(cfg.APIKeys))
+	}
+	if len(writeAllowlist) > 0 {
+		log.Printf("🛡️  API writes restricted to %v", writeAllowlist)
+	}
+	if cfg.HTTPAddr == "" {
+		log.Printf("ℹ️  http_addr empty; HTTP API disabled")
+		return
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+		ReadTimeout:       httpReadTimeout,
+		WriteTimeout:      httpWriteTimeout,
+		IdleTimeout:       httpIdleTimeout,
+	}
+	scheme := "http"
+	if cfg.TLSCert != "" {
+		// Never fall back to plaintext when TLS was asked for
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			log.Printf("HTTP server error: %v", err)
+			return
+		}
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+		scheme = "https"
+	}
+	ln, err := net.Listen("tcp", cfg.HTTPAddr)
+	if err != nil {
+		log.Printf("HTTP server error: %v", err)
+		return
+	}
+	// Log the bound address, which differs from the config for ":0"
+	log.Printf("🌐 HTTP server listening on %s://%s", scheme, ln.Addr())
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			// ServeTLS also negotiates HTTP/2
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil {
+			log.Printf("HTTP server error: %v", err)
+		}
+	}()
+}
+
+// migrationSource describes where migrations are read from, for the logs.
+func migrationSource(cfg *Config) string {
+	if cfg.MigrationsDir == "" {
+		return "embedded migrations"
+	}
+	return "migrations from " + cfg.MigrationsDir
+}
+
 func main() {
-	cfg, err := loadConfig()
+	flags := parseFlags()
+	cfg, err := loadConfig(flags.configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	flags.apply(cfg)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 
-	// Optional: connect to Postgres if configured (with retry/backoff)
+	// On-demand backfills requested through the API
+	jobs := newJobQueue(cfg.chainConfigs())
+	go jobs.run()
+
+	// Optional: connect to Postgres (with retry/backoff) or open SQLite if configured
 	var dbpool *pgxpool.Pool
-	if cfg.DatabaseURL != "" {
-		pool, dbErr := utilpkg.ConnectPostgresWithBackoff(context.Background(), cfg.DatabaseURL, 60*time.Second)
+	var dbstore dbpkg.Store
+	var settings *settingsLoader
+	switch {
+	case cfg.DatabaseURL == "":
+		log.Printf("ℹ️  DATABASE_URL not set; skipping Postgres connection")
+	case dbpkg.IsSQLiteURL(cfg.DatabaseURL):
+		sqlite, err := dbpkg.OpenSQLite(context.Background(), cfg.DatabaseURL)
+		if err != nil {
+			log.Fatalf("❌ Failed to open SQLite database: %v", err)
+		}
+		log.Printf("✅ Opened SQLite database; transaction storage and Postgres-only API routes are disabled")
+		dbstore = sqlite
+		defer sqlite.Close()
+	default:
+		poolCfg, err := utilpkg.PoolConfig(cfg.DatabaseURL, utilpkg.PoolOptions{
+			MaxConns:          int32(cfg.DBMaxConns),
+			MinConns:          int32(cfg.DBMinConns),
+			MaxConnLifetime:   time.Duration(cfg.DBMaxConnLifetime) * time.Second,
+			HealthCheckPeriod: time.Duration(cfg.DBHealthCheckPeriod) * time.Second,
+		})
+		if err != nil {
+			log.Fatalf("❌ Invalid database_url: %v", err)
+		}
+		pool, dbErr := utilpkg.ConnectPostgresWithBackoff(context.Background(), poolCfg, 60*time.Second)
 		if dbErr != nil {
 			log.Printf("⚠️  Postgres unavailable: %v", dbErr)
 		} else {
 			log.Printf("✅ Connected to Postgres")
-			// Run DB migrations at startup
-			if err := utilpkg.RunMigrations(cfg.DatabaseURL, "./migrations"); err != nil {
-				log.Printf("⚠️  Migrations failed: %v", err)
-			} else {
-				log.Printf("✅ Database migrations applied")
-			}
-			mux := http.NewServeMux()
-			routes.RegisterRoutes(mux, pool)
-			go func() {
-				log.Printf("🌐 HTTP server listening on :8080")
-				if err := http.ListenAndServe(":8080", mux); err != nil {
-					log.Printf("HTTP server error: %v", err)
-				}
-			}()
+			applyMigrations(cfg)
+			go watchAddressChanges(pool)
+			go watchPoolStats(pool)
 			dbpool = pool
-			defer pool.Close()
+			dbstore = dbpkg.NewPGStore(pool)
+			defer dbstore.Close()
+			settings = loadSettings(context.Background(), pool, cfg)
+			go settings.run(time.Duration(cfg.SettingsRefreshInterval) * time.Second)
 		}
-	} else {
-		log.Printf("ℹ️  DATABASE_URL not set; skipping Postgres connection")
 	}
-
-	client, err := ethclient.Dial(cfg.RPCURL)
-	if err != nil {
-		log.Fatalf("Failed to connect to RPC: %v", err)
+	if dbstore != nil {
+		serveHTTP(cfg, dbpool, dbstore, jobs, settings)
 	}
-	defer client.Close()
 
-	fmt.Println("✅ Connected to Ethereum RPC node via Alchemy!")
-	fmt.Println("👛 Monitoring wallets:", cfg.Wallets)
-	if cfg.AIAnalyzerURL != "" {
-		fmt.Println("🤖 AI Analyzer URL:", cfg.AIAnalyzerURL)
+	// Also applies to analyzers added later through the settings table
+	setAnalyzerConcurrency(cfg.AnalyzerConcurrency)
+	setAnalyzerBreakers(cfg)
+	if cfg.RiskLogSummaryInterval > 0 {
+		go riskLogs.run(time.Duration(cfg.RiskLogSummaryInterval) * time.Second)
+	}
+	if len(cfg.AnalyzerURLs) > 0 {
+		for _, u := range cfg.AnalyzerURLs {
+			fmt.Println("🤖 AI Analyzer URL:", u)
+		}
 	} else {
 		fmt.Println("⚠️  AI Analyzer URL not configured - transactions will only be logged")
 	}
 
-	// Load last processed block from state
-	lastBlock, err := loadState("state.json")
+	sinks, err := buildSinks(cfg, dbpool)
 	if err != nil {
-		log.Printf("Error loading state, starting from block 0: %v", err)
-		lastBlock = 0
+		log.Fatalf("❌ Failed to set up alert sinks: %v", err)
 	}
+	setAlertSinks(sinks)
 
-	fmt.Printf("Starting from block %d\n", lastBlock)
+	// Scanner writes ride out Postgres outages in a bounded buffer
+	var writes *writeBuffer
+	if dbpool != nil {
+		writes = newWriteBuffer(dbpool, cfg.DBWriteBufferSize, cfg.DBWriteBufferOverflow)
+		go writes.run()
+	}
 
-	// Main monitoring loop
-	for {
-		// Determine wallets source: prefer DB, fallback to config
-		wallets := cfg.Wallets
-		if dbpool != nil {
-			if w, derr := dbpkg.FetchMonitoredWallets(context.Background(), dbpool); derr == nil && len(w) > 0 {
-				wallets = w
-			}
-		}
+	// Failed analyzer sends are dead-lettered for retry
+	queue := newAnalysisQueue(dbpool, cfg.PendingQueueFile)
+	refreshQueueDepth(context.Background(), queue)
 
-		newLastBlock, err := fetchNewTransactions(client, wallets, lastBlock, cfg.AIAnalyzerURL)
-		if err != nil {
-			log.Printf("Error fetching transactions: %v", err)
-		} else if newLastBlock > lastBlock {
-			// Save state if we processed new blocks
-			err = saveState("state.json", newLastBlock)
-			if err != nil {
-				log.Printf("Error saving state: %v", err)
-			}
-			lastBlock = newLastBlock
-			fmt.Printf("✅ Updated last processed block to %d\n", lastBlock)
-		} else {
-			fmt.Println("⏳ No new blocks to process")
-		}
+	store, err := newStateStore(cfg, dbstore)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up state store: %v", err)
+	}
+	log.Printf("💾 Scan state stored in %s", store.Name())
 
-		fmt.Printf("💤 Sleeping for %d seconds...\n", cfg.PollInterval)
-		time.Sleep(time.Duration(cfg.PollInterval) * time.Second)
+	// One scan loop per chain, sharing the Postgres pool and HTTP server
+	var wg sync.WaitGroup
+	for _, chain := range cfg.chainConfigs() {
+		wg.Add(1)
+		go func(chain ChainConfig) {
+			defer wg.Done()
+			runChain(cfg, chain, dbpool, dbstore, writes, queue, store, jobs)
+		}(chain)
 	}
+	wg.Wait()
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	mempoolResubscribeWait    = 5 * time.Second
+	mempoolMaxResubscribeWait = 2 * time.Minute
+	mempoolLookupTimeout      = 10 * time.Second
+)
+
+// watchMempool subscribes to pending transaction hashes and forwards the ones
+// touching a monitored wallet with status "pending". Pending matches are only
+// analyzed and alerted on: they are not stored, queued for retry, or allowed
+// to move the block state, since the same transaction is picked up again by
+// the block scanner once it is mined. Requires a WebSocket RPC endpoint.
+// rpcClient is consulted on every (re)subscribe, so a reconnected client is
+// picked up.
+func (s *Scanner) watchMempool(rpcClient func() *rpc.Client, chainID *big.Int, wallets *WalletCache) {
+	signer := s.signer
+	wait := mempoolResubscribeWait
+	for {
+		hashes := make(chan common.Hash, 256)
+		sub, err := rpcClient().EthSubscribe(context.Background(), hashes, "newPendingTransactions")
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			s.logf("⚠️  Mempool watching needs a ws:// or wss:// RPC URL; disabled")
+			return
+		}
+		if err != nil {
+			s.logf("Error subscribing to pending transactions: %v (retrying in %s)", err, wait)
+			time.Sleep(wait)
+			if wait *= 2; wait > mempoolMaxResubscribeWait {
+				wait = mempoolMaxResubscribeWait
+			}
+			continue
+		}
+		wait = mempoolResubscribeWait
+		s.printf("👀 Watching mempool for pending transactions\n")
+
+	recv:
+		for {
+			select {
+			case err := <-sub.Err():
+				s.logf("Mempool subscription dropped: %v", err)
+				break recv
+			case hash := <-hashes:
+				s.checkPendingTx(hash, signer, chainID.Uint64(), wallets.Set(context.Background()))
+			}
+		}
+		sub.Unsubscribe()
+		time.Sleep(wait)
+	}
+}
+
+// checkPendingTx fetches one pending transaction and forwards it if it matches.
+func (s *Scanner) checkPendingTx(hash common.Hash, signer types.Signer, chainID uint64, walletSet map[common.Address]bool) {
+	lookupCtx, cancel := context.WithTimeout(context.Background(), mempoolLookupTimeout)
+	defer cancel()
+
+	tx, isPending, err := s.client.TransactionByHash(lookupCtx, hash)
+	if err != nil || !isPending {
+		// Already mined or dropped; the block scanner covers mined transactions
+		return
+	}
+	m, ok := matchTx(tx, signer, walletSet, s.directionFor, s.cfg.ScanCalldataForAddresses)
+	if !ok {
+		return
+	}
+	if min := s.minValueFor(m.wallet); min != nil && tx.Value().Cmp(min) < 0 {
+		return
+	}
+	if !s.filterRules(&m) {
+		return
+	}
+	if s.suppressSpam(tx.Hash(), s.spamMatch(context.Background(), m)) {
+		return
+	}
+
+	nonce := tx.Nonce()
+	p := &TxPayload{
+		Hash:      tx.Hash().Hex(),
+		From:      m.from.Hex(),
+		To:        m.to.Hex(),
+		Value:     tx.Value().String(),
+		Gas:       tx.Gas(),
+		GasPrice:  bigOrZero(tx.GasPrice()),
+		TxType:    tx.Type(),
+		Nonce:     &nonce,
+		ChainID:   chainID,
+		Direction: m.direction,
+		Pending:   true,
+		SeenAt:    time.Now().Unix(),
+	}
+	p.CorrelationID = correlationID(chainID, p.Hash)
+	ctx := withCorrelation(context.Background(), p.CorrelationID)
+	s.setInput(p, tx.Data())
+	p.ReferencedWallets = hexAddresses(m.referenced)
+	p.MatchedRules = m.rules
+	p.ENSName = s.wallets.ENSName(m.wallet)
+	p.Groups = s.groups.Groups(ctx, m.wallet)
+	if m.isCreation {
+		p.Type = "contract_creation"
+		p.ContractAddress = m.created.Hex()
+	} else {
+		s.setMethod(p, m.to, tx.Data())
+	}
+	if tx.Type() >= types.DynamicFeeTxType {
+		p.MaxFeePerGas = bigOrZero(tx.GasFeeCap())
+		p.MaxPriorityFeePerGas = bigOrZero(tx.GasTipCap())
+	}
+	setBlobFields(p, tx, nil)
+
+	jsonData, _ := json.Marshal(p)
+	s.txPrintf(ctx, "⏳ Found pending transaction: %s\n", string(jsonData))
+	publishTransaction(p)
+
+	if len(s.cfg.live().AnalyzerURLs) == 0 || s.dryRun(ctx, m.wallet.Hex(), p) {
+		return
+	}
+	results, err := sendToAnalyzers(ctx, s.cfg, p)
+	if err != nil {
+		s.txLogf(ctx, "Error sending pending transaction to AI analyzer: %v", err)
+	}
+	result := highestRisk(s.cfg, results)
+	if result == nil {
+		return
+	}
+	publishRisk(chainID, m.wallet.Hex(), p, result)
+	s.notifyIfRisky(p, m.wallet.Hex(), result)
+}
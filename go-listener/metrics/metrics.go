@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric is a single float64 sample exposed in Prometheus text format.
+type Metric struct {
+	labels string
+	bits   uint64
+}
+
+// Vec is a metric family partitioned by label values.
+type Vec struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+
+	mu       sync.Mutex
+	children map[string]*Metric
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]*Vec{}
+)
+
+func register(name, help, kind string, labelNames []string) *Vec {
+	mu.Lock()
+	defer mu.Unlock()
+	if v, ok := registry[name]; ok {
+		return v
+	}
+	v := &Vec{name: name, help: help, kind: kind, labelNames: labelNames, children: map[string]*Metric{}}
+	registry[name] = v
+	return v
+}
+
+// NewGauge registers (or returns the existing) unlabeled gauge with the given name.
+func NewGauge(name, help string) *Metric { return register(name, help, "gauge", nil).With() }
+
+// NewCounter registers (or returns the existing) unlabeled counter with the given name.
+func NewCounter(name, help string) *Metric { return register(name, help, "counter", nil).With() }
+
+// NewGaugeVec registers a gauge family keyed by the given label names.
+func NewGaugeVec(name, help string, labelNames ...string) *Vec {
+	return register(name, help, "gauge", labelNames)
+}
+
+// NewCounterVec registers a counter family keyed by the given label names.
+func NewCounterVec(name, help string, labelNames ...string) *Vec {
+	return register(name, help, "counter", labelNames)
+}
+
+// With returns the child metric for the given label values, creating it on first use.
+func (v *Vec) With(labelValues ...string) *Metric {
+	var b strings.Builder
+	for i, name := range v.labelNames {
+		val := ""
+		if i < len(labelValues) {
+			val = labelValues[i]
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", name, val)
+	}
+	key := b.String()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	m, ok := v.children[key]
+	if !ok {
+		m = &Metric{labels: key}
+		v.children[key] = m
+	}
+	return m
+}
+
+// Set stores val as the current value.
+func (m *Metric) Set(val float64) { atomic.StoreUint64(&m.bits, math.Float64bits(val)) }
+
+// Add adds delta to the current value.
+func (m *Metric) Add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&m.bits)
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&m.bits, old, next) {
+			return
+		}
+	}
+}
+
+// Inc adds one to the current value.
+func (m *Metric) Inc() { m.Add(1) }
+
+// Value returns the current value.
+func (m *Metric) Value() float64 { return math.Float64frombits(atomic.LoadUint64(&m.bits)) }
+
+// Handler serves every registered metric in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		vecs := make([]*Vec, 0, len(registry))
+		for _, v := range registry {
+			vecs = append(vecs, v)
+		}
+		mu.Unlock()
+		sort.Slice(vecs, func(i, j int) bool { return vecs[i].name < vecs[j].name })
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, v := range vecs {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+			v.mu.Lock()
+			keys := make([]string, 0, len(v.children))
+			for k := range v.children {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if k == "" {
+					fmt.Fprintf(w, "%s %g\n", v.name, v.children[k].Value())
+				} else {
+					fmt.Fprintf(w, "%s{%s} %g\n", v.name, k, v.children[k].Value())
+				}
+			}
+			v.mu.Unlock()
+		}
+	})
+}
@@ -0,0 +1,14 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Transactions are keyed by chain so one database can serve several chains.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS chain_id BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_hash_key;
+ALTER TABLE transactions ADD CONSTRAINT transactions_chain_hash_key UNIQUE (chain_id, hash);
+CREATE INDEX IF NOT EXISTS idx_transactions_hash ON transactions(hash);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_transactions_hash;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_chain_hash_key;
+ALTER TABLE transactions ADD CONSTRAINT transactions_hash_key UNIQUE (hash);
+ALTER TABLE transactions DROP COLUMN IF EXISTS chain_id;
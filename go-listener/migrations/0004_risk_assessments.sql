@@ -0,0 +1,20 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS risk_assessments (
+    id          BIGSERIAL PRIMARY KEY,
+    chain_id    BIGINT NOT NULL,
+    tx_hash     TEXT NOT NULL,
+    risk_score  DOUBLE PRECISION,
+    category    TEXT,
+    labels      TEXT[],
+    raw         JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (chain_id, tx_hash) REFERENCES transactions(chain_id, hash) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_tx ON risk_assessments(tx_hash, created_at DESC);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_risk_assessments_tx;
+DROP TABLE IF EXISTS risk_assessments;
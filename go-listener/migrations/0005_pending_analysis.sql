@@ -0,0 +1,21 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS pending_analysis (
+    id            BIGSERIAL PRIMARY KEY,
+    chain_id      BIGINT NOT NULL,
+    tx_hash       TEXT NOT NULL,
+    wallet        TEXT NOT NULL,
+    payload       JSONB NOT NULL,
+    attempts      INT NOT NULL DEFAULT 0,
+    last_error    TEXT,
+    first_failed  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    next_attempt  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (chain_id, tx_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_analysis_next ON pending_analysis(chain_id, next_attempt);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_pending_analysis_next;
+DROP TABLE IF EXISTS pending_analysis;
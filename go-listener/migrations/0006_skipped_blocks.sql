@@ -0,0 +1,14 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS skipped_blocks (
+    chain_id     BIGINT NOT NULL,
+    block_num    BIGINT NOT NULL,
+    last_error   TEXT,
+    attempts     INT NOT NULL,
+    skipped_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, block_num)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS skipped_blocks;
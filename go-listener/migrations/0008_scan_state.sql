@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Scan positions for state_backend: postgres, keyed like the state file.
+CREATE TABLE IF NOT EXISTS scan_state (
+    state_key    TEXT PRIMARY KEY,
+    last_block   BIGINT NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS scan_state;
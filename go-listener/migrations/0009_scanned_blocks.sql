@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Audit trail of every block the scanner examined (audit_blocks: true).
+CREATE TABLE IF NOT EXISTS scanned_blocks (
+    chain_id     BIGINT NOT NULL,
+    block_num    BIGINT NOT NULL,
+    block_hash   TEXT NOT NULL,
+    tx_count     INT NOT NULL,
+    match_count  INT NOT NULL,
+    scanned_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, block_num, scanned_at)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS scanned_blocks;
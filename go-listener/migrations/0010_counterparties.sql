@@ -0,0 +1,14 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Known-bad counterparties (sanctioned, mixers, ...) screened against every match.
+CREATE TABLE IF NOT EXISTS counterparties (
+    address      TEXT PRIMARY KEY,
+    category     TEXT NOT NULL,
+    source       TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS counterparties;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Named wallet groups (an exchange's hot wallets, a protocol treasury, ...)
+-- that matches are attributed to.
+CREATE TABLE IF NOT EXISTS wallet_groups (
+    name         TEXT PRIMARY KEY,
+    description  TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS wallet_group_members (
+    group_name   TEXT NOT NULL REFERENCES wallet_groups(name) ON DELETE CASCADE,
+    address      TEXT NOT NULL,
+    added_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_name, address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_group_members_address ON wallet_group_members(address);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS wallet_group_members;
+DROP TABLE IF EXISTS wallet_groups;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- With several analyzers configured each stores its own verdict; NULL for
+-- rows written before analyzers were recorded.
+ALTER TABLE risk_assessments ADD COLUMN IF NOT EXISTS analyzer TEXT;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+ALTER TABLE risk_assessments DROP COLUMN IF EXISTS analyzer;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- The 4-byte selector each transaction calls, and its name when decoded.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS method_selector TEXT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS method_name TEXT;
+
+UPDATE transactions SET method_selector = '0x' || lower(substr(input_hex, 1, 8))
+ WHERE method_selector IS NULL AND to_address IS NOT NULL AND length(input_hex) >= 8;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_block_timestamp ON transactions(block_timestamp);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_transactions_block_timestamp;
+ALTER TABLE transactions DROP COLUMN IF EXISTS method_name;
+ALTER TABLE transactions DROP COLUMN IF EXISTS method_selector;
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	erc721TransferTopic  = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	erc1155SingleTopic   = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	erc1155BatchTopic    = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+	erc1155BatchDataArgs = func() abi.Arguments {
+		uints, _ := abi.NewType("uint256[]", "", nil)
+		return abi.Arguments{{Type: uints}, {Type: uints}}
+	}()
+)
+
+// nftTransfer is one decoded ERC-721 or ERC-1155 transfer event.
+type nftTransfer struct {
+	standard   string // "erc721" or "erc1155"
+	collection common.Address
+	from       common.Address
+	to         common.Address
+	tokenIDs   []*big.Int
+	amounts    []*big.Int // ERC-1155 only
+	txHash     common.Hash
+	logIndex   uint
+	txIndex    uint
+	blockNum   uint64
+	direction  string
+	rules      []string // configured rules its transaction satisfies
+}
+
+// nftEnabled reports whether any NFT standard is being watched.
+func (s *Scanner) nftEnabled() bool {
+	return s.cfg.EnableERC721 || s.cfg.EnableERC1155
+}
+
+// maxTopicWallets caps the wallets in one log filter, since providers limit
+// how many topic values a single eth_getLogs call may carry.
+const maxTopicWallets = 500
+
+// fetchNFTTransfers returns the NFT transfers in [from, to] that involve a
+// monitored wallet, grouped by block number. The node filters the logs by
+// wallet; the sender and recipient sit in different topic positions, so each
+// standard needs one query per side and per chunk of wallets.
+func (s *Scanner) fetchNFTTransfers(ctx context.Context, walletSet map[common.Address]bool, from, to uint64) (map[uint64][]nftTransfer, error) {
+	if !s.nftEnabled() || len(walletSet) == 0 {
+		return nil, nil
+	}
+	wallets := make([]common.Hash, 0, len(walletSet))
+	for addr := range walletSet {
+		wallets = append(wallets, common.BytesToHash(addr.Bytes()))
+	}
+
+	// Query both sides; per-wallet direction filters are applied per transfer
+	var queries [][][]common.Hash
+	for len(wallets) > 0 {
+		chunk := wallets[:min(len(wallets), maxTopicWallets)]
+		wallets = wallets[len(chunk):]
+		if s.cfg.EnableERC721 {
+			sig := []common.Hash{erc721TransferTopic}
+			queries = append(queries,
+				[][]common.Hash{sig, chunk},
+				[][]common.Hash{sig, nil, chunk},
+			)
+		}
+		if s.cfg.EnableERC1155 {
+			sig := []common.Hash{erc1155SingleTopic, erc1155BatchTopic}
+			queries = append(queries,
+				[][]common.Hash{sig, nil, chunk},
+				[][]common.Hash{sig, nil, nil, chunk},
+			)
+		}
+	}
+
+	type logKey struct {
+		tx    common.Hash
+		index uint
+	}
+	seen := make(map[logKey]bool)
+	byBlock := make(map[uint64][]nftTransfer)
+	for _, topics := range queries {
+		logs, err := s.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Topics:    topics,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logs {
+			// A self-transfer matches both the sender and recipient query
+			key := logKey{l.TxHash, l.Index}
+			if seen[key] || l.Removed {
+				continue
+			}
+			t, ok := decodeNFTTransfer(l)
+			if !ok {
+				continue
+			}
+			if t.direction, ok = matchDirection(walletFilter(walletSet, s.directionFor, t.from), walletFilter(walletSet, s.directionFor, t.to)); !ok {
+				continue
+			}
+			seen[key] = true
+			byBlock[l.BlockNumber] = append(byBlock[l.BlockNumber], t)
+		}
+	}
+	for _, ts := range byBlock {
+		sort.Slice(ts, func(i, j int) bool { return ts[i].logIndex < ts[j].logIndex })
+	}
+	return byBlock, nil
+}
+
+// decodeNFTTransfer decodes an ERC-721 or ERC-1155 transfer log. ERC-20
+// Transfer events share the ERC-721 signature but carry the amount in data
+// instead of an indexed token id, so they are rejected by topic count.
+func decodeNFTTransfer(l types.Log) (nftTransfer, bool) {
+	if len(l.Topics) == 0 {
+		return nftTransfer{}, false
+	}
+	t := nftTransfer{
+		collection: l.Address,
+		txHash:     l.TxHash,
+		logIndex:   l.Index,
+		txIndex:    l.TxIndex,
+		blockNum:   l.BlockNumber,
+	}
+	switch l.Topics[0] {
+	case erc721TransferTopic:
+		if len(l.Topics) != 4 {
+			return nftTransfer{}, false
+		}
+		t.standard = "erc721"
+		t.from = common.BytesToAddress(l.Topics[1].Bytes())
+		t.to = common.BytesToAddress(l.Topics[2].Bytes())
+		t.tokenIDs = []*big.Int{l.Topics[3].Big()}
+	case erc1155SingleTopic:
+		if len(l.Topics) != 4 || len(l.Data) < 64 {
+			return nftTransfer{}, false
+		}
+		t.standard = "erc1155"
+		t.from = common.BytesToAddress(l.Topics[2].Bytes())
+		t.to = common.BytesToAddress(l.Topics[3].Bytes())
+		t.tokenIDs = []*big.Int{new(big.Int).SetBytes(l.Data[:32])}
+		t.amounts = []*big.Int{new(big.Int).SetBytes(l.Data[32:64])}
+	case erc1155BatchTopic:
+		if len(l.Topics) != 4 {
+			return nftTransfer{}, false
+		}
+		values, err := erc1155BatchDataArgs.Unpack(l.Data)
+		if err != nil || len(values) != 2 {
+			return nftTransfer{}, false
+		}
+		ids, ok1 := values[0].([]*big.Int)
+		amounts, ok2 := values[1].([]*big.Int)
+		if !ok1 || !ok2 {
+			return nftTransfer{}, false
+		}
+		t.standard = "erc1155"
+		t.from = common.BytesToAddress(l.Topics[2].Bytes())
+		t.to = common.BytesToAddress(l.Topics[3].Bytes())
+		t.tokenIDs = ids
+		t.amounts = amounts
+	default:
+		return nftTransfer{}, false
+	}
+	return t, true
+}
+
+func bigStrings(values []*big.Int) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = v.String()
+	}
+	return out
+}
+
+// processNFTTransfer builds the alert payload for one NFT transfer, stores the
+// enclosing transaction and forwards the transfer to the analyzer.
+func (s *Scanner) processNFTTransfer(ctx context.Context, block *types.Block, chainID uint64, signer types.Signer, t nftTransfer, walletSet map[common.Address]bool) {
+	wallet := t.from
+	if t.direction == directionIncoming {
+		wallet = t.to
+	}
+	logIndex, txIndex := t.logIndex, t.txIndex
+	p := &TxPayload{
+		Type:         "nft_transfer",
+		Standard:     t.standard,
+		Hash:         t.txHash.Hex(),
+		Collection:   t.collection.Hex(),
+		From:         t.from.Hex(),
+		To:           t.to.Hex(),
+		Value:        "0",
+		TokenIDs:     bigStrings(t.tokenIDs),
+		LogIndex:     &logIndex,
+		TxIndex:      &txIndex,
+		BlockNum:     block.NumberU64(),
+		Timestamp:    block.Time(),
+		TimestampISO: isoTime(block.Time()),
+		ChainID:      chainID,
+		Direction:    t.direction,
+	}
+	p.CorrelationID = correlationID(chainID, p.Hash)
+	ctx = withCorrelation(ctx, p.CorrelationID)
+	if t.amounts != nil {
+		p.Amounts = bigStrings(t.amounts)
+	}
+	tx := block.Transaction(t.txHash)
+	if tx != nil {
+		nonce := tx.Nonce()
+		p.Nonce = &nonce
+		p.Gas = tx.Gas()
+		p.GasPrice = minedGasPrice(tx, block.BaseFee())
+		p.TxType = tx.Type()
+		setBlobFields(p, tx, nil)
+	}
+	s.flagCounterparty(ctx, p, wallet, t.from, t.to)
+	p.MatchedRules = t.rules
+	p.ENSName = s.wallets.ENSName(wallet)
+	p.Groups = s.groups.Groups(ctx, wallet)
+
+	jsonData, _ := json.Marshal(p)
+	s.txPrintf(ctx, "Found NFT transfer: %s\n", string(jsonData))
+
+	// The enclosing transaction may also be a native match, so transfers are
+	// deduplicated per log rather than through the stored transaction row
+	if s.markSeen(ctx, fmt.Sprintf("%d:%s:%d", chainID, t.txHash.Hex(), t.logIndex)) {
+		s.txPrintf(ctx, "↩️  Already forwarded NFT transfer %s#%d recently; skipping\n", t.txHash.Hex(), t.logIndex)
+		return
+	}
+
+	if m, ok := enclosingTx(block, signer, t); ok {
+		s.storeTransaction(ctx, transactionRecord(chainID, block, m, nil))
+	}
+	s.analyze(ctx, chainID, wallet.Hex(), p)
+}
+
+// enclosingTx returns the transaction that emitted t as a match for storage and
+// rule evaluation. ok is false when the block lacks it or its sender cannot be
+// recovered.
+func enclosingTx(block *types.Block, signer types.Signer, t nftTransfer) (m matchedTx, ok bool) {
+	tx := block.Transaction(t.txHash)
+	if tx == nil {
+		return matchedTx{}, false
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return matchedTx{}, false
+	}
+	m = matchedTx{tx: tx, from: from, isCreation: tx.To() == nil}
+	if m.isCreation {
+		m.created = crypto.CreateAddress(from, tx.Nonce())
+	} else {
+		m.to = *tx.To()
+	}
+	return m, true
+}
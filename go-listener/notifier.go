@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// notifyTimeout bounds each notification so a slow sink never holds up scanning.
+const notifyTimeout = 10 * time.Second
+
+// Notification is the alert payload sent for a risky matched transaction.
+type Notification struct {
+	TxHash    string  `json:"tx_hash"`
+	Wallet    string  `json:"wallet"`
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Value     string  `json:"value"`
+	BlockNum  uint64  `json:"block_num"`
+	RiskScore float64 `json:"risk_score"`
+	RiskLevel string  `json:"risk_level,omitempty"`
+
+	ENSName string   `json:"ens_name,omitempty"` // the wallet's configured ENS name
+	Groups  []string `json:"groups,omitempty"`   // the wallet's groups, for routing per team
+
+	CorrelationID string `json:"correlation_id,omitempty"` // also sent as X-Correlation-ID
+}
+
+// HealthAlert reports a scanner stalling or recovering.
+type HealthAlert struct {
+	Chain     string    `json:"chain"`
+	Status    string    `json:"status"` // "stalled" or "recovered"
+	LastBlock uint64    `json:"last_block"`
+	HeadBlock uint64    `json:"head_block"` // latest confirmed block
+	Since     time.Time `json:"since"`      // last time the scanner was healthy
+}
+
+// Notifier delivers a notification to an external sink.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+	NotifyDigest(ctx context.Context, d Digest) error
+	NotifyHealth(ctx context.Context, a HealthAlert) error
+}
+
+// NotifierConfig selects and configures a notifier.
+type NotifierConfig struct {
+	Type string `yaml:"type"` // "webhook" or "slack"
+	URL  string `yaml:"url"`
+}
+
+func buildNotifiers(cfgs []NotifierConfig) ([]Notifier, error) {
+	var out []Notifier
+	for _, c := range cfgs {
+		switch c.Type {
+		case "webhook":
+			out = append(out, &webhookNotifier{url: c.URL})
+		case "slack":
+			out = append(out, &slackNotifier{webhookURL: c.URL})
+		default:
+			return nil, fmt.Errorf("unknown notifier type %q", c.Type)
+		}
+	}
+	return out, nil
+}
+
+// dispatchNotification fans n out to every notifier in the background.
+func dispatchNotification(notifiers []Notifier, n Notification) {
+	for _, nt := range notifiers {
+		go func(nt Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if n.CorrelationID != "" {
+				ctx = withCorrelation(ctx, n.CorrelationID)
+			}
+			if err := nt.Notify(ctx, n); err != nil {
+				logCorrelated(ctx, "Error sending %s notification for %s: %v", nt.Name(), n.TxHash, err)
+			}
+		}(nt)
+	}
+}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := correlationFrom(ctx); id != "" {
+		req.Header.Set(correlationHeader, id)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
+
+// webhookNotifier POSTs the notification as JSON to a generic endpoint.
+type webhookNotifier struct {
+	url string
+}
+
+func (w *webhookNotifier) Name() string { return "webhook" }
+
+func (w *webhookNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, w.url, n)
+}
+
+func (w *webhookNotifier) NotifyDigest(ctx context.Context, d Digest) error {
+	return postJSON(ctx, w.url, d)
+}
+
+func (w *webhookNotifier) NotifyHealth(ctx context.Context, a HealthAlert) error {
+	return postJSON(ctx, w.url, a)
+}
+
+// slackNotifier posts a formatted message to a Slack incoming webhook.
+type slackNotifier struct {
+	webhookURL string
+}
+
+func (s *slackNotifier) Name() string { return "slack" }
+
+func (s *slackNotifier) Notify(ctx context.Context, n Notification) error {
+	wallet := "`" + n.Wallet + "`"
+	if n.ENSName != "" {
+		wallet = n.ENSName + " (" + wallet + ")"
+	}
+	text := fmt.Sprintf(":rotating_light: Risky transaction for wallet %s\n"+
+		"*Tx:* `%s` (block %d)\n*Value:* %s wei\n*Risk:* %.2f %s",
+		wallet, n.TxHash, n.BlockNum, n.Value, n.RiskScore, n.RiskLevel)
+	if len(n.Groups) > 0 {
+		text += "\n*Groups:* " + strings.Join(n.Groups, ", ")
+	}
+	return postJSON(ctx, s.webhookURL, map[string]string{"text": text})
+}
+
+func (s *slackNotifier) NotifyDigest(ctx context.Context, d Digest) error {
+	var top []string
+	for _, c := range d.TopCounterparties {
+		top = append(top, fmt.Sprintf("`%s` (%d)", c.Address, c.Count))
+	}
+	text := fmt.Sprintf(":rotating_light: %d risky transactions for wallet `%s` in the last %s\n"+
+		"*Total value:* %s wei\n*Max risk:* %.2f %s",
+		d.Count, d.Wallet, d.WindowEnd.Sub(d.WindowStart).Round(time.Second), d.TotalValue, d.MaxRiskScore, d.MaxRiskLevel)
+	if len(top) > 0 {
+		text += "\n*Top counterparties:* " + strings.Join(top, ", ")
+	}
+	return postJSON(ctx, s.webhookURL, map[string]string{"text": text})
+}
+
+func (s *slackNotifier) NotifyHealth(ctx context.Context, a HealthAlert) error {
+	text := fmt.Sprintf(":white_check_mark: Scanner for `%s` recovered at block %d", a.Chain, a.LastBlock)
+	if a.Status == "stalled" {
+		text = fmt.Sprintf(":warning: Scanner for `%s` stalled at block %d since %s (confirmed head %d)",
+			a.Chain, a.LastBlock, a.Since.Format(time.RFC3339), a.HeadBlock)
+	}
+	return postJSON(ctx, s.webhookURL, map[string]string{"text": text})
+}
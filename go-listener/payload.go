@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Payload key styles for analyzer requests.
+const (
+	payloadNamingCamel = "camel"
+	payloadNamingSnake = "snake"
+)
+
+// How addresses appear in analyzer requests.
+const (
+	addressModeFull     = "full"
+	addressModeHash     = "hash"     // hex SHA-256 of the lowercase address
+	addressModeTruncate = "truncate" // "0x1234…abcd"
+)
+
+// TxPayload is the alert for one matched transaction, NFT transfer or pending
+// transaction, as sent to the analyzer, live subscribers and the retry queue.
+// Keys are camelCase; analyzer requests can use snake_case instead (see
+// encodePayload).
+type TxPayload struct {
+	Hash      string  `json:"hash"`
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Value     string  `json:"value"` // wei
+	Gas       uint64  `json:"gas"`
+	GasPrice  string  `json:"gasPrice"` // wei
+	TxType    uint8   `json:"txType"`
+	Nonce     *uint64 `json:"nonce,omitempty"`
+	ChainID   uint64  `json:"chainId"`
+	Direction string  `json:"direction"`
+
+	// Tags this transaction's log lines and outbound requests; see correlationID
+	CorrelationID string `json:"correlationId,omitempty"`
+
+	// Mined transactions only
+	TxIndex      *uint  `json:"txIndex,omitempty"` // position within the block
+	BlockNum     uint64 `json:"blockNum,omitempty"`
+	Timestamp    uint64 `json:"timestamp,omitempty"`
+	TimestampISO string `json:"timestampISO,omitempty"`
+
+	// Pending transactions only. Pending is sent as "status": "pending" in
+	// place of the receipt status (see MarshalJSON).
+	Pending bool  `json:"-"`
+	SeenAt  int64 `json:"seenAt,omitempty"` // unix seconds
+
+	Input          string `json:"input"` // hex, cut to MaxInputBytes
+	InputTruncated bool   `json:"inputTruncated,omitempty"`
+	InputBytes     int    `json:"inputBytes,omitempty"` // original size when truncated
+
+	ValueUSD             *float64 `json:"valueUSD,omitempty"`
+	MaxFeePerGas         string   `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string   `json:"maxPriorityFeePerGas,omitempty"`
+
+	Type            string                 `json:"type,omitempty"` // "contract_creation" or "nft_transfer"
+	ContractAddress string                 `json:"contractAddress,omitempty"`
+	Method          string                 `json:"method,omitempty"`
+	MethodSignature string                 `json:"methodSignature,omitempty"`
+	Args            map[string]interface{} `json:"args,omitempty"`
+
+	// From the receipt, when one was fetched
+	Status            *uint64 `json:"status,omitempty"`
+	GasUsed           *uint64 `json:"gasUsed,omitempty"`
+	EffectiveGasPrice string  `json:"effectiveGasPrice,omitempty"`
+
+	// Blob (EIP-4844) transactions only
+	BlobVersionedHashes []string `json:"blobVersionedHashes,omitempty"`
+	MaxFeePerBlobGas    string   `json:"maxFeePerBlobGas,omitempty"`
+	BlobGasUsed         *uint64  `json:"blobGasUsed,omitempty"`
+	BlobGasPrice        string   `json:"blobGasPrice,omitempty"` // from the receipt
+
+	InternalTransfers []InternalTransfer `json:"internalTransfers,omitempty"`
+
+	// Monitored wallets referenced in the calldata (scan_calldata_for_addresses)
+	ReferencedWallets []string `json:"referencedWallets,omitempty"`
+
+	ENSName      string   `json:"ensName,omitempty"`      // configured ENS name of the matched wallet
+	Groups       []string `json:"groups,omitempty"`       // wallet groups the matched wallet belongs to
+	MatchedRules []string `json:"matchedRules,omitempty"` // names of the configured rules it satisfies
+
+	Counterparty     string `json:"counterparty,omitempty"`
+	CounterpartyRisk string `json:"counterpartyRisk,omitempty"`
+
+	// NFT transfers only
+	Standard   string   `json:"standard,omitempty"`
+	Collection string   `json:"collection,omitempty"`
+	TokenIDs   []string `json:"tokenIds,omitempty"`
+	Amounts    []string `json:"amounts,omitempty"` // ERC-1155 only
+	LogIndex   *uint    `json:"logIndex,omitempty"`
+}
+
+// pendingStatus is the "status" of a transaction that has not been mined.
+const pendingStatus = "pending"
+
+// MarshalJSON writes Pending as "status": "pending". A pending transaction has
+// no receipt, so the key never carries both.
+func (p TxPayload) MarshalJSON() ([]byte, error) {
+	type plain TxPayload
+	if !p.Pending {
+		return json.Marshal(plain(p))
+	}
+	return json.Marshal(struct {
+		plain
+		Status string `json:"status"`
+	}{plain(p), pendingStatus})
+}
+
+// UnmarshalJSON reads "status" back into Pending or the receipt Status.
+func (p *TxPayload) UnmarshalJSON(data []byte) error {
+	type plain TxPayload
+	var wire struct {
+		plain
+		Status json.RawMessage `json:"status"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*p = TxPayload(wire.plain)
+	if string(wire.Status) == `"`+pendingStatus+`"` {
+		p.Pending = true
+		return nil
+	}
+	if len(wire.Status) == 0 {
+		return nil
+	}
+	return json.Unmarshal(wire.Status, &p.Status)
+}
+
+// InternalTransfer is a value transfer inside a traced contract call.
+type InternalTransfer struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value string `json:"value"` // wei
+	Type  string `json:"type"`  // call frame type, e.g. "CALL"
+}
+
+// encodePayload marshals p for the analyzer: addresses are redacted per
+// cfg.AnalyzerAddressMode, only the top-level fields in cfg.AnalyzerFields are
+// kept (every field when it is empty), and keys use cfg.PayloadNaming. Nested
+// keys, such as decoded argument names, are left alone.
+func encodePayload(p *TxPayload, cfg *Config) ([]byte, error) {
+	if cfg.AnalyzerAddressMode != "" && cfg.AnalyzerAddressMode != addressModeFull {
+		p = redactAddresses(p, cfg.AnalyzerAddressMode)
+	}
+	data, err := json.Marshal(p)
+	if err != nil || (cfg.PayloadNaming != payloadNamingSnake && len(cfg.AnalyzerFields) == 0) {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool, len(cfg.AnalyzerFields))
+	for _, f := range cfg.AnalyzerFields {
+		allowed[f] = true
+	}
+	out := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		if len(allowed) > 0 && !allowed[k] {
+			continue
+		}
+		if cfg.PayloadNaming == payloadNamingSnake {
+			k = snakeCase(k)
+		}
+		out[k] = v
+	}
+	return json.Marshal(out)
+}
+
+// payloadFields lists the top-level TxPayload keys, for validating
+// analyzer_fields.
+func payloadFields() map[string]bool {
+	t := reflect.TypeOf(TxPayload{})
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// redactAddresses returns a copy of p with every address field rewritten
+// according to mode. Hashes are stable, so the analyzer can still correlate
+// activity per address without learning it.
+func redactAddresses(p *TxPayload, mode string) *TxPayload {
+	redact := func(addr string) string {
+		if addr == "" {
+			return ""
+		}
+		if mode == addressModeHash {
+			sum := sha256.Sum256([]byte(strings.ToLower(addr)))
+			return hex.EncodeToString(sum[:])
+		}
+		if len(addr) <= 10 {
+			return addr
+		}
+		return addr[:6] + "…" + addr[len(addr)-4:]
+	}
+
+	out := *p
+	out.From = redact(p.From)
+	out.To = redact(p.To)
+	out.ContractAddress = redact(p.ContractAddress)
+	out.Counterparty = redact(p.Counterparty)
+	out.Collection = redact(p.Collection)
+	// The name identifies the wallet as surely as its address
+	out.ENSName = ""
+	if len(p.ReferencedWallets) > 0 {
+		out.ReferencedWallets = make([]string, len(p.ReferencedWallets))
+		for i, w := range p.ReferencedWallets {
+			out.ReferencedWallets[i] = redact(w)
+		}
+	}
+	if len(p.InternalTransfers) > 0 {
+		out.InternalTransfers = make([]InternalTransfer, len(p.InternalTransfers))
+		for i, t := range p.InternalTransfers {
+			t.From, t.To = redact(t.From), redact(t.To)
+			out.InternalTransfers[i] = t
+		}
+	}
+	return &out
+}
+
+// snakeCase converts a camelCase key to snake_case, keeping acronyms together:
+// "blockNum" becomes "block_num" and "timestampISO" becomes "timestamp_iso".
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1])
+			acronymEnd := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
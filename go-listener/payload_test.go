@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTxPayloadStatus(t *testing.T) {
+	success := uint64(1)
+	tests := []struct {
+		name    string
+		payload TxPayload
+		want    string
+	}{
+		{"pending", TxPayload{Hash: "0x1", Pending: true}, `"status":"pending"`},
+		{"mined", TxPayload{Hash: "0x1", Status: &success}, `"status":1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(&tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) || strings.Contains(string(data), `"pending":`) {
+				t.Fatalf("payload = %s, want it to contain %s", data, tt.want)
+			}
+			var back TxPayload
+			if err := json.Unmarshal(data, &back); err != nil {
+				t.Fatal(err)
+			}
+			if back.Pending != tt.payload.Pending || (back.Status == nil) != (tt.payload.Status == nil) {
+				t.Errorf("round trip = pending %v status %v, want pending %v status %v",
+					back.Pending, back.Status, tt.payload.Pending, tt.payload.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	defaultCoinGeckoURL   = "https://api.coingecko.com/api/v3"
+	defaultPriceAsset     = "ethereum"
+	defaultPriceCacheSecs = 3600
+	priceRequestTimeout   = 10 * time.Second
+	maxCachedPrices       = 1024
+)
+
+// PriceFeed looks up the USD price of the chain's native token.
+type PriceFeed interface {
+	Name() string
+	// PriceUSD returns the price at the given block and its timestamp (unix seconds).
+	PriceUSD(ctx context.Context, blockNum, timestamp uint64) (float64, error)
+}
+
+// newPriceFeed builds the configured feed, or nil when USD conversion is off.
+func newPriceFeed(cfg *Config, client EthClient) (PriceFeed, error) {
+	var feed PriceFeed
+	switch strings.ToLower(cfg.PriceFeedType) {
+	case "":
+		return nil, nil
+	case "coingecko":
+		base := cfg.PriceFeedURL
+		if base == "" {
+			base = defaultCoinGeckoURL
+		}
+		asset := cfg.PriceFeedAsset
+		if asset == "" {
+			asset = defaultPriceAsset
+		}
+		feed = &coinGeckoFeed{baseURL: strings.TrimRight(base, "/"), coinID: asset}
+	case "chainlink":
+		if !common.IsHexAddress(cfg.PriceFeedAsset) {
+			return nil, fmt.Errorf("chainlink price feed needs an aggregator address in price_feed_asset")
+		}
+		feed = &chainlinkFeed{client: client, aggregator: common.HexToAddress(cfg.PriceFeedAsset), decimals: -1}
+	default:
+		return nil, fmt.Errorf("unknown price feed type %q", cfg.PriceFeedType)
+	}
+	bucket := cfg.PriceCacheSeconds
+	if bucket <= 0 {
+		bucket = defaultPriceCacheSecs
+	}
+	return &cachedPriceFeed{feed: feed, bucket: uint64(bucket), prices: make(map[uint64]float64)}, nil
+}
+
+// errPriceUnavailable is returned for a bucket whose lookup already failed.
+var errPriceUnavailable = errors.New("price unavailable")
+
+// cachedPriceFeed memoizes prices per time bucket so each bucket costs at most
+// one lookup. Failed lookups are cached as NaN so an unreachable oracle is not
+// retried for every transaction in the same bucket.
+type cachedPriceFeed struct {
+	feed   PriceFeed
+	bucket uint64 // seconds
+
+	mu     sync.Mutex
+	prices map[uint64]float64
+}
+
+func (c *cachedPriceFeed) Name() string { return c.feed.Name() }
+
+func (c *cachedPriceFeed) PriceUSD(ctx context.Context, blockNum, timestamp uint64) (float64, error) {
+	key := timestamp / c.bucket
+	c.mu.Lock()
+	price, ok := c.prices[key]
+	c.mu.Unlock()
+	if ok {
+		if math.IsNaN(price) {
+			return 0, errPriceUnavailable
+		}
+		return price, nil
+	}
+
+	price, err := c.feed.PriceUSD(ctx, blockNum, timestamp)
+	c.mu.Lock()
+	if len(c.prices) >= maxCachedPrices {
+		c.prices = make(map[uint64]float64)
+	}
+	if err != nil {
+		c.prices[key] = math.NaN()
+	} else {
+		c.prices[key] = price
+	}
+	c.mu.Unlock()
+	return price, err
+}
+
+// coinGeckoFeed reads daily historical prices from the CoinGecko API.
+type coinGeckoFeed struct {
+	baseURL string
+	coinID  string
+}
+
+func (f *coinGeckoFeed) Name() string { return "coingecko" }
+
+func (f *coinGeckoFeed) PriceUSD(ctx context.Context, _, timestamp uint64) (float64, error) {
+	date := time.Unix(int64(timestamp), 0).UTC().Format("02-01-2006")
+	endpoint := fmt.Sprintf("%s/coins/%s/history?date=%s&localization=false", f.baseURL, url.PathEscape(f.coinID), date)
+
+	ctx, cancel := context.WithTimeout(ctx, priceRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("coingecko returned %s", resp.Status)
+	}
+
+	var body struct {
+		MarketData struct {
+			CurrentPrice map[string]float64 `json:"current_price"`
+		} `json:"market_data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	price, ok := body.MarketData.CurrentPrice["usd"]
+	if !ok {
+		return 0, fmt.Errorf("coingecko has no USD price for %s on %s", f.coinID, date)
+	}
+	return price, nil
+}
+
+var (
+	chainlinkLatestRoundData = common.Hex2Bytes("feaf968c") // latestRoundData()
+	chainlinkDecimals        = common.Hex2Bytes("313ce567") // decimals()
+)
+
+// chainlinkFeed reads an on-chain Chainlink aggregator at the transaction's
+// block. Historical blocks need an archive node.
+type chainlinkFeed struct {
+	client     EthClient
+	aggregator common.Address
+
+	mu       sync.Mutex
+	decimals int // -1 until read from the aggregator
+}
+
+func (f *chainlinkFeed) Name() string { return "chainlink" }
+
+func (f *chainlinkFeed) PriceUSD(ctx context.Context, blockNum, _ uint64) (float64, error) {
+	decimals, err := f.aggregatorDecimals(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := f.client.CallContract(ctx, ethereum.CallMsg{To: &f.aggregator, Data: chainlinkLatestRoundData}, new(big.Int).SetUint64(blockNum))
+	if err != nil {
+		return 0, err
+	}
+	if len(out) < 64 {
+		return 0, fmt.Errorf("short latestRoundData response")
+	}
+	// answer is the second 32-byte word, a signed int256
+	answer := new(big.Int).SetBytes(out[32:64])
+	if answer.Sign() <= 0 || out[32]&0x80 != 0 {
+		return 0, fmt.Errorf("invalid aggregator answer")
+	}
+	price, _ := new(big.Float).Quo(new(big.Float).SetInt(answer), new(big.Float).SetFloat64(math.Pow10(decimals))).Float64()
+	return price, nil
+}
+
+// aggregatorDecimals reads and caches the aggregator's answer precision.
+func (f *chainlinkFeed) aggregatorDecimals(ctx context.Context) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.decimals >= 0 {
+		return f.decimals, nil
+	}
+	out, err := f.client.CallContract(ctx, ethereum.CallMsg{To: &f.aggregator, Data: chainlinkDecimals}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("reading aggregator decimals: %w", err)
+	}
+	if len(out) < 32 {
+		return 0, fmt.Errorf("short decimals response")
+	}
+	f.decimals = int(out[31])
+	return f.decimals, nil
+}
+
+// weiPerEther converts native-token amounts from wei.
+var weiPerEther = new(big.Float).SetFloat64(1e18)
+
+// valueUSD converts a wei amount at the given block into USD. ok is false when
+// no feed is configured or the price could not be fetched.
+func (s *Scanner) valueUSD(ctx context.Context, wei *big.Int, blockNum, timestamp uint64) (float64, bool) {
+	if s.prices == nil || wei == nil {
+		return 0, false
+	}
+	price, err := s.prices.PriceUSD(ctx, blockNum, timestamp)
+	if err != nil {
+		// Only the first failure per bucket is worth logging
+		if !errors.Is(err, errPriceUnavailable) {
+			s.logf("Price lookup via %s failed for block %d: %v", s.prices.Name(), blockNum, err)
+		}
+		return 0, false
+	}
+	eth := new(big.Float).Quo(new(big.Float).SetInt(wei), weiPerEther)
+	usd, _ := new(big.Float).Mul(eth, big.NewFloat(price)).Float64()
+	return math.Round(usd*100) / 100, true
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+const (
+	defaultRPCMaxReconnects = 10
+	rpcReconnectBaseWait    = time.Second
+	rpcReconnectMaxWait     = 30 * time.Second
+)
+
+var (
+	rpcRequestsTotal = metrics.NewCounterVec("blocksentinel_rpc_requests_total", "RPC calls made to the node.", "chain")
+	rpcErrorsTotal   = metrics.NewCounterVec("blocksentinel_rpc_errors_total", "RPC calls that returned an error.", "chain")
+	rpcLastSuccess   = metrics.NewGaugeVec("blocksentinel_rpc_last_success_timestamp_seconds", "Unix time of the last RPC call that succeeded.", "chain")
+)
+
+// errRPCReconnectFailed marks a node that stayed unreachable through every
+// reconnect attempt. The chain loop treats it as fatal.
+var errRPCReconnectFailed = errors.New("RPC reconnect failed")
+
+// reconnectingClient is an EthClient that re-dials the node when a call fails
+// at the connection level and retries that call once on the new connection.
+// WebSocket connections in particular never recover on their own once dropped.
+type reconnectingClient struct {
+	url         string
+	name        string // chain name for log lines
+	maxAttempts int
+
+	mu     sync.Mutex
+	client *ethclient.Client
+	gen    uint64 // bumped on every reconnect
+
+	requests, failures, lastOK *metrics.Metric
+}
+
+func dialReconnecting(ctx context.Context, url, name string, maxAttempts int) (*reconnectingClient, error) {
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &reconnectingClient{
+		url:         url,
+		name:        name,
+		maxAttempts: maxAttempts,
+		client:      client,
+		requests:    rpcRequestsTotal.With(name),
+		failures:    rpcErrorsTotal.With(name),
+		lastOK:      rpcLastSuccess.With(name),
+	}, nil
+}
+
+func (c *reconnectingClient) current() (*ethclient.Client, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client, c.gen
+}
+
+// RPC returns the current raw RPC connection, for subscriptions.
+func (c *reconnectingClient) RPC() *rpc.Client {
+	client, _ := c.current()
+	return client.Client()
+}
+
+func (c *reconnectingClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.client.Close()
+}
+
+// reconnect replaces the connection seen at generation gen, unless another
+// caller already replaced it. It gives up after maxAttempts dials.
+func (c *reconnectingClient) reconnect(ctx context.Context, gen uint64, cause error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return nil
+	}
+
+	wait := rpcReconnectBaseWait
+	var err error
+	for i := 1; i <= c.maxAttempts; i++ {
+		log.Printf("[%s] 🔌 RPC connection lost (%v); reconnecting (attempt %d/%d)", c.name, cause, i, c.maxAttempts)
+		var client *ethclient.Client
+		client, err = ethclient.DialContext(ctx, c.url)
+		if err == nil {
+			// HTTP dials are lazy, so prove the node answers before switching
+			if _, err = client.ChainID(ctx); err == nil {
+				c.client.Close()
+				c.client = client
+				c.gen++
+				log.Printf("[%s] 🔌 Reconnected to RPC node", c.name)
+				return nil
+			}
+			client.Close()
+		}
+		cause = err
+		if i == c.maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > rpcReconnectMaxWait {
+			wait = rpcReconnectMaxWait
+		}
+	}
+	return fmt.Errorf("%w after %d attempts: %v", errRPCReconnectFailed, c.maxAttempts, err)
+}
+
+// do runs call, reconnecting and retrying once if it failed at the
+// connection level.
+func (c *reconnectingClient) do(ctx context.Context, call func(*ethclient.Client) error) error {
+	client, gen := c.current()
+	err := c.observe(call(client))
+	if !isConnectionError(err) || ctx.Err() != nil {
+		return err
+	}
+	if rerr := c.reconnect(ctx, gen, err); rerr != nil {
+		return rerr
+	}
+	client, _ = c.current()
+	return c.observe(call(client))
+}
+
+// observe records the outcome of one call in the RPC metrics.
+func (c *reconnectingClient) observe(err error) error {
+	c.requests.Inc()
+	if err != nil {
+		c.failures.Inc()
+	} else {
+		c.lastOK.Set(float64(time.Now().Unix()))
+	}
+	return err
+}
+
+// isConnectionError reports whether err means the transport itself failed, as
+// opposed to the node rejecting the request or the caller giving up.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rpcErr rpc.Error
+	var httpErr rpc.HTTPError
+	if errors.As(err, &rpcErr) || errors.As(err, &httpErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, rpc.ErrClientQuit) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		(errors.As(err, &netErr) && !netErr.Timeout())
+}
+
+func (c *reconnectingClient) HeaderByNumber(ctx context.Context, number *big.Int) (h *types.Header, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { h, err = cl.HeaderByNumber(ctx, number); return err })
+	return h, err
+}
+
+func (c *reconnectingClient) BlockByNumber(ctx context.Context, number *big.Int) (b *types.Block, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { b, err = cl.BlockByNumber(ctx, number); return err })
+	return b, err
+}
+
+func (c *reconnectingClient) NetworkID(ctx context.Context) (id *big.Int, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { id, err = cl.NetworkID(ctx); return err })
+	return id, err
+}
+
+func (c *reconnectingClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { logs, err = cl.FilterLogs(ctx, q); return err })
+	return logs, err
+}
+
+func (c *reconnectingClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (out []byte, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { out, err = cl.CallContract(ctx, msg, blockNumber); return err })
+	return out, err
+}
+
+func (c *reconnectingClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (r *types.Receipt, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { r, err = cl.TransactionReceipt(ctx, txHash); return err })
+	return r, err
+}
+
+func (c *reconnectingClient) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { tx, isPending, err = cl.TransactionByHash(ctx, hash); return err })
+	return tx, isPending, err
+}
+
+func (c *reconnectingClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (rs []*types.Receipt, err error) {
+	err = c.do(ctx, func(cl *ethclient.Client) error { rs, err = cl.BlockReceipts(ctx, blockNrOrHash); return err })
+	return rs, err
+}
+
+func (c *reconnectingClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return c.do(ctx, func(cl *ethclient.Client) error { return cl.Client().CallContext(ctx, result, method, args...) })
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// cacheRetryWait spaces out reloads of the in-memory address lists while the
+// store keeps failing, so a DB outage does not cost a query per lookup.
+const cacheRetryWait = 30 * time.Second
+
+// refreshingSet holds an address lookup loaded from the store, reloading it at
+// most once per interval or after invalidateWalletCaches. Through load errors
+// it keeps serving the last good value and waits cacheRetryWait before trying
+// again; until a load has succeeded it serves what the failed load returned.
+type refreshingSet[T any] struct {
+	interval time.Duration
+
+	mu         sync.Mutex
+	value      T
+	loaded     bool
+	loadedAt   time.Time
+	failedAt   time.Time
+	generation uint64
+}
+
+// get returns the current value, reloading it with load first when it is stale.
+func (r *refreshingSet[T]) get(load func() (T, error)) T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	gen := walletGeneration.Load()
+	if r.loaded && gen == r.generation && time.Since(r.loadedAt) < r.interval {
+		return r.value
+	}
+	if time.Since(r.failedAt) < cacheRetryWait {
+		return r.value
+	}
+	value, err := load()
+	if err != nil {
+		// Keep serving the last good value through transient DB errors
+		if !r.loaded {
+			r.value = value
+		}
+		r.failedAt = time.Now()
+		return r.value
+	}
+	r.value, r.loaded = value, true
+	r.loadedAt, r.failedAt = time.Now(), time.Time{}
+	r.generation = gen
+	return value
+}
+
+// peek returns the current value without reloading it.
+func (r *refreshingSet[T]) peek() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.value
+}
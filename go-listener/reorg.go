@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+const defaultMaxReorgDepth = 64
+
+// What the scanner does when a reorg goes deeper than MaxReorgDepth.
+const (
+	reorgActionHalt   = "halt"   // keep retrying at the current block until an operator steps in
+	reorgActionResync = "resync" // continue from StartupLookback blocks behind the confirmed head
+)
+
+var reorgsDetected = metrics.NewCounterVec("blocksentinel_reorgs_total", "Reorgs that replaced blocks the listener had already scanned.", "chain")
+
+// errReorgTooDeep is returned, with reorg_action halt, when no common ancestor
+// is found within MaxReorgDepth blocks.
+var errReorgTooDeep = errors.New("reorg deeper than max_reorg_depth")
+
+// scannedHashes remembers the hashes of recently scanned blocks, so a reorg
+// can be walked back to the common ancestor. Blocks more than the kept depth
+// below the newest one are dropped.
+type scannedHashes struct {
+	mu     sync.Mutex
+	newest uint64
+	hashes map[uint64]common.Hash
+}
+
+// record stores hash for block number, keeping blocks at most keep below the
+// newest recorded.
+func (h *scannedHashes) record(number uint64, hash common.Hash, keep uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if number > h.newest {
+		h.newest = number
+	}
+	if h.newest-number > keep {
+		return
+	}
+	if h.hashes == nil {
+		h.hashes = make(map[uint64]common.Hash)
+	}
+	h.hashes[number] = hash
+	for n := range h.hashes {
+		if h.newest-n > keep {
+			delete(h.hashes, n)
+		}
+	}
+}
+
+func (h *scannedHashes) hash(number uint64) (common.Hash, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hash, ok := h.hashes[number]
+	return hash, ok
+}
+
+// rewind forgets every block above number.
+func (h *scannedHashes) rewind(number uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for n := range h.hashes {
+		if n > number {
+			delete(h.hashes, n)
+		}
+	}
+	h.newest = number
+}
+
+// canonicalHash returns the hash of block number on the node's chain.
+func (s *Scanner) canonicalHash(ctx context.Context, number uint64) (common.Hash, error) {
+	header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if header == nil {
+		return common.Hash{}, errEmptyHeader
+	}
+	return header.Hash(), nil
+}
+
+// checkReorg compares block lastBlock with the hash recorded when it was
+// scanned. If a reorg replaced it, checkReorg walks back to the newest block
+// still on the chain and returns it, so the replaced blocks are scanned again.
+// The walk gives up after MaxReorgDepth blocks rather than rewinding towards
+// genesis; ReorgAction then halts or resyncs from StartupLookback.
+//
+// Blocks never downloaded (scan_mode logs) or scanned before a restart have
+// no recorded hash: the cursor is not checked, and a walk that reaches one
+// rescans from there.
+func (s *Scanner) checkReorg(ctx context.Context, lastBlock, latestBlock uint64) (uint64, error) {
+	want, ok := s.scanned.hash(lastBlock)
+	if !ok {
+		return lastBlock, nil
+	}
+	got, err := s.canonicalHash(ctx, lastBlock)
+	if err != nil || got == want {
+		return lastBlock, err
+	}
+	reorgsDetected.With(s.chain.Name).Inc()
+
+	depth := uint64(s.cfg.MaxReorgDepth)
+	for back := uint64(1); back <= depth && back <= lastBlock; back++ {
+		n := lastBlock - back
+		want, ok := s.scanned.hash(n)
+		if ok {
+			if got, err = s.canonicalHash(ctx, n); err != nil {
+				return lastBlock, err
+			}
+		}
+		if !ok || got == want {
+			s.printf("🔀 Reorg replaced blocks %d-%d; rescanning from block %d\n", n+1, lastBlock, n+1)
+			s.scanned.rewind(n)
+			return n, nil
+		}
+	}
+
+	s.logf("🚨 CRITICAL: block %d was reorged and no common ancestor was found within max_reorg_depth (%d blocks)", lastBlock, depth)
+	if s.cfg.ReorgAction != reorgActionResync {
+		return lastBlock, fmt.Errorf("%w: block %d", errReorgTooDeep, lastBlock)
+	}
+	resync := s.lookbackStart(latestBlock)
+	s.printf("⏩ Resyncing from block %d (latest confirmed: %d)\n", resync, latestBlock)
+	s.scanned.rewind(0)
+	return resync, nil
+}
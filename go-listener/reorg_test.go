@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fork replaces blocks from through to in client with blocks of a competing
+// chain, which hash differently.
+func fork(client *fakeClient, from, to uint64) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.blocks == nil {
+		client.blocks = map[uint64]*types.Block{}
+	}
+	for n := from; n <= to; n++ {
+		client.blocks[n] = types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(n), Time: 1_000_000 + n})
+	}
+}
+
+// scanTo scans a fresh scanner up to head and returns it.
+func scanTo(t *testing.T, client *fakeClient, cfg *Config, head uint64) *Scanner {
+	t.Helper()
+	s := newTestScanner(t, client, cfg)
+	client.head = headAt(head)
+	if last, err := s.fetchNewTransactions(map[common.Address]bool{}, 0); err != nil || last != head {
+		t.Fatalf("initial scan = (%d, %v), want (%d, nil)", last, err, head)
+	}
+	return s
+}
+
+func TestReorgRescansFromCommonAncestor(t *testing.T) {
+	client := &fakeClient{}
+	s := scanTo(t, client, testConfig(), 5)
+
+	fork(client, 4, 6)
+	client.head = headAt(6)
+	last, err := s.fetchNewTransactions(map[common.Address]bool{}, 5)
+	if err != nil {
+		t.Fatalf("fetchNewTransactions: %v", err)
+	}
+	if last != 3 {
+		t.Fatalf("last block = %d, want the common ancestor 3", last)
+	}
+
+	client.fetched = nil
+	if last, err = s.fetchNewTransactions(map[common.Address]bool{}, last); err != nil || last != 6 {
+		t.Fatalf("rescan = (%d, %v), want (6, nil)", last, err)
+	}
+	if got, want := client.fetchedBlocks(), []uint64{4, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("rescanned blocks %v, want %v", got, want)
+	}
+}
+
+func TestForkAboveCursorIsNotAReorg(t *testing.T) {
+	client := &fakeClient{}
+	s := scanTo(t, client, testConfig(), 5)
+
+	// Blocks above the cursor changing is not a reorg of anything scanned
+	fork(client, 6, 7)
+	client.head = headAt(7)
+	if last, err := s.fetchNewTransactions(map[common.Address]bool{}, 5); err != nil || last != 7 {
+		t.Fatalf("fetchNewTransactions = (%d, %v), want (7, nil)", last, err)
+	}
+}
+
+func TestReorgDeeperThanMaxHalts(t *testing.T) {
+	client := &fakeClient{}
+	cfg := testConfig()
+	cfg.MaxReorgDepth = 2
+	s := scanTo(t, client, cfg, 5)
+
+	fork(client, 1, 6)
+	client.head = headAt(6)
+	client.fetched = nil
+	last, err := s.fetchNewTransactions(map[common.Address]bool{}, 5)
+	if !errors.Is(err, errReorgTooDeep) {
+		t.Fatalf("err = %v, want errReorgTooDeep", err)
+	}
+	if last != 5 {
+		t.Errorf("last block = %d, want it held at 5", last)
+	}
+	if got := client.fetchedBlocks(); len(got) != 0 {
+		t.Errorf("fetched blocks %v after giving up on the reorg", got)
+	}
+}
+
+func TestReorgDeeperThanMaxResyncs(t *testing.T) {
+	client := &fakeClient{}
+	cfg := testConfig()
+	cfg.MaxReorgDepth = 2
+	cfg.ReorgAction = reorgActionResync
+	s := scanTo(t, client, cfg, 5)
+
+	cfg.StartupLookback = 3
+	fork(client, 1, 20)
+	client.head = headAt(20)
+	last, err := s.fetchNewTransactions(map[common.Address]bool{}, 5)
+	if err != nil {
+		t.Fatalf("fetchNewTransactions: %v", err)
+	}
+	if last != 17 {
+		t.Errorf("last block = %d, want 17 (head 20 minus startup_lookback 3)", last)
+	}
+}
+
+func TestScannedHashesKeepsMaxDepth(t *testing.T) {
+	var h scannedHashes
+	for n := uint64(1); n <= 10; n++ {
+		h.record(n, common.BigToHash(new(big.Int).SetUint64(n)), 3)
+	}
+	for n := uint64(1); n <= 10; n++ {
+		if _, ok := h.hash(n); ok != (n >= 7) {
+			t.Errorf("block %d kept = %v, want %v", n, ok, n >= 7)
+		}
+	}
+	// An old block, say from a backfill, does not evict recent ones
+	h.record(2, common.Hash{}, 3)
+	if _, ok := h.hash(2); ok || len(h.hashes) != 4 {
+		t.Errorf("old block recorded; kept %d hashes", len(h.hashes))
+	}
+}
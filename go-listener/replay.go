@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/routes"
+)
+
+const (
+	defaultReplayConcurrency = 4
+	replayPageSize           = 500
+	replayLogEvery           = 100 // transactions between progress log lines
+)
+
+// replayFilter selects the stored transactions a replay request covers.
+func replayFilter(s *Scanner, req routes.ReplayRequest) dbpkg.TransactionFilter {
+	return dbpkg.TransactionFilter{
+		ChainID:   s.chainID.Uint64(),
+		FromBlock: req.FromBlock,
+		ToBlock:   req.ToBlock,
+		Since:     req.Since,
+		Until:     req.Until,
+	}
+}
+
+// replay re-sends the stored transactions matching f to the analyzers, with
+// up to concurrency requests in flight, and stores each new verdict. Replayed
+// verdicts are not published or notified: the transactions are history. It
+// reports progress after every transaction and fails only when the stored
+// transactions cannot be read.
+func (s *Scanner) replay(ctx context.Context, f dbpkg.TransactionFilter, concurrency int, progress func(routes.JobProgress)) error {
+	total, err := dbpkg.CountTransactions(ctx, s.pool, f)
+	if err != nil {
+		return fmt.Errorf("counting stored transactions: %w", err)
+	}
+	var mu sync.Mutex
+	state := routes.JobProgress{Total: total}
+	progress(state)
+
+	walletSet := s.wallets.Set(ctx)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	var afterID int64
+	for {
+		page, err := dbpkg.ListTransactions(ctx, s.pool, f, afterID, replayPageSize)
+		if err != nil {
+			return fmt.Errorf("reading stored transactions: %w", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		afterID = page[len(page)-1].ID
+
+		for _, t := range page {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(t dbpkg.Transaction) {
+				defer func() { <-sem; wg.Done() }()
+				ok := s.replayTransaction(ctx, walletSet, t)
+
+				mu.Lock()
+				defer mu.Unlock()
+				state.Done++
+				if !ok {
+					state.Failed++
+				}
+				progress(state)
+				if state.Done%replayLogEvery == 0 || state.Done == state.Total {
+					s.printf("🔁 Replayed %d/%d transaction(s), %d failed\n", state.Done, state.Total, state.Failed)
+				}
+			}(t)
+		}
+	}
+	return nil
+}
+
+// replayTransaction rebuilds the alert payload for a stored transaction and
+// stores every analyzer's verdict on it, reporting whether any analyzer
+// answered. Fields the table does not keep, such as the nonce and internal
+// transfers, are left out.
+func (s *Scanner) replayTransaction(ctx context.Context, walletSet map[common.Address]bool, t dbpkg.Transaction) bool {
+	p := &TxPayload{
+		Hash:         t.Hash,
+		From:         t.From,
+		Value:        t.ValueWei,
+		GasPrice:     t.GasPriceWei,
+		BlockNum:     t.BlockNum,
+		Timestamp:    t.BlockTimestamp,
+		TimestampISO: isoTime(t.BlockTimestamp),
+		ChainID:      t.ChainID,
+	}
+	p.CorrelationID = correlationID(t.ChainID, t.Hash)
+	ctx = withCorrelation(ctx, p.CorrelationID)
+	from := common.HexToAddress(t.From)
+	var to common.Address
+	data := common.FromHex(t.InputHex)
+	s.setInput(p, data)
+	if t.To != nil {
+		to = common.HexToAddress(*t.To)
+		p.To = to.Hex()
+		s.setMethod(p, to, data)
+	} else {
+		p.Type = "contract_creation"
+	}
+	if t.GasUsed != nil {
+		gasUsed := uint64(*t.GasUsed)
+		p.GasUsed = &gasUsed
+	}
+
+	// The wallet may have been removed since; the analyzer still gets the
+	// transaction, attributed to its sender
+	p.Direction, _ = matchDirection(walletFilter(walletSet, s.directionFor, from), walletFilter(walletSet, s.directionFor, to))
+	wallet := from
+	if p.Direction == directionIncoming {
+		wallet = to
+	}
+	p.ENSName = s.wallets.ENSName(wallet)
+	p.Groups = s.groups.Groups(ctx, wallet)
+
+	if s.dryRun(ctx, wallet.Hex(), p) {
+		return true
+	}
+	results, err := sendToAnalyzers(ctx, s.cfg, p)
+	if err != nil {
+		s.txLogf(ctx, "Error replaying %s: %v", t.Hash, err)
+	}
+	for analyzer, result := range results {
+		s.storeRiskAssessment(ctx, t.ChainID, t.Hash, analyzer, result)
+	}
+	return len(results) > 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultRiskLogSummary = 60 // seconds
+
+// riskLog collapses repeated analyzer verdicts in the logs. A verdict below
+// the risk threshold that matches the previous one from the same analyzer is
+// counted rather than logged, and the counts are logged as one line per
+// analyzer every summary interval. Elevated and changed verdicts always log.
+type riskLog struct {
+	mu         sync.Mutex
+	last       map[string]string // analyzer URL -> fingerprint of its last verdict
+	suppressed map[string]int    // analyzer URL -> repeats since the last summary
+}
+
+var riskLogs = &riskLog{last: map[string]string{}, suppressed: map[string]int{}}
+
+// logVerdict logs result from the analyzer at base unless it repeats a
+// low-risk verdict. A zero RiskLogSummaryInterval or debug logging logs every
+// verdict.
+func (l *riskLog) logVerdict(ctx context.Context, cfg *Config, base string, result *RiskResult) {
+	if cfg.RiskLogSummaryInterval > 0 && cfg.LogLevel != logLevelDebug {
+		fingerprint := fmt.Sprintf("%s|%.2f|%s", result.Level, result.Score, strings.Join(result.Reasons, "|"))
+		elevated := result.Score >= cfg.live().RiskThreshold
+
+		l.mu.Lock()
+		repeat := !elevated && l.last[base] == fingerprint
+		l.last[base] = fingerprint
+		if repeat {
+			l.suppressed[base]++
+		}
+		l.mu.Unlock()
+		if repeat {
+			return
+		}
+	}
+	logCorrelated(ctx, "Risk Analysis from %s: %s (score %.2f) %v", redactURL(base, false), result.Level, result.Score, result.Reasons)
+}
+
+// run logs the suppressed counts every interval.
+func (l *riskLog) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		l.mu.Lock()
+		counts := l.suppressed
+		l.suppressed = map[string]int{}
+		l.mu.Unlock()
+		for _, base := range sortedKeys(counts) {
+			log.Printf("🔇 %d repeated low-risk verdict(s) from %s in the last %s", counts[base], redactURL(base, false), interval)
+		}
+	}
+}
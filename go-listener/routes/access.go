@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var (
+	// DefaultCORSMethods are allowed cross-origin when none are configured.
+	DefaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	// DefaultCORSHeaders are allowed cross-origin when none are configured.
+	DefaultCORSHeaders = []string{"Authorization", "Content-Type", IdempotencyHeader}
+)
+
+// ParseCIDRs parses an allowlist of CIDR ranges; bare IPs are taken as
+// single-host ranges.
+func ParseCIDRs(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if p, err := netip.ParsePrefix(e); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR or IP %q", e)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// isWrite reports whether method can change server state.
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// RestrictWrites rejects mutating requests from clients outside allowed with
+// 403. Reads are unaffected, and it is a no-op when allowed is empty. The
+// client is the TCP peer; proxy headers are not trusted.
+func RestrictWrites(allowed []netip.Prefix, next http.Handler) http.Handler {
+	if len(allowed) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWrite(r.Method) && !clientAllowed(r.RemoteAddr, allowed) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientAllowed(remoteAddr string, allowed []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range allowed {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS adds cross-origin headers for browser clients on the configured origins
+// ("*" allows any) and answers preflight requests, which carry no credentials,
+// before authentication runs. Empty methods or headers use the defaults. It is
+// a no-op when no origins are configured.
+func CORS(origins, methods, headers []string, next http.Handler) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[strings.TrimRight(o, "/")] = true
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	methodAllowed := func(m string) bool {
+		for _, am := range methods {
+			if strings.EqualFold(am, m) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(allowed["*"] || allowed[origin]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		method := r.Method
+		if preflight {
+			method = r.Header.Get("Access-Control-Request-Method")
+		}
+		if methodAllowed(method) {
+			if allowed["*"] {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,22 +1,43 @@
 package routes
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
 )
 
-type Address struct {
-	Address   string     `json:"address"`
-	FirstSeen *time.Time `json:"first_seen,omitempty"`
-	LastSeen  *time.Time `json:"last_seen,omitempty"`
-	Labels    []string   `json:"labels,omitempty"`
-	CreatedAt *time.Time `json:"created_at,omitempty"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+// Address is the body and response of the address routes.
+type Address = dbpkg.Address
+
+// AddressPatch is the body of PATCH /addresses/{address}. Settings, when
+// present, replaces the stored settings; an explicit null clears them.
+type AddressPatch struct {
+	Add      []string        `json:"add,omitempty"`
+	Remove   []string        `json:"remove,omitempty"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// parseSettings validates a settings object, returning nil for JSON null.
+func parseSettings(raw json.RawMessage) ([]byte, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	var ws dbpkg.WalletSettings
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ws); err != nil {
+		return nil, fmt.Errorf("invalid settings: %v", err)
+	}
+	if err := ws.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(ws)
 }
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
@@ -25,34 +46,36 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func registerAddressRoutes(mux *http.ServeMux, db *pgxpool.Pool) {
+func registerAddressRoutes(mux *http.ServeMux, spec *apiSpec, store dbpkg.Store, opts Options) {
+	taxonomy := newLabelTaxonomy(opts.AllowedLabels)
+	idempotent := newIdempotencyCache(opts.IdempotencyTTL)
+
 	// POST /addresses
-	mux.HandleFunc("/addresses", func(w http.ResponseWriter, r *http.Request) {
+	spec.add(http.MethodPost, "/addresses", apiOp{Summary: "Create or update an address", Tag: "addresses", Request: Address{}, Response: map[string]string{}, Status: http.StatusCreated})
+	mux.HandleFunc("/addresses", idempotent.wrap(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
 			var in Address
 			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+				writeDecodeError(w, err)
 				return
 			}
 			if strings.TrimSpace(in.Address) == "" {
 				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "address required"})
 				return
 			}
+			if !taxonomy.check(w, in.Labels) {
+				return
+			}
 			ctx := context.Background()
-			_, err := db.Exec(ctx,
-				`INSERT INTO addresses(address, first_seen, last_seen, labels)
-                 VALUES ($1, $2, $3, $4)
-                 ON CONFLICT (address) DO UPDATE SET first_seen = COALESCE(EXCLUDED.first_seen, addresses.first_seen),
-                                             last_seen = COALESCE(EXCLUDED.last_seen, addresses.last_seen),
-                                             labels = COALESCE(EXCLUDED.labels, addresses.labels),
-                                             updated_at = NOW()`,
-				in.Address, in.FirstSeen, in.LastSeen, toTextArray(in.Labels),
-			)
-			if err != nil {
+			if err := store.UpsertAddress(ctx, in); err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 				return
 			}
+			opts.addressesChanged()
+			if opts.Enrich != nil {
+				opts.Enrich(in.Address)
+			}
 			writeJSON(w, http.StatusCreated, map[string]string{"status": "ok"})
 		case http.MethodGet:
 			// Optional: list with pagination
@@ -61,55 +84,127 @@ func registerAddressRoutes(mux *http.ServeMux, db *pgxpool.Pool) {
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
-	})
+	}))
+
+	// POST /addresses/bulk
+	spec.add(http.MethodPost, "/addresses/bulk", apiOp{Summary: "Import a JSON array of addresses", Tag: "addresses", Request: []Address{}, Response: bulkResult{}})
+	mux.HandleFunc("/addresses/bulk", idempotent.wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		maxBatch := opts.MaxBulkAddresses
+		if maxBatch <= 0 {
+			maxBatch = DefaultMaxBulkAddresses
+		}
+		batch, err := decodeAddressBatch(r.Body, maxBatch)
+		if err != nil {
+			if errors.Is(err, errBatchTooLarge) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()})
+				return
+			}
+			writeDecodeError(w, err)
+			return
+		}
+		res, err := importAddresses(context.Background(), store, batch, taxonomy)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		opts.addressesChanged()
+		writeJSON(w, http.StatusOK, res)
+	}))
 
-	// GET/PUT/DELETE /addresses/{address}
+	// GET/PUT/PATCH/DELETE /addresses/{address}
+	spec.add(http.MethodGet, "/addresses/{address}", apiOp{Summary: "Get an address", Tag: "addresses", Response: Address{}})
+	spec.add(http.MethodPut, "/addresses/{address}", apiOp{Summary: "Replace an address's fields", Tag: "addresses", Request: Address{}, Response: map[string]string{}})
+	spec.add(http.MethodPatch, "/addresses/{address}", apiOp{Summary: "Add/remove labels and set alert settings", Tag: "addresses", Request: AddressPatch{}, Response: Address{}})
+	spec.add(http.MethodDelete, "/addresses/{address}", apiOp{Summary: "Soft-delete an address (hard=true purges it)", Tag: "addresses", Query: []string{"hard"}, Response: map[string]string{}})
 	mux.HandleFunc("/addresses/", func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/addresses/")
 		if path == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "address required"})
 			return
 		}
+		if addr, ok := strings.CutSuffix(path, "/backfill"); ok {
+			handleBackfill(w, r, addr, opts.Jobs)
+			return
+		}
 		addr := path
 		ctx := context.Background()
 
 		switch r.Method {
 		case http.MethodGet:
-			var out Address
-			var labels []string
-			err := db.QueryRow(ctx,
-				`SELECT address, first_seen, last_seen, labels, created_at, updated_at
-                 FROM addresses WHERE address = $1`, addr,
-			).Scan(&out.Address, &out.FirstSeen, &out.LastSeen, &labels, &out.CreatedAt, &out.UpdatedAt)
-			if err != nil {
+			out, err := store.GetAddress(ctx, addr)
+			if errors.Is(err, dbpkg.ErrNotFound) {
 				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 				return
 			}
-			out.Labels = labels
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
 			writeJSON(w, http.StatusOK, out)
 
 		case http.MethodPut:
 			var in Address
 			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+				writeDecodeError(w, err)
 				return
 			}
-			_, err := db.Exec(ctx,
-				`UPDATE addresses SET first_seen=$2, last_seen=$3, labels=$4, updated_at=NOW() WHERE address=$1`,
-				addr, in.FirstSeen, in.LastSeen, toTextArray(in.Labels),
-			)
-			if err != nil {
+			if !taxonomy.check(w, in.Labels) {
+				return
+			}
+			in.Address = addr
+			if err := store.UpdateAddress(ctx, in); err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 				return
 			}
+			opts.addressesChanged()
 			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 
-		case http.MethodDelete:
-			_, err := db.Exec(ctx, `DELETE FROM addresses WHERE address=$1`, addr)
+		case http.MethodPatch:
+			var in AddressPatch
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				writeDecodeError(w, err)
+				return
+			}
+			hasSettings := len(in.Settings) > 0
+			if len(in.Add) == 0 && len(in.Remove) == 0 && !hasSettings {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "add, remove or settings required"})
+				return
+			}
+			// Removing is always allowed so labels predating the taxonomy can be cleaned up
+			if !taxonomy.check(w, in.Add) {
+				return
+			}
+			var settings []byte
+			if hasSettings {
+				var err error
+				if settings, err = parseSettings(in.Settings); err != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+			labels, stored, err := store.PatchAddress(ctx, addr, in.Add, in.Remove, hasSettings, settings)
+			if errors.Is(err, dbpkg.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+				return
+			}
 			if err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 				return
 			}
+			opts.addressesChanged()
+			writeJSON(w, http.StatusOK, Address{Address: addr, Labels: labels, Settings: stored})
+
+		case http.MethodDelete:
+			// Soft-delete by default to keep history; ?hard=true purges the row
+			if err := store.DeleteAddress(ctx, addr, r.URL.Query().Get("hard") == "true"); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			opts.addressesChanged()
 			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 
 		default:
@@ -117,6 +212,3 @@ func registerAddressRoutes(mux *http.ServeMux, db *pgxpool.Pool) {
 		}
 	})
 }
-
-// toTextArray converts a slice to a Postgres text[] compatible value.
-func toTextArray(v []string) []string { return v }
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// alertKeepAlive is how often idle streams get a comment line, so proxies do
+// not time out quiet connections.
+const alertKeepAlive = 30 * time.Second
+
+// Alert is one event pushed to live subscribers.
+type Alert struct {
+	Event string          // "transaction" or "risk"
+	Data  json.RawMessage // JSON payload
+}
+
+// Broadcaster fans alerts out to every connected subscriber. Each subscriber
+// has a bounded buffer; one that falls behind is disconnected rather than
+// allowed to stall the scanner.
+type Broadcaster struct {
+	buffer int
+
+	mu   sync.Mutex
+	subs map[chan Alert]struct{}
+}
+
+// NewBroadcaster returns a broadcaster buffering up to buffer alerts per subscriber.
+func NewBroadcaster(buffer int) *Broadcaster {
+	return &Broadcaster{buffer: buffer, subs: make(map[chan Alert]struct{})}
+}
+
+// Publish sends v, encoded as JSON, to every subscriber without blocking.
+func (b *Broadcaster) Publish(event string, v interface{}) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subs) == 0 {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- Alert{Event: event, Data: data}:
+		default:
+			// Slow consumer: closing tells its handler to hang up
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *Broadcaster) subscribe() chan Alert {
+	ch := make(chan Alert, b.buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *Broadcaster) unsubscribe(ch chan Alert) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func registerAlertRoutes(mux *http.ServeMux, spec *apiSpec, alerts *Broadcaster) {
+	// GET /alerts/stream
+	spec.add(http.MethodGet, "/alerts/stream", apiOp{Summary: "Server-sent event stream of matched transactions and risk assessments", Tag: "alerts"})
+	mux.HandleFunc("/alerts/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if alerts == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "live alerts unavailable"})
+			return
+		}
+		rc := http.NewResponseController(w)
+		// Streams stay open indefinitely, past the server's write timeout
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ch := alerts.subscribe()
+		defer alerts.unsubscribe(ch)
+		ticker := time.NewTicker(alertKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case a, ok := <-ch:
+				if !ok {
+					return
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", a.Event, a.Data); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
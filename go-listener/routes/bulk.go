@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+// DefaultMaxBulkAddresses caps POST /addresses/bulk when no limit is configured.
+const DefaultMaxBulkAddresses = 10000
+
+var errBatchTooLarge = errors.New("batch too large")
+
+type bulkInvalid struct {
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+	Error   string `json:"error"`
+}
+
+type bulkResult struct {
+	Inserted int           `json:"inserted"`
+	Updated  int           `json:"updated"`
+	Skipped  int           `json:"skipped"`
+	Invalid  []bulkInvalid `json:"invalid,omitempty"`
+}
+
+// decodeAddressBatch streams a JSON array of addresses, stopping as soon as
+// the batch grows past max so oversized imports are never fully buffered.
+func decodeAddressBatch(r io.Reader, max int) ([]Address, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected json array")
+	}
+
+	var batch []Address
+	for dec.More() {
+		if len(batch) >= max {
+			return nil, fmt.Errorf("%w: max %d addresses", errBatchTooLarge, max)
+		}
+		var a Address
+		if err := dec.Decode(&a); err != nil {
+			return nil, err
+		}
+		batch = append(batch, a)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// importAddresses upserts every valid address of the batch in a single
+// transaction under its checksummed form. Invalid and duplicate entries,
+// including ones carrying labels outside the taxonomy, are skipped, not fatal.
+func importAddresses(ctx context.Context, store dbpkg.Store, in []Address, taxonomy labelTaxonomy) (bulkResult, error) {
+	var res bulkResult
+	seen := make(map[string]bool, len(in))
+	valid := make([]Address, 0, len(in))
+	for i, a := range in {
+		a.Address = strings.TrimSpace(a.Address)
+		if common.IsHexAddress(a.Address) {
+			// One row per address however the batch spells it
+			a.Address = common.HexToAddress(a.Address).Hex()
+		}
+		switch {
+		case a.Address == "":
+			res.Invalid = append(res.Invalid, bulkInvalid{Index: i, Address: a.Address, Error: "address required"})
+			res.Skipped++
+		case !common.IsHexAddress(a.Address):
+			res.Invalid = append(res.Invalid, bulkInvalid{Index: i, Address: a.Address, Error: "invalid hex address"})
+			res.Skipped++
+		case len(taxonomy.unknown(a.Labels)) > 0:
+			res.Invalid = append(res.Invalid, bulkInvalid{Index: i, Address: a.Address, Error: "unknown labels: " + strings.Join(taxonomy.unknown(a.Labels), ", ")})
+			res.Skipped++
+		case seen[a.Address]:
+			res.Skipped++
+		default:
+			seen[a.Address] = true
+			valid = append(valid, a)
+		}
+	}
+	if len(valid) == 0 {
+		return res, nil
+	}
+
+	var err error
+	res.Inserted, res.Updated, err = store.ImportAddresses(ctx, valid)
+	return res, err
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Counterparty is a flagged address that matches are screened against.
+type Counterparty struct {
+	Address   string     `json:"address"`
+	Category  string     `json:"category"`         // e.g. "sanctioned", "mixer"
+	Source    *string    `json:"source,omitempty"` // where the listing came from
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+const upsertCounterpartySQL = `INSERT INTO counterparties(address, category, source)
+                 VALUES ($1, $2, $3)
+                 ON CONFLICT (address) DO UPDATE SET category = EXCLUDED.category,
+                                                     source = COALESCE(EXCLUDED.source, counterparties.source),
+                                                     updated_at = NOW()`
+
+func registerCounterpartyRoutes(mux *http.ServeMux, spec *apiSpec, db *pgxpool.Pool, opts Options) {
+	// POST /counterparties/bulk
+	spec.add(http.MethodPost, "/counterparties/bulk", apiOp{Summary: "Import a JSON array of flagged counterparties", Tag: "counterparties", Request: []Counterparty{}, Response: bulkResult{}})
+	mux.HandleFunc("/counterparties/bulk", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		maxBatch := opts.MaxBulkAddresses
+		if maxBatch <= 0 {
+			maxBatch = DefaultMaxBulkAddresses
+		}
+		var batch []Counterparty
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		if len(batch) > maxBatch {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": errBatchTooLarge.Error()})
+			return
+		}
+		res, err := importCounterparties(context.Background(), db, batch)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		opts.addressesChanged()
+		writeJSON(w, http.StatusOK, res)
+	})
+
+	// GET/DELETE /counterparties/{address}
+	spec.add(http.MethodGet, "/counterparties/{address}", apiOp{Summary: "Get a flagged counterparty", Tag: "counterparties", Response: Counterparty{}})
+	spec.add(http.MethodDelete, "/counterparties/{address}", apiOp{Summary: "Remove a flagged counterparty", Tag: "counterparties", Response: map[string]string{}})
+	mux.HandleFunc("/counterparties/", func(w http.ResponseWriter, r *http.Request) {
+		addr := strings.TrimPrefix(r.URL.Path, "/counterparties/")
+		if !common.IsHexAddress(addr) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid hex address"})
+			return
+		}
+		addr = common.HexToAddress(addr).Hex()
+		ctx := context.Background()
+
+		switch r.Method {
+		case http.MethodGet:
+			var out Counterparty
+			err := db.QueryRow(ctx,
+				`SELECT address, category, source, created_at, updated_at FROM counterparties WHERE address = $1`, addr,
+			).Scan(&out.Address, &out.Category, &out.Source, &out.CreatedAt, &out.UpdatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, out)
+
+		case http.MethodDelete:
+			if _, err := db.Exec(ctx, `DELETE FROM counterparties WHERE address = $1`, addr); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			opts.addressesChanged()
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// importCounterparties upserts every valid entry in a single transaction,
+// storing addresses checksummed. Invalid and duplicate entries are skipped.
+func importCounterparties(ctx context.Context, db *pgxpool.Pool, in []Counterparty) (bulkResult, error) {
+	var res bulkResult
+	seen := make(map[string]bool, len(in))
+	valid := make([]Counterparty, 0, len(in))
+	for i, c := range in {
+		c.Category = strings.TrimSpace(c.Category)
+		switch {
+		case !common.IsHexAddress(strings.TrimSpace(c.Address)):
+			res.Invalid = append(res.Invalid, bulkInvalid{Index: i, Address: c.Address, Error: "invalid hex address"})
+			res.Skipped++
+		case c.Category == "":
+			res.Invalid = append(res.Invalid, bulkInvalid{Index: i, Address: c.Address, Error: "category required"})
+			res.Skipped++
+		default:
+			c.Address = common.HexToAddress(strings.TrimSpace(c.Address)).Hex()
+			if seen[c.Address] {
+				res.Skipped++
+				continue
+			}
+			seen[c.Address] = true
+			valid = append(valid, c)
+		}
+	}
+	if len(valid) == 0 {
+		return res, nil
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, c := range valid {
+		batch.Queue(upsertCounterpartySQL+` RETURNING (xmax = 0)`, c.Address, c.Category, c.Source)
+	}
+	br := tx.SendBatch(ctx, batch)
+	for range valid {
+		var inserted bool
+		if err := br.QueryRow().Scan(&inserted); err != nil {
+			br.Close()
+			return res, err
+		}
+		if inserted {
+			res.Inserted++
+		} else {
+			res.Updated++
+		}
+	}
+	if err := br.Close(); err != nil {
+		return res, err
+	}
+	return res, tx.Commit(ctx)
+}
@@ -0,0 +1,236 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Group is a named set of addresses that matches are attributed to.
+type Group struct {
+	Name        string     `json:"name"`
+	Description *string    `json:"description,omitempty"`
+	Addresses   []string   `json:"addresses,omitempty"`
+	MemberCount int        `json:"member_count"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+func registerGroupRoutes(mux *http.ServeMux, spec *apiSpec, db *pgxpool.Pool, opts Options) {
+	// GET/POST /groups
+	spec.add(http.MethodGet, "/groups", apiOp{Summary: "List wallet groups with member counts", Tag: "groups", Response: []Group{}})
+	spec.add(http.MethodPost, "/groups", apiOp{Summary: "Create a wallet group", Tag: "groups", Request: Group{}, Response: Group{}, Status: http.StatusCreated})
+	mux.HandleFunc("/groups", func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.Background()
+		switch r.Method {
+		case http.MethodGet:
+			rows, err := db.Query(ctx,
+				`SELECT g.name, g.description, count(m.address), g.created_at, g.updated_at
+                 FROM wallet_groups g LEFT JOIN wallet_group_members m ON m.group_name = g.name
+                 GROUP BY g.name ORDER BY g.name`)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			defer rows.Close()
+			out := []Group{}
+			for rows.Next() {
+				var g Group
+				if err := rows.Scan(&g.Name, &g.Description, &g.MemberCount, &g.CreatedAt, &g.UpdatedAt); err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+					return
+				}
+				out = append(out, g)
+			}
+			if err := rows.Err(); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, out)
+
+		case http.MethodPost:
+			var in Group
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				writeDecodeError(w, err)
+				return
+			}
+			in.Name = strings.TrimSpace(in.Name)
+			if in.Name == "" || strings.Contains(in.Name, "/") {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name required and must not contain /"})
+				return
+			}
+			var err error
+			if in.Addresses, err = normalizeMembers(in.Addresses); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			err = saveGroup(ctx, db, in, true)
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "group already exists"})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			opts.addressesChanged()
+			in.MemberCount = len(in.Addresses)
+			writeJSON(w, http.StatusCreated, in)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET/PUT/DELETE /groups/{name}
+	spec.add(http.MethodGet, "/groups/{name}", apiOp{Summary: "Get a wallet group and its members", Tag: "groups", Response: Group{}})
+	spec.add(http.MethodPut, "/groups/{name}", apiOp{Summary: "Replace a wallet group's description and members", Tag: "groups", Request: Group{}, Response: Group{}})
+	spec.add(http.MethodDelete, "/groups/{name}", apiOp{Summary: "Delete a wallet group", Tag: "groups", Response: map[string]string{}})
+	mux.HandleFunc("/groups/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/groups/")
+		if name == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name required"})
+			return
+		}
+		ctx := context.Background()
+
+		switch r.Method {
+		case http.MethodGet:
+			out := Group{Name: name, Addresses: []string{}}
+			err := db.QueryRow(ctx,
+				`SELECT description, created_at, updated_at FROM wallet_groups WHERE name = $1`, name,
+			).Scan(&out.Description, &out.CreatedAt, &out.UpdatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			rows, err := db.Query(ctx, `SELECT address FROM wallet_group_members WHERE group_name = $1 ORDER BY address`, name)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var addr string
+				if err := rows.Scan(&addr); err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+					return
+				}
+				out.Addresses = append(out.Addresses, addr)
+			}
+			if err := rows.Err(); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			out.MemberCount = len(out.Addresses)
+			writeJSON(w, http.StatusOK, out)
+
+		case http.MethodPut:
+			var in Group
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				writeDecodeError(w, err)
+				return
+			}
+			in.Name = name
+			var err error
+			if in.Addresses, err = normalizeMembers(in.Addresses); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			err = saveGroup(ctx, db, in, false)
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			opts.addressesChanged()
+			in.MemberCount = len(in.Addresses)
+			writeJSON(w, http.StatusOK, in)
+
+		case http.MethodDelete:
+			// Memberships go with the group (ON DELETE CASCADE)
+			if _, err := db.Exec(ctx, `DELETE FROM wallet_groups WHERE name = $1`, name); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			opts.addressesChanged()
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// normalizeMembers returns addrs checksummed and deduplicated, or an error
+// naming the first invalid address.
+func normalizeMembers(addrs []string) ([]string, error) {
+	seen := make(map[string]bool, len(addrs))
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		a = strings.TrimSpace(a)
+		if !common.IsHexAddress(a) {
+			return nil, fmt.Errorf("invalid hex address %q", a)
+		}
+		a = common.HexToAddress(a).Hex()
+		if !seen[a] {
+			seen[a] = true
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// saveGroup creates g, or with create false updates an existing group, and
+// replaces its members, all in one transaction. Updating a missing group
+// returns pgx.ErrNoRows.
+func saveGroup(ctx context.Context, db *pgxpool.Pool, g Group, create bool) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if create {
+		_, err = tx.Exec(ctx, `INSERT INTO wallet_groups(name, description) VALUES ($1, $2)`, g.Name, g.Description)
+	} else {
+		var tag pgconn.CommandTag
+		tag, err = tx.Exec(ctx, `UPDATE wallet_groups SET description = $2, updated_at = NOW() WHERE name = $1`, g.Name, g.Description)
+		if err == nil && tag.RowsAffected() == 0 {
+			err = pgx.ErrNoRows
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM wallet_group_members WHERE group_name = $1`, g.Name); err != nil {
+		return err
+	}
+	if len(g.Addresses) > 0 {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO wallet_group_members(group_name, address)
+             SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING`,
+			g.Name, g.Addresses,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,160 @@
+package routes
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyHeader lets clients retry a write safely: a repeated key gets
+// the original response instead of running the write again.
+const IdempotencyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long keys are remembered when no TTL is configured.
+const DefaultIdempotencyTTL = 10 * time.Minute
+
+// maxIdempotencyKeys caps the keys remembered at once; past it the oldest are
+// forgotten early.
+const maxIdempotencyKeys = 10_000
+
+// idempotencyCache remembers the response to each keyed request for ttl.
+type idempotencyCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*idempotentResponse
+	order   *list.List // keys, oldest first
+}
+
+type idempotentResponse struct {
+	elem     *list.Element     // in order
+	request  [sha256.Size]byte // method, path and body, to catch a key reused for a different request
+	done     bool
+	status   int
+	header   http.Header
+	body     []byte
+	expireAt time.Time
+}
+
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &idempotencyCache{ttl: ttl, entries: make(map[string]*idempotentResponse), order: list.New()}
+}
+
+// remove forgets key if it still maps to e. Callers hold c.mu.
+func (c *idempotencyCache) remove(key string, e *idempotentResponse) {
+	if c.entries[key] == e {
+		delete(c.entries, key)
+		c.order.Remove(e.elem)
+	}
+}
+
+// evict drops expired keys from the front of the order, then the oldest keys
+// while the cache is full. Keys expire roughly in the order they were added,
+// so this never scans the whole cache. Callers hold c.mu.
+func (c *idempotencyCache) evict(now time.Time) {
+	for front := c.order.Front(); front != nil; front = c.order.Front() {
+		key := front.Value.(string)
+		e := c.entries[key]
+		if len(c.entries) < maxIdempotencyKeys && !(e.done && now.After(e.expireAt)) {
+			return
+		}
+		c.remove(key, e)
+	}
+}
+
+// wrap replays the stored response for a repeated Idempotency-Key. Requests
+// without the header run as usual. Server errors are not remembered, so the
+// client can retry them.
+func (c *idempotencyCache) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" || !isWrite(r.Method) {
+			next(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		h := sha256.New()
+		h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		h.Write(body)
+		var fingerprint [sha256.Size]byte
+		copy(fingerprint[:], h.Sum(nil))
+
+		c.mu.Lock()
+		c.evict(time.Now())
+		prev, ok := c.entries[key]
+		switch {
+		case ok && prev.request != fingerprint:
+			c.mu.Unlock()
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "idempotency key reused for a different request"})
+			return
+		case ok && !prev.done:
+			c.mu.Unlock()
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "a request with this idempotency key is in progress"})
+			return
+		case ok:
+			c.mu.Unlock()
+			for k, v := range prev.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(prev.status)
+			_, _ = w.Write(prev.body)
+			return
+		}
+		entry := &idempotentResponse{request: fingerprint}
+		entry.elem = c.order.PushBack(key)
+		c.entries[key] = entry
+		c.mu.Unlock()
+
+		// A server error or a panic leaves nothing behind, so the key can be retried
+		defer func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if !entry.done {
+				c.remove(key, entry)
+			}
+		}()
+		rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entry.done = true
+		entry.status = rec.status
+		entry.header = w.Header().Clone()
+		entry.body = rec.body.Bytes()
+		entry.expireAt = time.Now().Add(c.ttl)
+	}
+}
+
+// recordingWriter passes a response through while keeping a copy.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recordingWriter) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recordingWriter) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
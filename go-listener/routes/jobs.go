@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrJobQueueFull is returned by JobQueue.EnqueueBackfill when no more jobs
+// can be accepted right now.
+var ErrJobQueueFull = errors.New("job queue full")
+
+// MaxBackfillBlocks caps the number of blocks one backfill job may scan.
+const MaxBackfillBlocks = 100_000
+
+// BackfillRequest is the body of POST /addresses/{address}/backfill. Give
+// either from_block (to_block defaults to the confirmed head) or lookback, the
+// number of blocks before the confirmed head to scan. Either way the range
+// spans at most MaxBackfillBlocks blocks.
+type BackfillRequest struct {
+	Chain     string  `json:"chain,omitempty"` // required when more than one chain is scanned
+	FromBlock *uint64 `json:"from_block,omitempty"`
+	ToBlock   *uint64 `json:"to_block,omitempty"`
+	Lookback  *uint64 `json:"lookback,omitempty"`
+}
+
+// ReplayRequest is the body of POST /transactions/replay. Stored transactions
+// of the chain within both the block range and the block-time range are
+// re-sent to the analyzers; at least one lower bound is required.
+type ReplayRequest struct {
+	Chain       string     `json:"chain,omitempty"` // required when more than one chain is scanned
+	FromBlock   *uint64    `json:"from_block,omitempty"`
+	ToBlock     *uint64    `json:"to_block,omitempty"`
+	Since       *time.Time `json:"since,omitempty"`
+	Until       *time.Time `json:"until,omitempty"`
+	Concurrency int        `json:"concurrency,omitempty"` // analyzer requests in flight; default 4
+}
+
+// MaxReplayConcurrency caps ReplayRequest.Concurrency.
+const MaxReplayConcurrency = 32
+
+// JobProgress counts the items a job has worked through.
+type JobProgress struct {
+	Total  int `json:"total"`
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
+}
+
+// Job is the status of one background job.
+type Job struct {
+	ID         string       `json:"id"`
+	Kind       string       `json:"kind"`
+	Status     string       `json:"status"` // queued, running, done or failed
+	Address    string       `json:"address,omitempty"`
+	Chain      string       `json:"chain,omitempty"`
+	FromBlock  *uint64      `json:"from_block,omitempty"`
+	ToBlock    *uint64      `json:"to_block,omitempty"`
+	Since      *time.Time   `json:"since,omitempty"`
+	Until      *time.Time   `json:"until,omitempty"`
+	Progress   *JobProgress `json:"progress,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// JobQueue runs background work on behalf of the API.
+type JobQueue interface {
+	EnqueueBackfill(address string, req BackfillRequest) (Job, error)
+	EnqueueReplay(req ReplayRequest) (Job, error)
+	Job(id string) (Job, bool)
+}
+
+func registerJobRoutes(mux *http.ServeMux, spec *apiSpec, opts Options) {
+	// POST /addresses/{address}/backfill is dispatched from the address routes
+	spec.add(http.MethodPost, "/addresses/{address}/backfill", apiOp{Summary: "Queue a backfill scan for one address", Tag: "jobs", Request: BackfillRequest{}, Response: Job{}, Status: http.StatusAccepted})
+
+	// POST /transactions/replay
+	spec.add(http.MethodPost, "/transactions/replay", apiOp{Summary: "Queue a replay of stored transactions through the analyzers", Tag: "jobs", Request: ReplayRequest{}, Response: Job{}, Status: http.StatusAccepted})
+	mux.HandleFunc("/transactions/replay", func(w http.ResponseWriter, r *http.Request) {
+		handleReplay(w, r, opts.Jobs)
+	})
+
+	// GET /jobs/{id}
+	spec.add(http.MethodGet, "/jobs/{id}", apiOp{Summary: "Get a background job's status", Tag: "jobs", Response: Job{}})
+	mux.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+		if opts.Jobs == nil || id == "" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		job, ok := opts.Jobs.Job(id)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	})
+}
+
+// handleBackfill serves POST /addresses/{address}/backfill.
+func handleBackfill(w http.ResponseWriter, r *http.Request, addr string, jobs JobQueue) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if jobs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "backfill jobs unavailable"})
+		return
+	}
+	if !common.IsHexAddress(addr) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid hex address"})
+		return
+	}
+	var in BackfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	switch {
+	case in.Lookback != nil && (in.FromBlock != nil || in.ToBlock != nil):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "give either lookback or from_block/to_block"})
+		return
+	case in.Lookback == nil && in.FromBlock == nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from_block or lookback required"})
+		return
+	case in.Lookback != nil && *in.Lookback == 0:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "lookback must be positive"})
+		return
+	case in.FromBlock != nil && in.ToBlock != nil && *in.FromBlock > *in.ToBlock:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from_block must not exceed to_block"})
+		return
+	case in.Lookback != nil && *in.Lookback > MaxBackfillBlocks,
+		in.FromBlock != nil && in.ToBlock != nil && *in.ToBlock-*in.FromBlock >= MaxBackfillBlocks:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("a backfill may span at most %d blocks", MaxBackfillBlocks)})
+		return
+	}
+
+	job, err := jobs.EnqueueBackfill(addr, in)
+	if errors.Is(err, ErrJobQueueFull) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleReplay serves POST /transactions/replay.
+func handleReplay(w http.ResponseWriter, r *http.Request, jobs JobQueue) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if jobs == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "replay jobs unavailable"})
+		return
+	}
+	var in ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	switch {
+	case in.FromBlock == nil && in.Since == nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from_block or since required"})
+		return
+	case in.FromBlock != nil && in.ToBlock != nil && *in.FromBlock > *in.ToBlock:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from_block must not exceed to_block"})
+		return
+	case in.Since != nil && in.Until != nil && in.Since.After(*in.Until):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must not be after until"})
+		return
+	case in.Concurrency < 0 || in.Concurrency > MaxReplayConcurrency:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("concurrency must be between 1 and %d", MaxReplayConcurrency)})
+		return
+	}
+
+	job, err := jobs.EnqueueReplay(in)
+	if errors.Is(err, ErrJobQueueFull) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
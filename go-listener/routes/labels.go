@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LabelCount struct {
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+func registerLabelRoutes(mux *http.ServeMux, spec *apiSpec, db *pgxpool.Pool) {
+	// GET /labels?prefix=
+	spec.add(http.MethodGet, "/labels", apiOp{Summary: "Distinct labels with address counts; prefix filters for autocomplete", Tag: "labels", Query: []string{"prefix"}, Response: []LabelCount{}})
+	mux.HandleFunc("/labels", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		prefix := strings.TrimSpace(r.URL.Query().Get("prefix"))
+		rows, err := db.Query(context.Background(),
+			`SELECT label, count(*) FROM addresses, unnest(labels) AS label
+              WHERE deleted_at IS NULL AND ($1::text = '' OR starts_with(label, $1::text))
+              GROUP BY label ORDER BY count(*) DESC, label`, prefix,
+		)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		defer rows.Close()
+
+		out := []LabelCount{}
+		for rows.Next() {
+			var lc LabelCount
+			if err := rows.Scan(&lc.Label, &lc.Count); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			out = append(out, lc)
+		}
+		if err := rows.Err(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+}
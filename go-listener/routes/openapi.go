@@ -0,0 +1,235 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiSpec collects an OpenAPI 3 description of the routes as they are
+// registered, so the served document cannot drift from the mux.
+type apiSpec struct {
+	paths   map[string]map[string]interface{}
+	schemas map[string]interface{}
+}
+
+// apiOp describes one method on a path. Request and Response are sample Go
+// values whose types are turned into JSON schemas; nil means no body.
+type apiOp struct {
+	Summary  string
+	Tag      string
+	Query    []string // optional query parameters
+	Request  interface{}
+	Response interface{}
+	Status   int // success status; defaults to 200
+}
+
+func newAPISpec() *apiSpec {
+	return &apiSpec{
+		paths:   make(map[string]map[string]interface{}),
+		schemas: map[string]interface{}{"Error": errorSchema},
+	}
+}
+
+var (
+	pathParamRe = regexp.MustCompile(`\{([^}]+)\}`)
+	errorSchema = map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"error": map[string]interface{}{"type": "string"}},
+	}
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// add documents method on path, e.g. ("GET", "/addresses/{address}").
+func (s *apiSpec) add(method, path string, op apiOp) {
+	var params []interface{}
+	for _, m := range pathParamRe.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true,
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range op.Query {
+		params = append(params, map[string]interface{}{
+			"name": q, "in": "query",
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]interface{}{"description": http.StatusText(status)}
+	if op.Response != nil {
+		ok["content"] = jsonContent(s.schemaFor(reflect.TypeOf(op.Response)))
+	}
+	o := map[string]interface{}{
+		"summary": op.Summary,
+		"responses": map[string]interface{}{
+			strconv.Itoa(status): ok,
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+			},
+		},
+	}
+	if op.Tag != "" {
+		o["tags"] = []string{op.Tag}
+	}
+	if publicPaths[path] {
+		o["security"] = []interface{}{}
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+	if op.Request != nil {
+		o["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(s.schemaFor(reflect.TypeOf(op.Request))),
+		}
+	}
+
+	if s.paths[path] == nil {
+		s.paths[path] = make(map[string]interface{})
+	}
+	s.paths[path][strings.ToLower(method)] = o
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// schemaFor converts a Go type into a JSON schema, registering named structs
+// as reusable components.
+func (s *apiSpec) schemaFor(t reflect.Type) interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawType:
+		return map[string]interface{}{"type": "object"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if name != "" {
+			name = strings.ToUpper(name[:1]) + name[1:]
+			if _, ok := s.schemas[name]; !ok {
+				s.schemas[name] = nil // reserve first so recursive types terminate
+				s.schemas[name] = s.structSchema(t)
+			}
+			return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		}
+		return s.structSchema(t)
+	}
+	return map[string]interface{}{}
+}
+
+func (s *apiSpec) structSchema(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		// Untagged embedded structs are flattened by encoding/json
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range s.structSchema(f.Type)["properties"].(map[string]interface{}) {
+				props[k] = v
+			}
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n, _, _ := strings.Cut(tag, ","); n != "" {
+				name = n
+			}
+		}
+		props[name] = s.schemaFor(f.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// document returns the complete OpenAPI document.
+func (s *apiSpec) document() map[string]interface{} {
+	paths := make(map[string]interface{}, len(s.paths))
+	keys := make([]string, 0, len(s.paths))
+	for p := range s.paths {
+		keys = append(keys, p)
+	}
+	sort.Strings(keys)
+	for _, p := range keys {
+		paths[p] = s.paths[p]
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "BlockSentinel API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": s.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>BlockSentinel API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// registerDocRoutes serves the collected spec and a Swagger UI page. It must
+// be called after every other route group has been registered.
+func registerDocRoutes(mux *http.ServeMux, spec *apiSpec) {
+	spec.add(http.MethodGet, "/openapi.json", apiOp{Summary: "This OpenAPI document", Tag: "docs"})
+	spec.add(http.MethodGet, "/docs", apiOp{Summary: "Swagger UI", Tag: "docs"})
+	doc, _ := json.Marshal(spec.document())
+
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	})
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(swaggerUIPage))
+	})
+}
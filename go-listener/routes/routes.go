@@ -1,13 +1,201 @@
 package routes
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"net/netip"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
 )
 
-// RegisterRoutes wires all HTTP routes.
-func RegisterRoutes(mux *http.ServeMux, db *pgxpool.Pool) {
-	registerAddressRoutes(mux, db)
-	// Add more route groups here
+// Options carries the config-driven knobs the route groups need.
+type Options struct {
+	// MaxBulkAddresses caps POST /addresses/bulk; <= 0 uses DefaultMaxBulkAddresses.
+	MaxBulkAddresses int
+	// APIKeys enables bearer-token auth when non-empty.
+	APIKeys []string
+	// Store backs the address routes; nil uses the Postgres pool.
+	Store dbpkg.Store
+	// OnAddressesChanged, if set, is called after any successful address write.
+	OnAddressesChanged func()
+	// CheckSettings validates a full set of config overrides before PUT
+	// /settings stores it; nil stores any set.
+	CheckSettings func(settings map[string]json.RawMessage) error
+	// OnSettingsChanged, if set, is called after PUT /settings stores a set.
+	OnSettingsChanged func()
+	// Enrich, if set, is called with each address written through POST
+	// /addresses, to look up its labels in the background.
+	Enrich func(address string)
+	// IdempotencyTTL is how long Idempotency-Key responses are replayed; <= 0
+	// uses DefaultIdempotencyTTL.
+	IdempotencyTTL time.Duration
+	// MaxBodyBytes caps request bodies; <= 0 uses DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// AllowedLabels, when non-empty, is the only labels address writes may set.
+	AllowedLabels []string
+	// Jobs runs on-demand backfills and replays; nil disables their endpoints.
+	Jobs JobQueue
+	// Alerts feeds GET /alerts/stream; nil disables it.
+	Alerts *Broadcaster
+	// Readiness lists reasons the service is unhealthy for GET /readyz; nil
+	// or an empty list is ready.
+	Readiness func() []string
+	// Config is the effective configuration, already redacted, for GET
+	// /config. The route is only served when APIKeys are set.
+	Config interface{}
+	// WriteAllowlist limits mutating requests to these client ranges when non-empty.
+	WriteAllowlist []netip.Prefix
+	// CORSOrigins enables cross-origin access for these origins when non-empty.
+	CORSOrigins []string
+	CORSMethods []string
+	CORSHeaders []string
+}
+
+// DefaultMaxBodyBytes caps request bodies when no limit is configured.
+const DefaultMaxBodyBytes = 1 << 20
+
+// addressesChanged signals a watchlist write in process and, through the
+// store, to scanners running in other processes.
+func (o Options) addressesChanged() {
+	if o.OnAddressesChanged != nil {
+		o.OnAddressesChanged()
+	}
+	if err := o.Store.NotifyAddressesChanged(context.Background()); err != nil {
+		log.Printf("Error notifying address change: %v", err)
+	}
+}
+
+// publicPaths are served without authentication.
+var publicPaths = map[string]bool{
+	"/healthz":      true,
+	"/readyz":       true,
+	"/openapi.json": true,
+	"/docs":         true,
+}
+
+// RegisterRoutes wires all HTTP routes. db may be nil when opts.Store is
+// set, in which case only the address routes are served from the database.
+func RegisterRoutes(mux *http.ServeMux, db *pgxpool.Pool, opts Options) {
+	if opts.Store == nil {
+		opts.Store = dbpkg.NewPGStore(db)
+	}
+	spec := newAPISpec()
+	spec.add(http.MethodGet, "/healthz", apiOp{Summary: "Liveness check", Tag: "health", Response: map[string]string{}})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	spec.add(http.MethodGet, "/readyz", apiOp{Summary: "Readiness check; 503 while any chain scanner is stalled", Tag: "health", Response: map[string]interface{}{}})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		var problems []string
+		if opts.Readiness != nil {
+			problems = opts.Readiness()
+		}
+		if len(problems) > 0 {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"status": "unhealthy", "problems": problems})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	if opts.Config != nil && len(opts.APIKeys) > 0 {
+		spec.add(http.MethodGet, "/config", apiOp{Summary: "Effective configuration with secrets redacted", Tag: "health", Response: map[string]interface{}{}})
+		mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			writeJSON(w, http.StatusOK, opts.Config)
+		})
+	}
+	registerAddressRoutes(mux, spec, opts.Store, opts)
+	if db != nil {
+		registerTransactionRoutes(mux, spec, db)
+		registerStatsRoutes(mux, spec, db)
+		registerLabelRoutes(mux, spec, db)
+		registerCounterpartyRoutes(mux, spec, db, opts)
+		registerGroupRoutes(mux, spec, db, opts)
+		registerSettingsRoutes(mux, spec, db, opts)
+	}
+	registerJobRoutes(mux, spec, opts)
+	registerAlertRoutes(mux, spec, opts.Alerts)
+	spec.add(http.MethodGet, "/metrics", apiOp{Summary: "Prometheus metrics (text format)", Tag: "health"})
+	mux.Handle("/metrics", metrics.Handler())
+	// Add more route groups here, before the docs
+	registerDocRoutes(mux, spec)
+}
+
+// Handler returns the full API with all routes registered and middleware applied.
+func Handler(db *pgxpool.Pool, opts Options) http.Handler {
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, db, opts)
+	h := RequireAPIKey(opts.APIKeys, LimitBody(opts.MaxBodyBytes, mux))
+	h = RestrictWrites(opts.WriteAllowlist, Gzip(h))
+	return CORS(opts.CORSOrigins, opts.CORSMethods, opts.CORSHeaders, h)
+}
+
+// LimitBody caps every request body at max bytes so oversized uploads fail
+// fast instead of being buffered; <= 0 uses DefaultMaxBodyBytes.
+func LimitBody(max int64, next http.Handler) http.Handler {
+	if max <= 0 {
+		max = DefaultMaxBodyBytes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeDecodeError reports a request body that failed to decode, using 413
+// when the body hit the size limit.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "request body too large"})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+}
+
+// RequireAPIKey rejects requests without a valid "Authorization: Bearer <key>"
+// header with 401. It is a no-op when no keys are configured.
+func RequireAPIKey(keys []string, next http.Handler) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+	// Compare fixed-size digests so neither key contents nor lengths leak through timing
+	digests := make([][sha256.Size]byte, len(keys))
+	for i, k := range keys {
+		digests[i] = sha256.Sum256([]byte(k))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="blocksentinel"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		got := sha256.Sum256([]byte(token))
+		match := 0
+		for _, d := range digests {
+			match |= subtle.ConstantTimeCompare(got[:], d[:])
+		}
+		if match != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="blocksentinel"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
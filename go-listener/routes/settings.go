@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+func registerSettingsRoutes(mux *http.ServeMux, spec *apiSpec, db *pgxpool.Pool, opts Options) {
+	// GET/PUT /settings
+	spec.add(http.MethodGet, "/settings", apiOp{Summary: "Stored config overrides, keyed by config file name", Tag: "settings", Response: map[string]interface{}{}})
+	spec.add(http.MethodPut, "/settings", apiOp{Summary: "Replace the stored config overrides; keys left out fall back to the file and environment", Tag: "settings", Request: map[string]interface{}{}, Response: map[string]interface{}{}})
+	mux.HandleFunc("/settings", func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.Background()
+		switch r.Method {
+		case http.MethodGet:
+			settings, err := dbpkg.FetchSettings(ctx, db)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, settings)
+
+		case http.MethodPut:
+			var settings map[string]json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+				writeDecodeError(w, err)
+				return
+			}
+			if settings == nil {
+				settings = map[string]json.RawMessage{}
+			}
+			for key, value := range settings {
+				if string(value) == "null" {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%s: value must not be null; leave the key out to remove it", key)})
+					return
+				}
+			}
+			if opts.CheckSettings != nil {
+				if err := opts.CheckSettings(settings); err != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+			if err := dbpkg.ReplaceSettings(ctx, db, settings); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			if opts.OnSettingsChanged != nil {
+				opts.OnSettingsChanged()
+			}
+			writeJSON(w, http.StatusOK, settings)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultStatsWindow = 24 * time.Hour
+	defaultStatsLimit  = 20
+	maxStatsLimit      = 500
+)
+
+// MethodStat is how often stored transactions called one method selector.
+type MethodStat struct {
+	Selector string  `json:"selector"`       // "0x" and 4 bytes of hex
+	Name     *string `json:"name,omitempty"` // decoded method name, when known
+	Count    int64   `json:"count"`
+}
+
+func registerStatsRoutes(mux *http.ServeMux, spec *apiSpec, db *pgxpool.Pool) {
+	// GET /stats/methods?window=24h&limit=20&chain_id=
+	spec.add(http.MethodGet, "/stats/methods", apiOp{Summary: "Most called method selectors across stored transactions within a window of block time", Tag: "stats", Query: []string{"window", "limit", "chain_id"}, Response: []MethodStat{}})
+	mux.HandleFunc("/stats/methods", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		window := defaultStatsWindow
+		if v := q.Get("window"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "window must be a positive duration such as 24h"})
+				return
+			}
+			window = d
+		}
+		limit := defaultStatsLimit
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxStatsLimit {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and " + strconv.Itoa(maxStatsLimit)})
+				return
+			}
+			limit = n
+		}
+		var chainID *uint64
+		if v := q.Get("chain_id"); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "chain_id must be a non-negative integer"})
+				return
+			}
+			chainID = &id
+		}
+
+		// Rows stored before names were recorded have none; any named row of
+		// the same selector names the group
+		since := time.Now().Add(-window).Unix()
+		rows, err := db.Query(context.Background(),
+			`SELECT method_selector, max(method_name), count(*) FROM transactions
+              WHERE method_selector IS NOT NULL AND block_timestamp >= $1
+                AND ($2::bigint IS NULL OR chain_id = $2)
+              GROUP BY method_selector ORDER BY count(*) DESC, method_selector LIMIT $3`,
+			since, chainID, limit,
+		)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		defer rows.Close()
+
+		out := []MethodStat{}
+		for rows.Next() {
+			var ms MethodStat
+			if err := rows.Scan(&ms.Selector, &ms.Name, &ms.Count); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			out = append(out, ms)
+		}
+		if err := rows.Err(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+}
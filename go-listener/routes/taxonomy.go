@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// labelTaxonomy is the set of labels addresses may carry. A nil taxonomy
+// allows any label.
+type labelTaxonomy map[string]bool
+
+func newLabelTaxonomy(labels []string) labelTaxonomy {
+	if len(labels) == 0 {
+		return nil
+	}
+	t := make(labelTaxonomy, len(labels))
+	for _, l := range labels {
+		t[l] = true
+	}
+	return t
+}
+
+// unknown returns the labels not in the taxonomy, in input order.
+func (t labelTaxonomy) unknown(labels []string) []string {
+	if t == nil {
+		return nil
+	}
+	var out []string
+	for _, l := range labels {
+		if !t[l] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (t labelTaxonomy) sorted() []string {
+	out := make([]string, 0, len(t))
+	for l := range t {
+		out = append(out, l)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// check writes a 400 listing the valid labels and returns false when labels
+// contains anything outside the taxonomy.
+func (t labelTaxonomy) check(w http.ResponseWriter, labels []string) bool {
+	bad := t.unknown(labels)
+	if len(bad) == 0 {
+		return true
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error":          "unknown labels: " + strings.Join(bad, ", "),
+		"allowed_labels": t.sorted(),
+	})
+	return false
+}
@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RiskAssessment struct {
+	ChainID   uint64          `json:"chain_id"`
+	TxHash    string          `json:"tx_hash"`
+	Analyzer  *string         `json:"analyzer,omitempty"`
+	RiskScore *float64        `json:"risk_score,omitempty"`
+	Category  *string         `json:"category,omitempty"`
+	Labels    []string        `json:"labels,omitempty"`
+	Raw       json.RawMessage `json:"raw"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// TransactionDetail is a stored transaction with its latest risk assessment
+// and the labels of both parties.
+type TransactionDetail struct {
+	ExportedTransaction
+	MethodSelector *string         `json:"method_selector,omitempty"` // first 4 bytes of input, hex
+	FromLabels     []string        `json:"from_labels,omitempty"`
+	ToLabels       []string        `json:"to_labels,omitempty"`
+	Risk           *RiskAssessment `json:"risk,omitempty"`
+}
+
+func registerTransactionRoutes(mux *http.ServeMux, spec *apiSpec, db *pgxpool.Pool) {
+	registerExportRoute(mux, spec, db)
+
+	// GET /transactions/{hash} and GET /transactions/{hash}/risk
+	spec.add(http.MethodGet, "/transactions/{hash}", apiOp{Summary: "Stored transaction with its latest risk assessment and party labels", Tag: "transactions", Query: []string{"chain_id"}, Response: TransactionDetail{}})
+	spec.add(http.MethodGet, "/transactions/{hash}/risk", apiOp{Summary: "Latest risk assessment for a transaction", Tag: "transactions", Response: RiskAssessment{}})
+	mux.HandleFunc("/transactions/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/transactions/"), "/"), "/")
+		if parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "risk") {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		hash := parts[0]
+		ctx := context.Background()
+
+		if len(parts) == 1 {
+			var chainID *uint64
+			if v := r.URL.Query().Get("chain_id"); v != "" {
+				id, err := strconv.ParseUint(v, 10, 64)
+				if err != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "chain_id must be a non-negative integer"})
+					return
+				}
+				chainID = &id
+			}
+			out, err := transactionDetail(ctx, db, hash, chainID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, out)
+			return
+		}
+
+		out, err := latestRiskAssessment(ctx, db, hash, nil)
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+}
+
+// latestRiskAssessment returns the newest assessment of hash, optionally on
+// one chain only.
+func latestRiskAssessment(ctx context.Context, db *pgxpool.Pool, hash string, chainID *uint64) (RiskAssessment, error) {
+	var out RiskAssessment
+	var raw []byte
+	err := db.QueryRow(ctx,
+		`SELECT chain_id, tx_hash, analyzer, risk_score, category, labels, raw, created_at
+           FROM risk_assessments WHERE lower(tx_hash) = lower($1) AND ($2::bigint IS NULL OR chain_id = $2)
+          ORDER BY created_at DESC, id DESC LIMIT 1`, hash, chainID,
+	).Scan(&out.ChainID, &out.TxHash, &out.Analyzer, &out.RiskScore, &out.Category, &out.Labels, &raw, &out.CreatedAt)
+	out.Raw = raw
+	return out, err
+}
+
+// transactionDetail loads a stored transaction by hash. When the same hash was
+// stored on several chains and chainID is nil, the most recent row wins.
+func transactionDetail(ctx context.Context, db *pgxpool.Pool, hash string, chainID *uint64) (TransactionDetail, error) {
+	var out TransactionDetail
+	t := &out.ExportedTransaction
+	err := db.QueryRow(ctx,
+		`SELECT t.chain_id, t.hash, t.from_address, t.to_address, t.value_wei::text, t.gas_used,
+                t.gas_price_wei::text, t.block_num, t.block_timestamp, t.input_hex, t.created_at,
+                fa.labels, ta.labels
+           FROM transactions t
+           LEFT JOIN addresses fa ON lower(fa.address) = lower(t.from_address) AND fa.deleted_at IS NULL
+           LEFT JOIN addresses ta ON lower(ta.address) = lower(t.to_address) AND ta.deleted_at IS NULL
+          WHERE lower(t.hash) = lower($1) AND ($2::bigint IS NULL OR t.chain_id = $2)
+          ORDER BY t.created_at DESC, t.id DESC LIMIT 1`, hash, chainID,
+	).Scan(&t.ChainID, &t.Hash, &t.From, &t.To, &t.ValueWei, &t.GasUsed,
+		&t.GasPriceWei, &t.BlockNum, &t.BlockTimestamp, &t.InputHex, &t.CreatedAt,
+		&out.FromLabels, &out.ToLabels)
+	if err != nil {
+		return out, err
+	}
+	if t.InputHex != nil {
+		if in := strings.TrimPrefix(*t.InputHex, "0x"); len(in) >= 8 {
+			selector := "0x" + in[:8]
+			out.MethodSelector = &selector
+		}
+	}
+
+	risk, err := latestRiskAssessment(ctx, db, t.Hash, &t.ChainID)
+	switch {
+	case err == nil:
+		out.Risk = &risk
+	case !errors.Is(err, pgx.ErrNoRows):
+		return out, err
+	}
+	return out, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+)
+
+// EthClient is the subset of node RPC the scanner uses. The scanner depends on
+// this rather than *ethclient.Client so it can run against a fake node.
+type EthClient interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	NetworkID(ctx context.Context) (*big.Int, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error)
+	// CallContext issues a raw JSON-RPC call for methods the ethclient does not wrap.
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// rateLimitedClient wraps an EthClient so every RPC call the scanner makes
+// waits on a shared token bucket. A nil limiter means unlimited.
+type rateLimitedClient struct {
+	inner   EthClient
+	limiter *rate.Limiter
+}
+
+func newRateLimitedClient(client EthClient, requestsPerSecond float64) *rateLimitedClient {
+	c := &rateLimitedClient{inner: client}
+	if requestsPerSecond > 0 {
+		burst := int(requestsPerSecond)
+		if burst < 1 {
+			burst = 1
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+	}
+	return c
+}
+
+func (c *rateLimitedClient) wait(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	return c.limiter.Wait(ctx)
+}
+
+func (c *rateLimitedClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.inner.HeaderByNumber(ctx, number)
+}
+
+func (c *rateLimitedClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.inner.BlockByNumber(ctx, number)
+}
+
+func (c *rateLimitedClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.inner.NetworkID(ctx)
+}
+
+func (c *rateLimitedClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.inner.FilterLogs(ctx, q)
+}
+
+func (c *rateLimitedClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.inner.CallContract(ctx, msg, blockNumber)
+}
+
+func (c *rateLimitedClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.inner.TransactionReceipt(ctx, txHash)
+}
+
+func (c *rateLimitedClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, false, err
+	}
+	return c.inner.TransactionByHash(ctx, hash)
+}
+
+func (c *rateLimitedClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.inner.BlockReceipts(ctx, blockNrOrHash)
+}
+
+func (c *rateLimitedClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.inner.CallContext(ctx, result, method, args...)
+}
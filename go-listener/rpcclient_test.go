@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedClientThrottles(t *testing.T) {
+	inner := &fakeClient{}
+	// 10/s with a burst of 10: the first 10 calls pass at once, the next 3
+	// wait roughly 100ms each
+	c := newRateLimitedClient(inner, 10)
+
+	start := time.Now()
+	for i := 0; i < 13; i++ {
+		if _, err := c.BlockByNumber(context.Background(), big.NewInt(int64(i))); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("13 calls at 10/s took %s, want at least 250ms", elapsed)
+	}
+	if got := len(inner.fetchedBlocks()); got != 13 {
+		t.Errorf("inner client saw %d calls, want 13", got)
+	}
+}
+
+func TestRateLimitedClientGivesUpWithContext(t *testing.T) {
+	inner := &fakeClient{}
+	c := newRateLimitedClient(inner, 1)
+	if _, err := c.BlockByNumber(context.Background(), big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bucket is empty, and the next token is further off than the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.BlockByNumber(ctx, big.NewInt(2)); err == nil {
+		t.Fatal("call past the deadline succeeded, want an error")
+	}
+	if got := len(inner.fetchedBlocks()); got != 1 {
+		t.Errorf("inner client saw %d calls, want 1: a throttled call must not reach it", got)
+	}
+}
+
+func TestRateLimitedClientUnlimited(t *testing.T) {
+	c := newRateLimitedClient(&fakeClient{}, 0)
+	if c.limiter != nil {
+		t.Fatal("a zero rate should not install a limiter")
+	}
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if _, err := c.BlockByNumber(context.Background(), big.NewInt(int64(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("100 unlimited calls took %s", elapsed)
+	}
+}
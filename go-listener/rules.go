@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RuleConfig is one declarative match rule. Every condition it sets must
+// hold; unset conditions match anything. A matched transaction is tagged
+// with the names of all the rules it satisfies.
+type RuleConfig struct {
+	Name        string   `yaml:"name"`
+	MinValueWei string   `yaml:"min_value_wei,omitempty"` // inclusive
+	MaxValueWei string   `yaml:"max_value_wei,omitempty"` // inclusive
+	MinGas      uint64   `yaml:"min_gas,omitempty"`       // gas limit, inclusive
+	MaxGas      uint64   `yaml:"max_gas,omitempty"`       // gas limit, inclusive
+	From        []string `yaml:"from,omitempty"`
+	To          []string `yaml:"to,omitempty"` // the deployed address for contract creations
+}
+
+// rule is a RuleConfig parsed for evaluation.
+type rule struct {
+	name           string
+	minValue       *big.Int
+	maxValue       *big.Int
+	minGas, maxGas uint64
+	fromSet, toSet map[common.Address]bool
+}
+
+// compileRules parses cfgs, reporting every invalid rule at once.
+func compileRules(cfgs []RuleConfig) ([]rule, error) {
+	out := make([]rule, 0, len(cfgs))
+	seen := make(map[string]bool, len(cfgs))
+	var problems []string
+	for i, c := range cfgs {
+		r := rule{name: strings.TrimSpace(c.Name), minGas: c.MinGas, maxGas: c.MaxGas}
+		bad := func(format string, args ...interface{}) {
+			problems = append(problems, fmt.Sprintf("rules[%d]: ", i)+fmt.Sprintf(format, args...))
+		}
+		switch {
+		case r.name == "":
+			bad("name is required")
+		case seen[r.name]:
+			bad("duplicate name %q", r.name)
+		}
+		seen[r.name] = true
+
+		var err error
+		if r.minValue, err = parseWei(c.MinValueWei); err != nil {
+			bad("min_value_wei: %v", err)
+		}
+		if r.maxValue, err = parseWei(c.MaxValueWei); err != nil {
+			bad("max_value_wei: %v", err)
+		}
+		if r.minValue != nil && r.maxValue != nil && r.minValue.Cmp(r.maxValue) > 0 {
+			bad("min_value_wei is above max_value_wei")
+		}
+		if r.maxGas > 0 && r.minGas > r.maxGas {
+			bad("min_gas is above max_gas")
+		}
+		if r.fromSet, err = addressSet(c.From); err != nil {
+			bad("from: %v", err)
+		}
+		if r.toSet, err = addressSet(c.To); err != nil {
+			bad("to: %v", err)
+		}
+		out = append(out, r)
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return out, nil
+}
+
+// parseWei parses a non-negative decimal wei amount, or nil when s is empty.
+func parseWei(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("%q is not a non-negative decimal integer", s)
+	}
+	return v, nil
+}
+
+// addressSet parses addrs, or returns nil when empty.
+func addressSet(addrs []string) (map[common.Address]bool, error) {
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+	set := make(map[common.Address]bool, len(addrs))
+	for _, a := range addrs {
+		if !common.IsHexAddress(strings.TrimSpace(a)) {
+			return nil, fmt.Errorf("invalid hex address %q", a)
+		}
+		set[common.HexToAddress(strings.TrimSpace(a))] = true
+	}
+	return set, nil
+}
+
+func (r rule) matches(m matchedTx) bool {
+	value := m.tx.Value()
+	if r.minValue != nil && value.Cmp(r.minValue) < 0 {
+		return false
+	}
+	if r.maxValue != nil && value.Cmp(r.maxValue) > 0 {
+		return false
+	}
+	if gas := m.tx.Gas(); gas < r.minGas || (r.maxGas > 0 && gas > r.maxGas) {
+		return false
+	}
+	if r.fromSet != nil && !r.fromSet[m.from] {
+		return false
+	}
+	to := m.to
+	if m.isCreation {
+		to = m.created
+	}
+	return r.toSet == nil || r.toSet[to]
+}
+
+// matchRules returns the names of the rules m satisfies, in config order.
+func matchRules(rules []rule, m matchedTx) []string {
+	var names []string
+	for _, r := range rules {
+		if r.matches(m) {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+// filterRules tags m with the rules it satisfies and reports whether it
+// should still alert: with rules_only set, only transactions matching at
+// least one rule do.
+func (s *Scanner) filterRules(m *matchedTx) bool {
+	m.rules = matchRules(s.rules, *m)
+	return !s.cfg.RulesOnly || len(m.rules) > 0
+}
+
+// filterNFTRules is filterRules for an NFT transfer, whose rules are
+// evaluated against the transaction that emitted it. A transfer whose
+// transaction cannot be read matches no rule.
+func (s *Scanner) filterNFTRules(block *types.Block, signer types.Signer, t *nftTransfer) bool {
+	m, ok := enclosingTx(block, signer, *t)
+	if !ok {
+		return !s.cfg.RulesOnly
+	}
+	ok = s.filterRules(&m)
+	t.rules = m.rules
+	return ok
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+var sampledOut = metrics.NewCounterVec("blocksentinel_sampled_out_total", "Matches not sent to the analyzer because of sample_rate.", "chain")
+
+// sampled reports whether p goes to the analyzer under sample_rate. Matches
+// worth at least sample_above_wei always do; the rest are kept when their
+// transaction hash falls in the sampled fraction, so re-scans and NFT
+// transfers of the same transaction get the same answer.
+func (s *Scanner) sampled(p *TxPayload) bool {
+	rate := s.cfg.SampleRate
+	if rate >= 1 {
+		return true
+	}
+	if min := s.cfg.sampleAbove(); min != nil {
+		if v, ok := new(big.Int).SetString(p.Value, 10); ok && v.Cmp(min) >= 0 {
+			return true
+		}
+	}
+	h := common.HexToHash(p.Hash)
+	return float64(binary.BigEndian.Uint64(h[:8])) < rate*math.MaxUint64
+}
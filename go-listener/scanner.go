@@ -3,101 +3,884 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
 )
 
-func fetchNewTransactions(client *ethclient.Client, wallets []string, lastBlock uint64, analyzerURL string) (uint64, error) {
+var (
+	headBlockGauge = metrics.NewGaugeVec("blocksentinel_head_block", "Latest block number reported by the RPC node.", "chain")
+	lastBlockGauge = metrics.NewGaugeVec("blocksentinel_last_processed_block", "Last block fully scanned by the listener.", "chain")
+	lastPollGauge  = metrics.NewGaugeVec("blocksentinel_last_poll_timestamp_seconds", "Unix time of the last successful poll of the chain head.", "chain")
+	blockLagGauge  = metrics.NewGaugeVec("blocksentinel_block_lag", "Blocks between the latest confirmed block (head minus confirmations) and the last processed block.", "chain")
+)
+
+// knownSelectors maps common ERC-20/ERC-721 function selectors to method names.
+var knownSelectors = map[string]string{
+	"a9059cbb": "transfer",
+	"23b872dd": "transferFrom",
+	"095ea7b3": "approve",
+	"a22cb465": "setApprovalForAll",
+	"42842e0e": "safeTransferFrom",
+	"b88d4fde": "safeTransferFrom",
+}
+
+// decodeMethod returns the method name for the 4-byte selector of input, or the
+// raw selector hex when unknown. ok is false when input carries no selector.
+func decodeMethod(input []byte) (method string, ok bool) {
+	if len(input) < 4 {
+		return "", false
+	}
+	selector := common.Bytes2Hex(input[:4])
+	if name, found := knownSelectors[selector]; found {
+		return name, true
+	}
+	return "0x" + selector, true
+}
+
+// Scanner carries the client, config and alert sinks of one chain across loop ticks.
+type Scanner struct {
+	client         EthClient
+	cfg            *Config
+	chain          ChainConfig
+	notifiers      []Notifier
+	digest         *digester          // nil sends every notification immediately
+	pool           *pgxpool.Pool      // optional; nil when Postgres is unavailable
+	writes         *writeBuffer       // Postgres writes, buffered through outages; set with pool
+	queue          analysisQueue      // failed analyzer sends awaiting retry
+	wallets        *WalletCache       // per-address settings; nil uses the global config
+	counterparties *counterpartyCache // flagged addresses; nil disables screening
+	spamTokens     *spamTokenCache    // token denylist; nil disables it
+	autoLabels     *autoLabeler       // nil disables auto-labelling
+	groups         *groupCache        // wallet group memberships; nil without Postgres
+	state          StateStore         // scan positions and recently forwarded transactions
+	prices         PriceFeed          // optional; nil disables valueUSD
+	abis           map[common.Address]*abi.ABI
+	rules          []rule
+	tiers          []confirmationTier
+	archiver       Archiver // optional; nil disables block archiving
+
+	held    heldMatches   // matches waiting for their confirmation tier
+	scanned scannedHashes // recently scanned blocks, for reorg detection
+
+	// Set once by loadChainID; the chain ID cannot change within a session
+	chainID *big.Int
+	signer  types.Signer
+
+	tracingUnsupported bool
+
+	progress scanProgress // read by watchForStalls
+
+	headGauge *metrics.Metric
+	lastGauge *metrics.Metric
+	lagGauge  *metrics.Metric
+	pollGauge *metrics.Metric
+}
+
+func newScanner(client EthClient, cfg *Config, chain ChainConfig, pool *pgxpool.Pool) (*Scanner, error) {
+	notifiers, err := buildNotifiers(cfg.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+	rl := newRateLimitedClient(client, cfg.RPCRequestsPerSecond)
+	prices, err := newPriceFeed(cfg, rl)
+	if err != nil {
+		return nil, err
+	}
+	abis, err := loadContractABIs(cfg.ContractABIs)
+	if err != nil {
+		return nil, err
+	}
+	archiver, err := newArchiver(cfg.Archive)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := compileRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := compileConfirmationTiers(cfg.ConfirmationTiers)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{
+		client:    rl,
+		cfg:       cfg,
+		chain:     chain,
+		notifiers: notifiers,
+		digest:    newDigester(notifiers, cfg.NotificationDigest),
+		pool:      pool,
+		prices:    prices,
+		abis:      abis,
+		rules:     rules,
+		tiers:     tiers,
+		archiver:  archiver,
+		headGauge: headBlockGauge.With(chain.Name),
+		lastGauge: lastBlockGauge.With(chain.Name),
+		lagGauge:  blockLagGauge.With(chain.Name),
+		pollGauge: lastPollGauge.With(chain.Name),
+	}, nil
+}
+
+// printf writes progress output prefixed with the chain name.
+func (s *Scanner) printf(format string, args ...interface{}) {
+	fmt.Printf("[%s] "+format, append([]interface{}{s.chain.Name}, args...)...)
+}
+
+// logf logs prefixed with the chain name.
+func (s *Scanner) logf(format string, args ...interface{}) {
+	log.Printf("[%s] "+format, append([]interface{}{s.chain.Name}, args...)...)
+}
+
+// txLogf is logf tagged with the correlation ID of the transaction in ctx.
+func (s *Scanner) txLogf(ctx context.Context, format string, args ...interface{}) {
+	s.logf(correlationPrefix(ctx)+format, args...)
+}
+
+// txPrintf is printf tagged with the correlation ID of the transaction in ctx.
+func (s *Scanner) txPrintf(ctx context.Context, format string, args ...interface{}) {
+	s.printf(correlationPrefix(ctx)+format, args...)
+}
+
+// debugf logs like logf when log_level is debug.
+func (s *Scanner) debugf(format string, args ...interface{}) {
+	if s.cfg.LogLevel == logLevelDebug {
+		s.logf(format, args...)
+	}
+}
+
+// errEmptyHeader is returned when the node reports no latest header.
+var errEmptyHeader = errors.New("RPC node returned an empty latest header")
+
+// fetchNewTransactions scans blocks after lastBlock up to the chain head, at most
+// MaxBlocksPerBatch per call (0 means unbounded), and returns the last block scanned.
+func (s *Scanner) fetchNewTransactions(walletSet map[common.Address]bool, lastBlock uint64) (uint64, error) {
 	ctx := context.Background()
+	client := s.client
 
 	latestHeader, err := client.HeaderByNumber(ctx, nil)
 	if err != nil {
 		return lastBlock, err
 	}
-	latestBlock := latestHeader.Number.Uint64()
+	// Some providers answer (nil, nil) under load; retry on the next tick
+	if latestHeader == nil || latestHeader.Number == nil {
+		return lastBlock, errEmptyHeader
+	}
+	headBlock := latestHeader.Number.Uint64()
+	s.headGauge.Set(float64(headBlock))
+	s.pollGauge.Set(float64(time.Now().Unix()))
+	s.releaseConfirmed(ctx, headBlock, walletSet)
 
-	if lastBlock == 0 && latestBlock > 1000 {
-		lastBlock = latestBlock - 1000
-		fmt.Printf("Starting from recent block: %d (latest: %d)\n", lastBlock, latestBlock)
+	// Blocks within Confirmations of the head may still reorg; leave them for a later tick
+	latestBlock := uint64(0)
+	if confirmations := uint64(s.cfg.Confirmations); headBlock > confirmations {
+		latestBlock = headBlock - confirmations
+	}
+
+	// No saved position: start from StartupLookback
+	if lastBlock == 0 {
+		if start := s.lookbackStart(latestBlock); start > 0 {
+			lastBlock = start
+			s.printf("Starting from recent block: %d (latest confirmed: %d)\n", lastBlock, latestBlock)
+		}
 	}
 
 	if lastBlock >= latestBlock {
+		s.lagGauge.Set(0)
+		s.progress.observe(lastBlock, latestBlock)
 		return lastBlock, nil
 	}
 
-	walletSet := make(map[common.Address]bool)
-	for _, w := range wallets {
-		walletSet[common.HexToAddress(w)] = true
+	// Make sure the last block scanned is still on the chain before building on it
+	if rewound, err := s.checkReorg(ctx, lastBlock, latestBlock); err != nil || rewound != lastBlock {
+		return rewound, err
 	}
 
-	chainID, err := client.NetworkID(ctx)
+	// Bound the range so a long catch-up is split across loop ticks
+	toBlock := latestBlock
+	maxBlocks := uint64(0)
+	if s.cfg.MaxBlocksPerBatch > 0 {
+		maxBlocks = uint64(s.cfg.MaxBlocksPerBatch)
+	}
+	if maxBlocks > 0 && toBlock-lastBlock > maxBlocks {
+		toBlock = lastBlock + maxBlocks
+		s.printf("📦 Catching up: scanning %d of %d pending blocks\n", maxBlocks, latestBlock-lastBlock)
+	}
+	defer func() {
+		s.lastGauge.Set(float64(lastBlock))
+		s.lagGauge.Set(float64(latestBlock - lastBlock))
+	}()
+
+	lastBlock, err = s.scanRange(ctx, walletSet, lastBlock, toBlock)
+	s.progress.observe(lastBlock, latestBlock)
+	return lastBlock, err
+}
+
+// lookbackStart returns the position StartupLookback gives for a confirmed
+// head of latestBlock: that many blocks behind it, the head itself when
+// negative, or genesis when 0.
+func (s *Scanner) lookbackStart(latestBlock uint64) uint64 {
+	if s.cfg.StartupLookback == 0 {
+		return 0
+	}
+	lookback := uint64(0)
+	if s.cfg.StartupLookback > 0 {
+		lookback = uint64(s.cfg.StartupLookback)
+	}
+	if latestBlock > lookback {
+		return latestBlock - lookback
+	}
+	return 0
+}
+
+// scanRange scans blocks lastBlock+1 through toBlock and returns the last block
+// fully scanned, which is lastBlock itself if the first block fails.
+func (s *Scanner) scanRange(ctx context.Context, walletSet map[common.Address]bool, lastBlock, toBlock uint64) (uint64, error) {
+	chainID, signer := s.chainID, s.signer
+
+	nftTransfers, err := s.fetchNFTTransfers(ctx, walletSet, lastBlock+1, toBlock)
 	if err != nil {
+		s.logf("Error fetching NFT transfers: %v", err)
 		return lastBlock, err
 	}
-	signer := types.LatestSignerForChainID(chainID)
 
-	for blockNum := lastBlock + 1; blockNum <= latestBlock; blockNum++ {
-		block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(blockNum))
+	logsOnly := s.cfg.ScanMode == scanModeLogs
+	for blockNum := lastBlock + 1; blockNum <= toBlock; blockNum++ {
+		if logsOnly && len(nftTransfers[blockNum]) == 0 {
+			lastBlock = blockNum
+			continue
+		}
+		block, err := s.fetchBlock(ctx, blockNum)
 		if err != nil {
-			log.Printf("Error fetching block %d: %v", blockNum, err)
-			return lastBlock, err
+			// An unreachable node is not the block's fault, so never skip past it
+			if s.cfg.OnBlockFailure != blockFailureSkip || errors.Is(err, errRPCReconnectFailed) {
+				return lastBlock, err
+			}
+			s.skipBlock(ctx, chainID.Uint64(), blockNum, err)
+			lastBlock = blockNum
+			continue
 		}
 
-		fmt.Printf("Scanning block %d (%d transactions)\n", blockNum, len(block.Transactions()))
+		s.printf("Scanning block %d (%d transactions)\n", blockNum, len(block.Transactions()))
+
+		var matches []matchedTx
+		if !logsOnly {
+			matches = matchBlock(block, signer, walletSet, s.directionFor, s.cfg.ScanCalldataForAddresses)
+		}
+		receipts := s.fetchReceipts(ctx, block, matches)
 
 		foundCount := 0
-		for _, tx := range block.Transactions() {
-			from, err := types.Sender(signer, tx)
-			if err != nil {
+		touched := make(map[common.Address]bool)
+		for _, m := range matches {
+			receipt := receipts[m.tx.Hash()]
+			if s.cfg.SkipReverted && receipt != nil && receipt.Status == types.ReceiptStatusFailed {
+				s.printf("Skipping reverted transaction %s\n", m.tx.Hash().Hex())
 				continue
 			}
-
-			to := common.Address{}
-			if tx.To() != nil {
-				to = *tx.To()
+			if min := s.minValueFor(m.wallet); min != nil && m.tx.Value().Cmp(min) < 0 {
+				continue
 			}
-
-			if walletSet[from] || walletSet[to] {
-				foundCount++
-				txData := map[string]interface{}{
-					"hash":  tx.Hash().Hex(),
-					"from":  from.Hex(),
-					"to":    to.Hex(),
-					"value": tx.Value().String(),
-					"gas":   tx.Gas(),
-					"gasPrice": func() string {
-						if tx.GasPrice() != nil {
-							return tx.GasPrice().String()
-						}
-						return "0"
-					}(),
-					"blockNum":  blockNum,
-					"timestamp": block.Time(),
-					"input":     common.Bytes2Hex(tx.Data()),
+			if !s.filterRules(&m) {
+				continue
+			}
+			if s.suppressSpam(m.tx.Hash(), s.spamMatch(ctx, m)) {
+				continue
+			}
+			foundCount++
+			for _, addr := range []common.Address{m.from, m.to, m.created} {
+				if walletSet[addr] {
+					touched[addr] = true
 				}
+			}
+			if !s.holdUntilConfirmed(block, m) {
+				s.processMatch(ctx, block, chainID.Uint64(), m, receipt, walletSet)
+			}
+		}
 
-				jsonData, _ := json.Marshal(txData)
-				fmt.Printf("Found relevant transaction: %s\n", string(jsonData))
-
-				if analyzerURL != "" {
-					if err := sendToAIAnalyzer(analyzerURL, txData); err != nil {
-						log.Printf("Error sending to AI analyzer: %v", err)
-					}
+		for _, t := range nftTransfers[blockNum] {
+			if !s.filterNFTRules(block, signer, &t) {
+				continue
+			}
+			if s.suppressSpam(t.txHash, s.spamNFTTransfer(ctx, block, signer, t)) {
+				continue
+			}
+			foundCount++
+			for _, addr := range []common.Address{t.from, t.to} {
+				if walletSet[addr] {
+					touched[addr] = true
 				}
 			}
+			s.processNFTTransfer(ctx, block, chainID.Uint64(), signer, t, walletSet)
 		}
 
 		if foundCount > 0 {
-			fmt.Printf("Found %d relevant transactions in block %d\n", foundCount, blockNum)
+			s.printf("Found %d relevant transactions in block %d\n", foundCount, blockNum)
+			s.archiveBlock(ctx, chainID.Uint64(), block)
 		}
+		s.touchWallets(ctx, touched, block.Time())
+		s.auditBlock(ctx, chainID.Uint64(), block, foundCount)
+		s.scanned.record(blockNum, block.Hash(), uint64(s.cfg.MaxReorgDepth))
 
 		lastBlock = blockNum
 	}
 
 	return lastBlock, nil
 }
+
+const (
+	blockFailureHalt = "halt"
+	blockFailureSkip = "skip"
+)
+
+// Scan modes.
+const (
+	scanModeBlocks = "blocks"
+	scanModeLogs   = "logs" // NFT events only; blocks without one are never downloaded
+)
+
+const (
+	chainIDAttempts = 5
+	chainIDTimeout  = 10 * time.Second
+)
+
+// loadChainID fetches the chain ID once, retrying transient failures with
+// exponential backoff, and caches it with the matching signer for scanRange.
+func (s *Scanner) loadChainID(ctx context.Context) (*big.Int, error) {
+	wait := time.Second
+	var err error
+	for i := 1; i <= chainIDAttempts; i++ {
+		callCtx, cancel := context.WithTimeout(ctx, chainIDTimeout)
+		var chainID *big.Int
+		chainID, err = s.client.NetworkID(callCtx)
+		cancel()
+		if err == nil {
+			signer, err := newChainSigner(s.chain.Signer, s.chain.Name, chainID)
+			if err != nil {
+				return nil, err
+			}
+			s.chainID = chainID
+			s.signer = signer
+			return chainID, nil
+		}
+		s.logf("Error fetching chain ID (attempt %d/%d): %v", i, chainIDAttempts, err)
+		if i == chainIDAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+	return nil, err
+}
+
+// fetchBlock fetches one block, retrying transient failures with exponential
+// backoff up to BlockRetryAttempts times.
+func (s *Scanner) fetchBlock(ctx context.Context, blockNum uint64) (*types.Block, error) {
+	attempts := s.cfg.BlockRetryAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := time.Second
+	var err error
+	for i := 1; i <= attempts; i++ {
+		var block *types.Block
+		block, err = s.client.BlockByNumber(ctx, new(big.Int).SetUint64(blockNum))
+		if err == nil {
+			return block, nil
+		}
+		s.logf("Error fetching block %d (attempt %d/%d): %v", blockNum, i, attempts, err)
+		if i == attempts || errors.Is(err, errRPCReconnectFailed) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+	return nil, err
+}
+
+// skipBlock records a block that exhausted its retries so it can be reprocessed later.
+func (s *Scanner) skipBlock(ctx context.Context, chainID, blockNum uint64, cause error) {
+	s.logf("⚠️  Skipping block %d after %d attempts: %v", blockNum, s.cfg.BlockRetryAttempts, cause)
+	if s.pool == nil {
+		return
+	}
+	err := s.writes.write(ctx, fmt.Sprintf("skipped block %d", blockNum), func(ctx context.Context, pool *pgxpool.Pool) error {
+		return dbpkg.RecordSkippedBlock(ctx, pool, chainID, blockNum, s.cfg.BlockRetryAttempts, cause.Error())
+	})
+	if err != nil {
+		s.logf("Error recording skipped block %d: %v", blockNum, err)
+	}
+}
+
+// directionFor returns the direction filter for a monitored wallet.
+func (s *Scanner) directionFor(addr common.Address) string {
+	if ws := s.wallets.Settings(addr); ws != nil && ws.Direction != "" {
+		return ws.Direction
+	}
+	return s.cfg.Direction
+}
+
+// minValueFor returns the smallest transfer value that alerts for wallet, or
+// nil when every value does.
+func (s *Scanner) minValueFor(addr common.Address) *big.Int {
+	if ws := s.wallets.Settings(addr); ws != nil && ws.MinValueWei != "" {
+		return ws.MinValue()
+	}
+	return s.cfg.minValue()
+}
+
+// riskThresholdFor returns the risk score at which wallet's alerts notify.
+func (s *Scanner) riskThresholdFor(addr common.Address) float64 {
+	if ws := s.wallets.Settings(addr); ws != nil && ws.MinRiskScore != nil {
+		return *ws.MinRiskScore
+	}
+	return s.cfg.live().RiskThreshold
+}
+
+// matchedTx is a block transaction involving at least one monitored wallet.
+type matchedTx struct {
+	tx         *types.Transaction
+	from       common.Address
+	to         common.Address // zero for contract creations
+	created    common.Address // deployed contract address for contract creations
+	isCreation bool
+	wallet     common.Address   // monitored party the alert is attributed to
+	index      uint             // position within the block; unset for pending transactions
+	direction  string           // "incoming", "outgoing" or "both" relative to monitored wallets
+	referenced []common.Address // monitored wallets found in the calldata, when scanned
+	rules      []string         // names of the configured rules it satisfies
+}
+
+// Direction filters, relative to the monitored wallet.
+const (
+	directionBoth     = "both"
+	directionIncoming = "incoming"
+	directionOutgoing = "outgoing"
+)
+
+// matchDirection decides whether a transfer from -> to is kept, given each
+// monitored side's direction filter, returning the matched direction and
+// whether it is kept. Unmonitored sides pass an empty filter.
+func matchDirection(fromFilter, toFilter string) (string, bool) {
+	out := fromFilter != "" && fromFilter != directionIncoming
+	in := toFilter != "" && toFilter != directionOutgoing
+	switch {
+	case out && in:
+		return directionBoth, true
+	case out:
+		return directionOutgoing, true
+	case in:
+		return directionIncoming, true
+	}
+	return "", false
+}
+
+// walletFilter returns addr's direction filter, or "" when addr is not monitored.
+func walletFilter(walletSet map[common.Address]bool, directionFor func(common.Address) string, addr common.Address) string {
+	if !walletSet[addr] {
+		return ""
+	}
+	return directionFor(addr)
+}
+
+// matchBlock returns the transactions of block that touch a monitored wallet
+// in the configured direction.
+func matchBlock(block *types.Block, signer types.Signer, walletSet map[common.Address]bool, directionFor func(common.Address) string, scanCalldata bool) []matchedTx {
+	var matches []matchedTx
+	for i, tx := range block.Transactions() {
+		if m, ok := matchTx(tx, signer, walletSet, directionFor, scanCalldata); ok {
+			m.index = uint(i)
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// matchTx reports whether tx touches a monitored wallet in an allowed direction.
+// With scanCalldata, a contract call whose calldata references a monitored
+// wallet also matches, as incoming to that wallet (see referencedWallets).
+func matchTx(tx *types.Transaction, signer types.Signer, walletSet map[common.Address]bool, directionFor func(common.Address) string, scanCalldata bool) (matchedTx, bool) {
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return matchedTx{}, false
+	}
+
+	// Contract creations have no recipient; derive the deployed address instead
+	m := matchedTx{tx: tx, from: from, isCreation: tx.To() == nil}
+	if m.isCreation {
+		m.created = crypto.CreateAddress(from, tx.Nonce())
+	} else {
+		m.to = *tx.To()
+	}
+
+	recipient := m.to
+	if m.isCreation {
+		recipient = m.created
+	}
+	if scanCalldata && !m.isCreation {
+		m.referenced = referencedWallets(tx.Data(), walletSet)
+	}
+	var ok bool
+	m.direction, ok = matchDirection(walletFilter(walletSet, directionFor, m.from), walletFilter(walletSet, directionFor, recipient))
+	if !ok {
+		for _, w := range m.referenced {
+			if _, in := matchDirection("", walletFilter(walletSet, directionFor, w)); in {
+				m.direction, m.wallet = directionIncoming, w
+				return m, true
+			}
+		}
+		return matchedTx{}, false
+	}
+	m.wallet = m.from
+	if m.direction == directionIncoming {
+		m.wallet = recipient
+	}
+	return m, true
+}
+
+// fetchReceipts loads receipts for the matched transactions. Several matches in
+// one block are fetched with a single eth_getBlockReceipts call, falling back
+// to per-transaction lookups when the provider does not support it.
+func (s *Scanner) fetchReceipts(ctx context.Context, block *types.Block, matches []matchedTx) map[common.Hash]*types.Receipt {
+	receipts := make(map[common.Hash]*types.Receipt, len(matches))
+	if len(matches) == 0 {
+		return receipts
+	}
+	if len(matches) > 1 {
+		all, err := s.client.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+		if err == nil {
+			for _, r := range all {
+				receipts[r.TxHash] = r
+			}
+			return receipts
+		}
+	}
+	for _, m := range matches {
+		r, err := s.client.TransactionReceipt(ctx, m.tx.Hash())
+		if err != nil {
+			s.logf("Error fetching receipt for %s: %v", m.tx.Hash().Hex(), err)
+			continue
+		}
+		receipts[m.tx.Hash()] = r
+	}
+	return receipts
+}
+
+// processMatch builds the alert payload for one matched transaction, stores it
+// and forwards it to the analyzer.
+func (s *Scanner) processMatch(ctx context.Context, block *types.Block, chainID uint64, m matchedTx, receipt *types.Receipt, walletSet map[common.Address]bool) {
+	tx := m.tx
+	nonce, index := tx.Nonce(), m.index
+	p := &TxPayload{
+		Hash:         tx.Hash().Hex(),
+		From:         m.from.Hex(),
+		To:           m.to.Hex(),
+		Value:        tx.Value().String(),
+		Gas:          tx.Gas(),
+		GasPrice:     minedGasPrice(tx, block.BaseFee()),
+		TxType:       tx.Type(),
+		Nonce:        &nonce,
+		TxIndex:      &index,
+		BlockNum:     block.NumberU64(),
+		Timestamp:    block.Time(),
+		TimestampISO: isoTime(block.Time()),
+		ChainID:      chainID,
+		Direction:    m.direction,
+	}
+	p.CorrelationID = correlationID(chainID, p.Hash)
+	ctx = withCorrelation(ctx, p.CorrelationID)
+	s.setInput(p, tx.Data())
+	p.ReferencedWallets = hexAddresses(m.referenced)
+	p.MatchedRules = m.rules
+	if usd, ok := s.valueUSD(ctx, tx.Value(), block.NumberU64(), block.Time()); ok {
+		p.ValueUSD = &usd
+	}
+	// Dynamic-fee (EIP-1559) and later types price gas by fee cap and tip
+	if tx.Type() >= types.DynamicFeeTxType {
+		p.MaxFeePerGas = bigOrZero(tx.GasFeeCap())
+		p.MaxPriorityFeePerGas = bigOrZero(tx.GasTipCap())
+	}
+	setBlobFields(p, tx, receipt)
+	if m.isCreation {
+		p.Type = "contract_creation"
+		p.ContractAddress = m.created.Hex()
+	} else {
+		s.setMethod(p, m.to, tx.Data())
+	}
+	if receipt != nil {
+		status, gasUsed := receipt.Status, receipt.GasUsed
+		p.Status = &status
+		p.GasUsed = &gasUsed
+		if receipt.EffectiveGasPrice != nil {
+			p.EffectiveGasPrice = receipt.EffectiveGasPrice.String()
+		}
+	}
+	recipient := m.to
+	if m.isCreation {
+		recipient = m.created
+	}
+	s.flagCounterparty(ctx, p, m.wallet, m.from, recipient)
+	p.ENSName = s.wallets.ENSName(m.wallet)
+	p.Groups = s.groups.Groups(ctx, m.wallet)
+	if !m.isCreation && len(tx.Data()) > 0 {
+		s.traceWalletTransfers(ctx, tx.Hash(), walletSet, p)
+	}
+
+	jsonData, _ := json.Marshal(p)
+	s.txPrintf(ctx, "Found relevant transaction: %s\n", string(jsonData))
+
+	rec := transactionRecord(chainID, block, m, receipt)
+	if p.MethodSignature != "" {
+		// Decoded from the contract's own ABI, so better than the generic table
+		rec.MethodName = p.Method
+	}
+	if s.storeTransaction(ctx, rec) {
+		s.txPrintf(ctx, "↩️  Already forwarded %s recently; skipping\n", rec.Hash)
+		return
+	}
+	s.analyze(ctx, chainID, m.wallet.Hex(), p)
+}
+
+// setInput adds the calldata to p as hex, cut to MaxInputBytes so large
+// blobs don't bloat analyzer requests and logs. Truncated payloads carry
+// inputTruncated and the original inputBytes; the stored record keeps it all.
+func (s *Scanner) setInput(p *TxPayload, data []byte) {
+	if max := s.cfg.MaxInputBytes; max > 0 && len(data) > max {
+		p.Input = common.Bytes2Hex(data[:max])
+		p.InputTruncated = true
+		p.InputBytes = len(data)
+		return
+	}
+	p.Input = common.Bytes2Hex(data)
+}
+
+// isoTime formats a unix block timestamp as RFC3339 in UTC.
+func isoTime(unix uint64) string {
+	return time.Unix(int64(unix), 0).UTC().Format(time.RFC3339)
+}
+
+// bigOrZero formats v in decimal, treating nil as zero.
+func bigOrZero(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+// transactionRecord converts a matched transaction into its database row.
+func transactionRecord(chainID uint64, block *types.Block, m matchedTx, receipt *types.Receipt) dbpkg.Transaction {
+	tx := m.tx
+	rec := dbpkg.Transaction{
+		ChainID:        chainID,
+		Hash:           tx.Hash().Hex(),
+		From:           m.from.Hex(),
+		ValueWei:       tx.Value().String(),
+		GasPriceWei:    minedGasPrice(tx, block.BaseFee()),
+		BlockNum:       block.NumberU64(),
+		BlockTimestamp: block.Time(),
+		InputHex:       common.Bytes2Hex(tx.Data()),
+	}
+	if !m.isCreation {
+		toHex := m.to.Hex()
+		rec.To = &toHex
+		if data := tx.Data(); len(data) >= 4 {
+			rec.MethodSelector = "0x" + common.Bytes2Hex(data[:4])
+			if name, _ := decodeMethod(data); name != rec.MethodSelector {
+				rec.MethodName = name
+			}
+		}
+	}
+	if receipt != nil {
+		gasUsed := int64(receipt.GasUsed)
+		rec.GasUsed = &gasUsed
+	}
+	return rec
+}
+
+// analyze forwards p to the analyzers, queueing it for retry when none of
+// them answered. With AnalyzerBreakerAction "drop", a transaction no analyzer
+// was tried for because their breakers are open is dropped instead.
+func (s *Scanner) analyze(ctx context.Context, chainID uint64, wallet string, p *TxPayload) {
+	publishTransaction(p)
+	if len(s.cfg.live().AnalyzerURLs) == 0 {
+		return
+	}
+	if !s.sampled(p) {
+		sampledOut.With(s.chain.Name).Inc()
+		s.debugf(correlationPrefix(ctx)+"🎲 Not sending %s to the analyzer: sampled out", p.Hash)
+		return
+	}
+	if s.dryRun(ctx, wallet, p) {
+		return
+	}
+	results, err := sendToAnalyzers(ctx, s.cfg, p)
+	if err != nil {
+		s.txLogf(ctx, "Error sending to AI analyzer: %v", err)
+	}
+	if len(results) == 0 {
+		if s.cfg.AnalyzerBreakerAction == breakerActionDrop && onlyBreakerErrors(err) {
+			s.txLogf(ctx, "Dropping %s: every analyzer's circuit breaker is open", p.Hash)
+			return
+		}
+		s.enqueueFailedAnalysis(ctx, chainID, wallet, p, err)
+		return
+	}
+	s.handleAnalysisResults(ctx, chainID, wallet, p, results)
+}
+
+// dryRun logs what would be forwarded for p and reports whether outbound
+// calls should be suppressed.
+func (s *Scanner) dryRun(ctx context.Context, wallet string, p *TxPayload) bool {
+	if !s.cfg.DryRun {
+		return false
+	}
+	msg := fmt.Sprintf("🧪 [dry-run] would send %s to the analyzer", p.Hash)
+	if len(s.notifiers) > 0 {
+		msg += fmt.Sprintf(" and notify %d notifier(s) at risk >= %.2f", len(s.notifiers), s.riskThresholdFor(common.HexToAddress(wallet)))
+	}
+	s.txPrintf(ctx, "%s\n", msg)
+	return true
+}
+
+// handleAnalysisResults persists each analyzer's verdict and alerts on the
+// highest one.
+func (s *Scanner) handleAnalysisResults(ctx context.Context, chainID uint64, wallet string, p *TxPayload, results map[string]*RiskResult) {
+	for analyzer, result := range results {
+		s.storeRiskAssessment(ctx, chainID, p.Hash, analyzer, result)
+	}
+	result := highestRisk(s.cfg, results)
+	publishRisk(chainID, wallet, p, result)
+	s.notifyIfRisky(p, wallet, result)
+	s.autoLabel(ctx, common.HexToAddress(wallet), p, result)
+}
+
+// notifyIfRisky dispatches a notification when the analyzer score reaches the threshold.
+func (s *Scanner) notifyIfRisky(p *TxPayload, wallet string, result *RiskResult) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+	if result.Score < s.riskThresholdFor(common.HexToAddress(wallet)) {
+		return
+	}
+	n := Notification{
+		TxHash:    p.Hash,
+		Wallet:    wallet,
+		From:      p.From,
+		To:        p.To,
+		Value:     p.Value,
+		BlockNum:  p.BlockNum,
+		RiskScore: result.Score,
+		RiskLevel: result.Level,
+		ENSName:   p.ENSName,
+		Groups:    p.Groups,
+
+		CorrelationID: p.CorrelationID,
+	}
+	// Bursts are batched into digests; only the riskiest alerts go out at once
+	if s.digest != nil && result.Score < s.cfg.NotificationDigest.ImmediateThreshold {
+		s.digest.add(n)
+		return
+	}
+	dispatchNotification(s.notifiers, n)
+}
+
+// touchWallets updates first_seen/last_seen for the wallets active in one block.
+func (s *Scanner) touchWallets(ctx context.Context, touched map[common.Address]bool, blockTime uint64) {
+	if s.pool == nil || len(touched) == 0 || s.cfg.DryRun {
+		return
+	}
+	addrs := make([]string, 0, len(touched))
+	for addr := range touched {
+		addrs = append(addrs, addr.Hex())
+	}
+	ts := time.Unix(int64(blockTime), 0).UTC()
+	err := s.writes.write(ctx, "address activity", func(ctx context.Context, pool *pgxpool.Pool) error {
+		return dbpkg.TouchAddresses(ctx, pool, addrs, ts)
+	})
+	if err != nil {
+		s.logf("Error updating address activity: %v", err)
+	}
+}
+
+// backfillScanner returns a scanner for an API backfill that runs alongside the
+// live loop. It shares s's client, caches and sinks, which are safe for
+// concurrent use, but starts with its own held matches, scanned hashes and
+// tracing probe so the two never race on that per-session state.
+func (s *Scanner) backfillScanner() *Scanner {
+	return &Scanner{
+		client:         s.client,
+		cfg:            s.cfg,
+		chain:          s.chain,
+		notifiers:      s.notifiers,
+		digest:         s.digest,
+		pool:           s.pool,
+		writes:         s.writes,
+		queue:          s.queue,
+		wallets:        s.wallets,
+		counterparties: s.counterparties,
+		spamTokens:     s.spamTokens,
+		autoLabels:     s.autoLabels,
+		groups:         s.groups,
+		state:          s.state,
+		prices:         s.prices,
+		abis:           s.abis,
+		rules:          s.rules,
+		tiers:          s.tiers,
+		archiver:       s.archiver,
+		chainID:        s.chainID,
+		signer:         s.signer,
+		headGauge:      s.headGauge,
+		lastGauge:      s.lastGauge,
+		lagGauge:       s.lagGauge,
+		pollGauge:      s.pollGauge,
+	}
+}
+
+// backfill scans the closed range [from, to] in MaxBlocksPerBatch chunks without
+// touching the live-tail state, returning once to has been scanned.
+func (s *Scanner) backfill(walletSet map[common.Address]bool, from, to uint64) error {
+	ctx := context.Background()
+	chunk := uint64(s.cfg.MaxBlocksPerBatch)
+	if s.cfg.MaxBlocksPerBatch <= 0 {
+		chunk = to - from + 1
+	}
+
+	s.printf("⏪ Backfilling blocks %d-%d\n", from, to)
+	last := from - 1
+	if from == 0 {
+		// Block 0 holds no transactions; start at 1 to keep the range arithmetic unsigned
+		last = 0
+	}
+	for last < to {
+		end := last + chunk
+		if end > to || end < last {
+			end = to
+		}
+		next, err := s.scanRange(ctx, walletSet, last, end)
+		if err != nil {
+			return fmt.Errorf("backfill stopped after block %d: %w", next, err)
+		}
+		last = next
+		s.printf("⏪ Backfill progress: %d/%d\n", last, to)
+	}
+	s.printf("🏁 Backfill of blocks %d-%d complete\n", from, to)
+	return nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var errNotServed = errors.New("not served by fakeClient")
+
+// fakeClient is an EthClient serving canned headers and blocks.
+type fakeClient struct {
+	mu      sync.Mutex
+	head    *types.Header // nil answers HeaderByNumber with (nil, nil)
+	blocks  map[uint64]*types.Block
+	fetched []uint64 // block numbers requested, in order
+}
+
+func (c *fakeClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return c.head, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.block(number.Uint64()).Header(), nil
+}
+
+func (c *fakeClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := number.Uint64()
+	c.fetched = append(c.fetched, n)
+	return c.block(n), nil
+}
+
+// block returns the canned block n. c.mu must be held.
+func (c *fakeClient) block(n uint64) *types.Block {
+	if b, ok := c.blocks[n]; ok {
+		return b
+	}
+	// Empty blocks keep tests from spelling out every number in a range
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(n), Time: n})
+}
+
+func (c *fakeClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (c *fakeClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, errNotServed
+}
+
+func (c *fakeClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return nil, ethereum.NotFound
+}
+
+func (c *fakeClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	return nil, false, ethereum.NotFound
+}
+
+func (c *fakeClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	return nil, errNotServed
+}
+
+func (c *fakeClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return errNotServed
+}
+
+func (c *fakeClient) fetchedBlocks() []uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.fetched)
+}
+
+// recordingAnalyzer is an analyzer endpoint that records every payload the
+// scanner sends it.
+type recordingAnalyzer struct {
+	payloads chan map[string]interface{}
+}
+
+// captureAnalyzer points cfg at a recordingAnalyzer for the test.
+func captureAnalyzer(t *testing.T, cfg *Config) *recordingAnalyzer {
+	t.Helper()
+	rec := &recordingAnalyzer{payloads: make(chan map[string]interface{}, 16)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rec.payloads <- p
+		w.Write([]byte(`{"risk_score": 0, "risk_level": "low"}`))
+	}))
+	t.Cleanup(srv.Close)
+	cfg.AnalyzerURLs = []string{srv.URL}
+	return rec
+}
+
+// collect waits for n payloads, then briefly for any unexpected extras.
+func (r *recordingAnalyzer) collect(t *testing.T, n int) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	timeout := time.After(2 * time.Second)
+	for len(out) < n {
+		select {
+		case p := <-r.payloads:
+			out = append(out, p)
+		case <-timeout:
+			t.Fatalf("got %d payloads, want %d", len(out), n)
+		}
+	}
+	select {
+	case p := <-r.payloads:
+		t.Fatalf("unexpected extra payload for %v", p["hash"])
+	case <-time.After(50 * time.Millisecond):
+	}
+	return out
+}
+
+func testConfig() *Config {
+	cfg := defaultConfig()
+	cfg.Confirmations = 0
+	cfg.MaxBlocksPerBatch = 0
+	cfg.BlockRetryAttempts = 1
+	return cfg
+}
+
+func newTestScanner(t *testing.T, client EthClient, cfg *Config) *Scanner {
+	t.Helper()
+	s, err := newScanner(client, cfg, ChainConfig{Name: "test"}, nil)
+	if err != nil {
+		t.Fatalf("newScanner: %v", err)
+	}
+	if _, err := s.loadChainID(context.Background()); err != nil {
+		t.Fatalf("loadChainID: %v", err)
+	}
+	s.state = &fileStateStore{path: filepath.Join(t.TempDir(), "state.json"), seen: newSeenCache(cfg.SeenCacheSize)}
+	return s
+}
+
+func walletSet(wallets ...common.Address) map[common.Address]bool {
+	set := make(map[common.Address]bool, len(wallets))
+	for _, w := range wallets {
+		set[w] = true
+	}
+	return set
+}
+
+func mustKey(t *testing.T) (*ecdsa.PrivateKey, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func signedTransfer(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, to common.Address, value int64) *types.Transaction {
+	t.Helper()
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(value),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func testBlock(number uint64, txs ...*types.Transaction) *types.Block {
+	header := &types.Header{Number: new(big.Int).SetUint64(number), Time: 1_700_000_000 + number, BaseFee: big.NewInt(10)}
+	return types.NewBlock(header, &types.Body{Transactions: txs}, nil, trie.NewStackTrie(nil))
+}
+
+func headAt(number uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(number)}
+}
+
+func TestScanRangeMatchesBothDirections(t *testing.T) {
+	walletKey, wallet := mustKey(t)
+	otherKey, other := mustKey(t)
+	_, stranger := mustKey(t)
+
+	outgoing := signedTransfer(t, walletKey, 0, other, 1)
+	incoming := signedTransfer(t, otherKey, 0, wallet, 2)
+	unrelated := signedTransfer(t, otherKey, 1, stranger, 3)
+	client := &fakeClient{
+		head:   headAt(1),
+		blocks: map[uint64]*types.Block{1: testBlock(1, outgoing, incoming, unrelated)},
+	}
+	cfg := testConfig()
+	rec := captureAnalyzer(t, cfg)
+	s := newTestScanner(t, client, cfg)
+
+	last, err := s.fetchNewTransactions(walletSet(wallet), 0)
+	if err != nil {
+		t.Fatalf("fetchNewTransactions: %v", err)
+	}
+	if last != 1 {
+		t.Fatalf("last block = %d, want 1", last)
+	}
+
+	got := map[interface{}]interface{}{}
+	for _, p := range rec.collect(t, 2) {
+		got[p["hash"]] = p["direction"]
+	}
+	want := map[string]string{
+		outgoing.Hash().Hex(): directionOutgoing,
+		incoming.Hash().Hex(): directionIncoming,
+	}
+	for hash, dir := range want {
+		if got[hash] != dir {
+			t.Errorf("%s: direction %v, want %q", hash, got[hash], dir)
+		}
+	}
+}
+
+func TestScanRangeHonoursWalletDirection(t *testing.T) {
+	walletKey, wallet := mustKey(t)
+	otherKey, other := mustKey(t)
+
+	outgoing := signedTransfer(t, walletKey, 0, other, 1)
+	incoming := signedTransfer(t, otherKey, 0, wallet, 2)
+	client := &fakeClient{
+		head:   headAt(1),
+		blocks: map[uint64]*types.Block{1: testBlock(1, outgoing, incoming)},
+	}
+	cfg := testConfig()
+	cfg.Direction = directionIncoming
+	rec := captureAnalyzer(t, cfg)
+	s := newTestScanner(t, client, cfg)
+
+	if _, err := s.fetchNewTransactions(walletSet(wallet), 0); err != nil {
+		t.Fatalf("fetchNewTransactions: %v", err)
+	}
+	got := rec.collect(t, 1)
+	if got[0]["hash"] != incoming.Hash().Hex() {
+		t.Errorf("forwarded %v, want only the incoming %s", got[0]["hash"], incoming.Hash().Hex())
+	}
+}
+
+func TestFetchNewTransactionsLeavesUnconfirmedBlocks(t *testing.T) {
+	client := &fakeClient{head: headAt(10)}
+	cfg := testConfig()
+	cfg.Confirmations = 3
+	s := newTestScanner(t, client, cfg)
+
+	last, err := s.fetchNewTransactions(walletSet(), 5)
+	if err != nil {
+		t.Fatalf("fetchNewTransactions: %v", err)
+	}
+	if last != 7 {
+		t.Errorf("last block = %d, want 7 (head 10 minus 3 confirmations)", last)
+	}
+	if got, want := client.fetchedBlocks(), []uint64{6, 7}; !slices.Equal(got, want) {
+		t.Errorf("fetched blocks %v, want %v", got, want)
+	}
+
+	// Nothing new is confirmed until the head moves
+	client.fetched = nil
+	last, err = s.fetchNewTransactions(walletSet(), last)
+	if err != nil || last != 7 {
+		t.Fatalf("second poll = (%d, %v), want (7, nil)", last, err)
+	}
+	if got := client.fetchedBlocks(); len(got) != 0 {
+		t.Errorf("second poll fetched %v, want nothing", got)
+	}
+}
+
+func TestFetchNewTransactionsBatchesCatchUp(t *testing.T) {
+	client := &fakeClient{head: headAt(5)}
+	cfg := testConfig()
+	cfg.MaxBlocksPerBatch = 2
+	s := newTestScanner(t, client, cfg)
+
+	var last uint64
+	var batches [][]uint64
+	for i := 0; i < 3; i++ {
+		client.fetched = nil
+		var err error
+		if last, err = s.fetchNewTransactions(walletSet(), last); err != nil {
+			t.Fatalf("poll %d: %v", i, err)
+		}
+		batches = append(batches, client.fetchedBlocks())
+	}
+	if last != 5 {
+		t.Errorf("last block = %d, want 5", last)
+	}
+	want := [][]uint64{{1, 2}, {3, 4}, {5}}
+	for i := range want {
+		if !slices.Equal(batches[i], want[i]) {
+			t.Errorf("batch %d fetched %v, want %v", i, batches[i], want[i])
+		}
+	}
+}
+
+func TestRescanSkipsRecentlyForwarded(t *testing.T) {
+	_, wallet := mustKey(t)
+	otherKey, _ := mustKey(t)
+	incoming := signedTransfer(t, otherKey, 0, wallet, 1)
+	client := &fakeClient{
+		head:   headAt(1),
+		blocks: map[uint64]*types.Block{1: testBlock(1, incoming)},
+	}
+	cfg := testConfig()
+	rec := captureAnalyzer(t, cfg)
+	s := newTestScanner(t, client, cfg)
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.fetchNewTransactions(walletSet(wallet), 0); err != nil {
+			t.Fatalf("scan %d: %v", i, err)
+		}
+	}
+	rec.collect(t, 1)
+}
+
+// Regression: some providers answer eth_getBlockByNumber("latest") with
+// (nil, nil) under load, which used to panic on the nil header.
+func TestFetchNewTransactionsNilHeader(t *testing.T) {
+	client := &fakeClient{}
+	s := newTestScanner(t, client, testConfig())
+
+	last, err := s.fetchNewTransactions(walletSet(), 42)
+	if !errors.Is(err, errEmptyHeader) {
+		t.Fatalf("err = %v, want errEmptyHeader", err)
+	}
+	if last != 42 {
+		t.Errorf("last block = %d, want it unchanged at 42", last)
+	}
+	if got := client.fetchedBlocks(); len(got) != 0 {
+		t.Errorf("fetched blocks %v after an empty header", got)
+	}
+}
+
+func TestRulesOnlyAppliesToNFTTransfers(t *testing.T) {
+	key, _ := mustKey(t)
+	_, collection := mustKey(t)
+	small := signedTransfer(t, key, 0, collection, 1)
+	large := signedTransfer(t, key, 1, collection, 5000)
+	block := testBlock(1, small, large)
+
+	cfg := testConfig()
+	cfg.Rules = []RuleConfig{{Name: "large", MinValueWei: "1000"}}
+	cfg.RulesOnly = true
+	s := newTestScanner(t, &fakeClient{}, cfg)
+
+	dropped := nftTransfer{txHash: small.Hash()}
+	if s.filterNFTRules(block, s.signer, &dropped) {
+		t.Error("NFT transfer satisfying no rule was kept with rules_only")
+	}
+	kept := nftTransfer{txHash: large.Hash()}
+	if !s.filterNFTRules(block, s.signer, &kept) || len(kept.rules) != 1 || kept.rules[0] != "large" {
+		t.Errorf("NFT transfer satisfying a rule: rules = %v, want [large]", kept.rules)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// seenCache is a bounded LRU of recently forwarded keys and when they were
+// first forwarded. It backs the per-process state stores' dedup, suppressing
+// re-sends when a range is scanned again without a database round-trip.
+type seenCache struct {
+	size int
+
+	mu    sync.Mutex
+	order *list.List // front = most recently used
+	items map[string]*list.Element
+}
+
+type seenEntry struct {
+	key    string
+	seenAt time.Time
+}
+
+func newSeenCache(size int) *seenCache {
+	return &seenCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// markSeen reports whether key was recorded less than window ago, and
+// otherwise records it as seen now, evicting the least recently used entry
+// when full.
+func (c *seenCache) markSeen(key string, window time.Duration) bool {
+	if c == nil || c.size <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		entry := el.Value.(*seenEntry)
+		if now.Sub(entry.seenAt) < window {
+			return true
+		}
+		entry.seenAt = now
+		return false
+	}
+	c.items[key] = c.order.PushFront(&seenEntry{key: key, seenAt: now})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*seenEntry).key)
+	}
+	return false
+}
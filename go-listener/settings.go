@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultSettingsRefresh = 60 // seconds
+
+// settingKeys are the config keys the settings table may override, by YAML
+// name. Live keys take effect on the next reload; the rest are read once
+// while starting up, so changing them needs a restart.
+var settingKeys = map[string]bool{
+	"poll_interval":  true,
+	"risk_threshold": true,
+	"min_value_wei":  true,
+	// Replaces every configured analyzer, ai_analyzer_url included
+	"analyzer_urls": true,
+
+	"analyzer_concurrency":       false,
+	"analyzer_breaker_threshold": false,
+	"analyzer_breaker_cooldown":  false,
+	"analyzer_breaker_action":    false,
+	"sample_rate":                false,
+	"sample_above_wei":           false,
+	"dedup_window":               false,
+	"wallet_refresh_interval":    false,
+}
+
+// liveConfig is the config with the latest settings table applied; nil until
+// a reload after startup. Code reading a live key goes through Config.live.
+var liveConfig atomic.Pointer[Config]
+
+// live returns the config carrying the current values of the live setting
+// keys. There is one Config per process, so the override applies to it.
+func (c *Config) live() *Config {
+	if l := liveConfig.Load(); l != nil {
+		return l
+	}
+	return c
+}
+
+// withSettings returns a copy of c with settings applied over it, validated.
+func (c *Config) withSettings(settings map[string]json.RawMessage) (*Config, error) {
+	overlay := make(map[string]interface{}, len(settings))
+	for key, raw := range settings {
+		if _, ok := settingKeys[key]; !ok {
+			return nil, fmt.Errorf("%s cannot be set at runtime", key)
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		overlay[key] = v
+	}
+	doc, err := yaml.Marshal(overlay)
+	if err != nil {
+		return nil, err
+	}
+	out := *c
+	if err := yaml.Unmarshal(doc, &out); err != nil {
+		return nil, err
+	}
+	if _, ok := settings["analyzer_urls"]; ok {
+		out.AIAnalyzerURL = ""
+	}
+	out.normalizeAnalyzerURLs()
+	if err := out.Validate(); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// settingsLoader keeps liveConfig in step with the settings table, applying
+// the stored overrides over the file and environment config they started from.
+type settingsLoader struct {
+	pool *pgxpool.Pool
+	base Config // file and environment config, before any override
+
+	mu     sync.Mutex
+	stored map[string]json.RawMessage // as last applied
+}
+
+// loadSettings applies the stored overrides to cfg before anything reads it,
+// returning the loader that keeps the live keys current. Invalid overrides
+// are logged and ignored, leaving the file and environment config in place.
+func loadSettings(ctx context.Context, pool *pgxpool.Pool, cfg *Config) *settingsLoader {
+	l := &settingsLoader{pool: pool, base: *cfg}
+	settings, err := dbpkg.FetchSettings(ctx, pool)
+	if err != nil {
+		log.Printf("⚠️  Could not read the settings table: %v", err)
+		return l
+	}
+	merged, err := cfg.withSettings(settings)
+	if err != nil {
+		log.Printf("⚠️  Ignoring stored settings: %v", err)
+		return l
+	}
+	*cfg = *merged
+	l.stored = settings
+	if len(settings) > 0 {
+		log.Printf("⚙️  Applied stored settings: %s", strings.Join(sortedKeys(settings), ", "))
+	}
+	return l
+}
+
+// Check validates a full set of overrides against the base config.
+func (l *settingsLoader) Check(settings map[string]json.RawMessage) error {
+	_, err := l.base.withSettings(settings)
+	return err
+}
+
+// reload re-reads the settings table and publishes the live keys. Changed
+// keys that are only read at startup are logged as waiting for a restart.
+func (l *settingsLoader) reload(ctx context.Context) error {
+	settings, err := dbpkg.FetchSettings(ctx, l.pool)
+	if err != nil {
+		return err
+	}
+	merged, err := l.base.withSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var changed, pending []string
+	for _, key := range sortedKeys(settingKeys) {
+		if string(settings[key]) == string(l.stored[key]) {
+			continue
+		}
+		if settingKeys[key] {
+			changed = append(changed, key)
+		} else {
+			pending = append(pending, key)
+		}
+	}
+	liveConfig.Store(merged)
+	l.stored = settings
+	if len(changed) > 0 {
+		log.Printf("⚙️  Settings changed: %s", strings.Join(changed, ", "))
+	}
+	if len(pending) > 0 {
+		log.Printf("⚙️  Settings changed that apply on restart: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Reload is reload for callers without a context, such as the API after a
+// write.
+func (l *settingsLoader) Reload() {
+	if err := l.reload(context.Background()); err != nil {
+		log.Printf("Error reloading settings: %v", err)
+	}
+}
+
+// run reloads the settings every interval, for writes made by other processes.
+func (l *settingsLoader) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		l.Reload()
+	}
+}
+
+// sortedKeys returns m's keys in order, for stable log lines.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+// Signers a chain can be configured with.
+const (
+	signerLatest    = "latest" // every transaction type, EIP-155 replay protection optional
+	signerEIP155    = "eip155" // legacy transactions by EIP-155 rules
+	signerHomestead = "homestead"
+	signerFrontier  = "frontier"
+)
+
+var unrecoveredSenders = metrics.NewCounterVec("blocksentinel_unrecovered_senders_total", "Transactions skipped because no signer could recover their sender.", "chain")
+
+// fallbackSigner recovers senders with its primary signer and, when that
+// fails, with each fallback in turn: Homestead for unprotected transactions,
+// then Frontier for pre-Homestead ones with high-s signatures. Transactions
+// no signer accepts are logged and counted, so gaps are visible. Typed
+// transactions (access-list, dynamic-fee, blob, ...) only exist under the
+// latest rules, so they are always recovered with the latest signer.
+type fallbackSigner struct {
+	types.Signer
+	fallbacks []types.Signer
+	typed     types.Signer
+	chain     string
+}
+
+// newChainSigner returns the named signer for chainID with the Homestead and
+// Frontier fallbacks; an empty name means signerLatest.
+func newChainSigner(name, chain string, chainID *big.Int) (types.Signer, error) {
+	var primary types.Signer
+	var fallbacks []types.Signer
+	switch name {
+	case "", signerLatest:
+		primary = types.LatestSignerForChainID(chainID)
+		fallbacks = []types.Signer{types.HomesteadSigner{}, types.FrontierSigner{}}
+	case signerEIP155:
+		primary = types.NewEIP155Signer(chainID)
+		fallbacks = []types.Signer{types.HomesteadSigner{}, types.FrontierSigner{}}
+	case signerHomestead:
+		primary = types.HomesteadSigner{}
+		fallbacks = []types.Signer{types.FrontierSigner{}}
+	case signerFrontier:
+		primary = types.FrontierSigner{}
+	default:
+		return nil, fmt.Errorf("unknown signer %q", name)
+	}
+	return &fallbackSigner{Signer: primary, fallbacks: fallbacks, typed: types.LatestSignerForChainID(chainID), chain: chain}, nil
+}
+
+func (f *fallbackSigner) Sender(tx *types.Transaction) (common.Address, error) {
+	primary, fallbacks := f.Signer, f.fallbacks
+	if tx.Type() != types.LegacyTxType {
+		primary, fallbacks = f.typed, nil
+	}
+	from, err := primary.Sender(tx)
+	if err == nil {
+		return from, nil
+	}
+	for _, s := range fallbacks {
+		if from, fallbackErr := s.Sender(tx); fallbackErr == nil {
+			return from, nil
+		}
+	}
+	unrecoveredSenders.With(f.chain).Inc()
+	log.Printf("[%s] ⚠️  Skipping transaction %s: cannot recover sender: %v", f.chain, tx.Hash().Hex(), err)
+	return common.Address{}, err
+}
+
+// Equal keeps types.Sender's per-transaction cache keyed to this signer
+// rather than to the primary it wraps.
+func (f *fallbackSigner) Equal(s types.Signer) bool {
+	other, ok := s.(*fallbackSigner)
+	return ok && other == f
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+// Sink types.
+const (
+	sinkStdout   = "stdout"   // one JSON object per line on stdout
+	sinkFile     = "file"     // JSON lines in a size-rotated file
+	sinkDatabase = "database" // rows in the alerts table
+)
+
+const (
+	// sinkBuffer is how many alerts a sink may fall behind before new ones
+	// are dropped for it, so a slow sink never holds up scanning.
+	sinkBuffer      = 1024
+	sinkTimeout     = 10 * time.Second
+	defaultSinkSize = 100 // MB
+)
+
+var sinkDropped = metrics.NewCounterVec("blocksentinel_sink_dropped_total", "Alerts dropped because a sink fell too far behind.", "sink")
+
+// SinkConfig selects and configures an alert sink. Every matched transaction
+// is written to every sink, independently of the analyzer.
+type SinkConfig struct {
+	Type       string `yaml:"type"`                  // "stdout", "file" or "database"
+	Path       string `yaml:"path,omitempty"`        // file sink
+	MaxSizeMB  int    `yaml:"max_size_mb,omitempty"` // file sink: rotate past this size; default 100
+	MaxBackups int    `yaml:"max_backups,omitempty"` // file sink: rotated files kept as path.1 ... path.N
+}
+
+// Sink receives every matched transaction.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, p *TxPayload) error
+}
+
+// buildSinks creates the configured sinks. pool is nil without Postgres,
+// which rules out database sinks; a dry run leaves them out.
+func buildSinks(cfg *Config, pool *pgxpool.Pool) ([]Sink, error) {
+	var out []Sink
+	for i, c := range cfg.Sinks {
+		switch c.Type {
+		case sinkStdout:
+			out = append(out, &stdoutSink{})
+		case sinkFile:
+			maxBytes := int64(c.MaxSizeMB) << 20
+			if c.MaxSizeMB <= 0 {
+				maxBytes = defaultSinkSize << 20
+			}
+			out = append(out, &fileSink{path: c.Path, maxBytes: maxBytes, maxBackups: c.MaxBackups})
+		case sinkDatabase:
+			if cfg.DryRun {
+				log.Printf("🧪 [dry-run] not writing alerts to the database sink")
+				continue
+			}
+			if pool == nil {
+				return nil, fmt.Errorf("sinks[%d]: the database sink needs Postgres", i)
+			}
+			out = append(out, &databaseSink{pool: pool})
+		default:
+			return nil, fmt.Errorf("sinks[%d]: unknown type %q", i, c.Type)
+		}
+	}
+	return out, nil
+}
+
+// asyncSink feeds one sink from its own goroutine through a bounded queue.
+type asyncSink struct {
+	sink    Sink
+	queue   chan *TxPayload
+	dropped *metrics.Metric
+}
+
+// alertSinks receive every matched transaction; see setAlertSinks.
+var alertSinks []*asyncSink
+
+// setAlertSinks starts a writer per sink. It must be called before any
+// scanner starts.
+func setAlertSinks(sinks []Sink) {
+	alertSinks = nil
+	for _, s := range sinks {
+		a := &asyncSink{sink: s, queue: make(chan *TxPayload, sinkBuffer), dropped: sinkDropped.With(s.Name())}
+		go a.run()
+		alertSinks = append(alertSinks, a)
+	}
+}
+
+func (a *asyncSink) run() {
+	for p := range a.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), sinkTimeout)
+		if err := a.sink.Write(ctx, p); err != nil {
+			log.Printf("Error writing %s to the %s sink: %v", p.Hash, a.sink.Name(), err)
+		}
+		cancel()
+	}
+}
+
+// emitToSinks queues p for every sink without waiting.
+func emitToSinks(p *TxPayload) {
+	for _, a := range alertSinks {
+		select {
+		case a.queue <- p:
+		default:
+			a.dropped.Inc()
+		}
+	}
+}
+
+// stdoutSink prints each alert as a JSON line, for log pipelines.
+type stdoutSink struct{}
+
+func (s *stdoutSink) Name() string { return sinkStdout }
+
+func (s *stdoutSink) Write(_ context.Context, p *TxPayload) error {
+	line, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(line, '\n'))
+	return err
+}
+
+// fileSink appends JSON lines to path, rotating it to path.1 (and older
+// files to path.2 ... path.N) once it would grow past maxBytes.
+type fileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (s *fileSink) Name() string { return sinkFile }
+
+func (s *fileSink) Write(_ context.Context, p *TxPayload) error {
+	line, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.open(); err != nil {
+		return err
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// open opens path for appending unless it is already open. s.mu must be held.
+func (s *fileSink) open() error {
+	if s.file != nil {
+		return nil
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+// rotate closes the current file and shifts it and its backups up by one,
+// dropping the oldest. s.mu must be held.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file, s.size = nil, 0
+	if s.maxBackups <= 0 {
+		return os.Remove(s.path)
+	}
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", s.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(s.path, s.path+".1")
+}
+
+// databaseSink stores each alert in the alerts table.
+type databaseSink struct {
+	pool *pgxpool.Pool
+}
+
+func (s *databaseSink) Name() string { return sinkDatabase }
+
+func (s *databaseSink) Write(ctx context.Context, p *TxPayload) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return dbpkg.InsertAlert(ctx, s.pool, p.ChainID, p.Hash, payload)
+}
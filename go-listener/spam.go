@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+const defaultSpamLabel = "spam"
+
+// Why an alert was suppressed as spam.
+const (
+	spamReasonDenylisted  = "denylisted token"
+	spamReasonUnsolicited = "unsolicited zero-value transfer of unknown token"
+)
+
+var spamSuppressed = metrics.NewCounterVec("blocksentinel_spam_suppressed_total", "Alerts suppressed as spam or airdrop token transfers.", "chain", "reason")
+
+// spamTokenCache holds the token denylist: the configured spam_tokens plus
+// stored addresses carrying the spam label, so the list can be managed
+// through the labels API and bulk import. It reloads like the wallet caches.
+type spamTokenCache struct {
+	store  dbpkg.Store
+	label  string
+	static []string
+	set    refreshingSet[map[common.Address]bool]
+}
+
+func newSpamTokenCache(store dbpkg.Store, label string, static []string, interval time.Duration) *spamTokenCache {
+	return &spamTokenCache{store: store, label: label, static: static, set: refreshingSet[map[common.Address]bool]{interval: interval}}
+}
+
+// Denied reports whether token is on the denylist.
+func (c *spamTokenCache) Denied(ctx context.Context, token common.Address) bool {
+	if c == nil {
+		return false
+	}
+	return c.set.get(func() (map[common.Address]bool, error) { return c.load(ctx) })[token]
+}
+
+// load builds the denylist. On a store error it returns the configured
+// spam_tokens along with the error.
+func (c *spamTokenCache) load(ctx context.Context) (map[common.Address]bool, error) {
+	addrs := append([]string(nil), c.static...)
+	var err error
+	if c.store != nil && c.label != "" {
+		var stored []dbpkg.MonitoredWallet
+		stored, err = c.store.FetchMonitoredWallets(ctx, c.label)
+		for _, w := range stored {
+			addrs = append(addrs, w.Address)
+		}
+	}
+	set := make(map[common.Address]bool, len(addrs))
+	for _, a := range addrs {
+		if canonical, ok := normalizeAddress(a); ok {
+			set[common.HexToAddress(canonical)] = true
+		}
+	}
+	return set, err
+}
+
+// knownToken reports whether token has a configured ABI, which the spam
+// heuristic takes as a sign the operator cares about it.
+func (s *Scanner) knownToken(token common.Address) bool {
+	return s.abis[token] != nil
+}
+
+// spamMatch returns why m should be suppressed as spam, or "". A call to a
+// denylisted contract is spam; with spam_heuristic set, so is a zero-value
+// call that only names the wallet in its calldata (the airdrop pattern) to a
+// contract without a configured ABI.
+func (s *Scanner) spamMatch(ctx context.Context, m matchedTx) string {
+	if m.isCreation {
+		return ""
+	}
+	if s.spamTokens.Denied(ctx, m.to) {
+		return spamReasonDenylisted
+	}
+	referencedOnly := m.wallet != m.from && m.wallet != m.to
+	if s.cfg.SpamHeuristic && referencedOnly && m.tx.Value().Sign() == 0 && !s.knownToken(m.to) {
+		return spamReasonUnsolicited
+	}
+	return ""
+}
+
+// spamNFTTransfer returns why t should be suppressed as spam, or "". Transfers
+// of denylisted collections are spam; with spam_heuristic set, so are
+// incoming transfers of a collection without a configured ABI in a zero-value
+// transaction the wallet did not send.
+func (s *Scanner) spamNFTTransfer(ctx context.Context, block *types.Block, signer types.Signer, t nftTransfer) string {
+	if s.spamTokens.Denied(ctx, t.collection) {
+		return spamReasonDenylisted
+	}
+	if !s.cfg.SpamHeuristic || t.direction != directionIncoming || s.knownToken(t.collection) {
+		return ""
+	}
+	tx := block.Transaction(t.txHash)
+	if tx == nil || tx.Value().Sign() != 0 {
+		return ""
+	}
+	if sender, err := types.Sender(signer, tx); err == nil && sender == t.to {
+		return ""
+	}
+	return spamReasonUnsolicited
+}
+
+// suppressSpam logs and counts a suppressed alert and reports whether reason
+// is set.
+func (s *Scanner) suppressSpam(hash common.Hash, reason string) bool {
+	if reason == "" {
+		return false
+	}
+	spamSuppressed.With(s.chain.Name, reason).Inc()
+	s.debugf("🗑️  Suppressed %s as spam: %s", hash.Hex(), reason)
+	return true
+}
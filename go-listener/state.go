@@ -2,14 +2,22 @@ package main
 
 import (
 	"encoding/json"
+	"log"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
+// State is the persisted scan progress. LastBlock holds the legacy single-chain
+// position; Chains holds per-chain positions keyed by state key (chain ID by default).
 type State struct {
-	LastBlock uint64 `json:"last_block"`
+	LastBlock uint64            `json:"last_block"`
+	Chains    map[string]uint64 `json:"chains,omitempty"`
 }
 
+// stateMu serializes read-modify-write cycles from concurrent chain scanners.
+var stateMu sync.Mutex
+
 func resolveStateFile(path string) (string, error) {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -33,24 +41,43 @@ func resolveStateFile(path string) (string, error) {
 	return path, nil
 }
 
-func loadState(path string) (uint64, error) {
+func readState(resolved string) (State, error) {
+	var state State
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("⚠️  State file %s is corrupt, starting from block 0: %v", resolved, err)
+		return State{}, nil
+	}
+	return state, nil
+}
+
+// loadState returns the last processed block for key; an empty key reads the
+// legacy single-chain position.
+func loadState(path, key string) (uint64, error) {
 	resolved, err := resolveStateFile(path)
 	if err != nil {
 		return 0, err
 	}
-	data, err := os.ReadFile(resolved)
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := readState(resolved)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
-		}
 		return 0, err
 	}
-	var state State
-	json.Unmarshal(data, &state)
-	return state.LastBlock, nil
+	if key == "" {
+		return state.LastBlock, nil
+	}
+	return state.Chains[key], nil
 }
 
-func saveState(path string, blockNum uint64) error {
+// saveState records blockNum for key, preserving other chains' positions.
+func saveState(path, key string, blockNum uint64) error {
 	resolved, err := resolveStateFile(path)
 	if err != nil {
 		return err
@@ -61,7 +88,56 @@ func saveState(path string, blockNum uint64) error {
 			return mkErr
 		}
 	}
-	state := State{LastBlock: blockNum}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := readState(resolved)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		state.LastBlock = blockNum
+	} else {
+		if state.Chains == nil {
+			state.Chains = make(map[string]uint64)
+		}
+		state.Chains[key] = blockNum
+	}
 	data, _ := json.Marshal(state)
-	return os.WriteFile(resolved, data, 0644)
+	return writeFileAtomic(resolved, data, 0644)
+}
+
+// readFileIfExists returns the file contents, or nil when it does not exist.
+func readFileIfExists(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// writeFileAtomic writes to a temp file in the target's directory and renames it
+// into place, so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+const (
+	stateBackendFile     = "file"
+	stateBackendPostgres = "postgres"
+	stateBackendRedis    = "redis"
+
+	stateFile = "state.json"
+)
+
+// StateStore holds scan positions and the recently-forwarded set. The file and
+// Postgres stores dedup in process memory; the Redis store shares both the
+// positions and the dedup set across replicas.
+type StateStore interface {
+	Name() string
+	// Load returns the last processed block for key, or 0 when none is stored.
+	Load(ctx context.Context, key string) (uint64, error)
+	Save(ctx context.Context, key string, blockNum uint64) error
+	// MarkSeen reports whether key was marked less than window ago, and
+	// otherwise marks it now.
+	MarkSeen(ctx context.Context, key string, window time.Duration) (bool, error)
+}
+
+// newStateStore builds the store selected by state_backend. A dry run never
+// writes to a shared backend, so other replicas' cursors stay put.
+func newStateStore(cfg *Config, db dbpkg.Store) (StateStore, error) {
+	var store StateStore
+	switch cfg.StateBackend {
+	case "", stateBackendFile:
+		return &fileStateStore{path: stateFile, seen: newSeenCache(cfg.SeenCacheSize)}, nil
+	case stateBackendPostgres:
+		if db == nil {
+			return nil, fmt.Errorf("state_backend %q needs a database connection", stateBackendPostgres)
+		}
+		store = &dbStateStore{db: db, seen: newSeenCache(cfg.SeenCacheSize)}
+	case stateBackendRedis:
+		client, err := newRedisClient(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		store = &redisStateStore{client: client}
+	default:
+		return nil, fmt.Errorf("unknown state backend %q", cfg.StateBackend)
+	}
+	if cfg.DryRun {
+		return &dryRunStateStore{StateStore: store, seen: newSeenCache(cfg.SeenCacheSize)}, nil
+	}
+	return store, nil
+}
+
+// sharedStateKey names the legacy single-chain position in shared stores,
+// where the state file's top-level last_block has no equivalent.
+func sharedStateKey(key string) string {
+	if key == "" {
+		return "default"
+	}
+	return key
+}
+
+// fileStateStore keeps positions in the local state file.
+type fileStateStore struct {
+	path string
+	seen *seenCache
+}
+
+func (f *fileStateStore) Name() string { return stateBackendFile }
+
+func (f *fileStateStore) Load(_ context.Context, key string) (uint64, error) {
+	return loadState(f.path, key)
+}
+
+func (f *fileStateStore) Save(_ context.Context, key string, blockNum uint64) error {
+	return saveState(f.path, key, blockNum)
+}
+
+func (f *fileStateStore) MarkSeen(_ context.Context, key string, window time.Duration) (bool, error) {
+	return f.seen.markSeen(key, window), nil
+}
+
+// dbStateStore keeps positions in the scan_state table of the database_url
+// store, which is Postgres or, despite the backend's name, SQLite. On Postgres
+// cross-replica dedup already comes from the transactions table, so the seen
+// set stays local.
+type dbStateStore struct {
+	db   dbpkg.Store
+	seen *seenCache
+}
+
+func (p *dbStateStore) Name() string { return p.db.Name() }
+
+func (p *dbStateStore) Load(ctx context.Context, key string) (uint64, error) {
+	return p.db.LoadScanState(ctx, sharedStateKey(key))
+}
+
+func (p *dbStateStore) Save(ctx context.Context, key string, blockNum uint64) error {
+	return p.db.SaveScanState(ctx, sharedStateKey(key), blockNum)
+}
+
+func (p *dbStateStore) MarkSeen(_ context.Context, key string, window time.Duration) (bool, error) {
+	return p.seen.markSeen(key, window), nil
+}
+
+// redisKeyPrefix namespaces every key the listener writes to Redis.
+const redisKeyPrefix = "blocksentinel:"
+
+// redisStateStore shares positions and the seen set through Redis. Seen keys
+// expire with the dedup window, so the set needs no size bound.
+type redisStateStore struct {
+	client *redisClient
+}
+
+func (r *redisStateStore) Name() string { return stateBackendRedis }
+
+func (r *redisStateStore) Load(ctx context.Context, key string) (uint64, error) {
+	reply, err := r.client.do(ctx, "GET", redisKeyPrefix+"state:"+sharedStateKey(key))
+	if err != nil || reply == nil {
+		return 0, err
+	}
+	s, ok := reply.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func (r *redisStateStore) Save(ctx context.Context, key string, blockNum uint64) error {
+	_, err := r.client.do(ctx, "SET", redisKeyPrefix+"state:"+sharedStateKey(key), strconv.FormatUint(blockNum, 10))
+	return err
+}
+
+func (r *redisStateStore) MarkSeen(ctx context.Context, key string, window time.Duration) (bool, error) {
+	// SET NX claims the key atomically, so two replicas re-scanning the same
+	// block cannot both forward it
+	reply, err := r.client.do(ctx, "SET", redisKeyPrefix+"seen:"+key, "1", "NX", "PX", strconv.FormatInt(window.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == nil, nil
+}
+
+// dryRunStateStore reads positions from a shared store but keeps saves and
+// dedup in process.
+type dryRunStateStore struct {
+	StateStore
+	seen *seenCache
+}
+
+func (d *dryRunStateStore) Save(context.Context, string, uint64) error { return nil }
+
+func (d *dryRunStateStore) MarkSeen(_ context.Context, key string, window time.Duration) (bool, error) {
+	return d.seen.markSeen(key, window), nil
+}
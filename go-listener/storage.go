@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+// storeTransaction persists a matched transaction when Postgres is available and
+// reports whether it was already forwarded within the dedup window, either
+// according to the state store's seen set or to the stored row's age.
+func (s *Scanner) storeTransaction(ctx context.Context, rec dbpkg.Transaction) (duplicate bool) {
+	window := time.Duration(s.cfg.DedupWindow) * time.Second
+	if s.markSeen(ctx, fmt.Sprintf("%d:%s", rec.ChainID, rec.Hash)) {
+		return true
+	}
+	if s.pool == nil || s.cfg.DryRun {
+		return false
+	}
+	// A buffered insert reports nothing, so the transaction is not a duplicate
+	var firstSeen time.Time
+	var inserted bool
+	err := s.writes.write(ctx, "transaction "+rec.Hash, func(ctx context.Context, pool *pgxpool.Pool) (err error) {
+		firstSeen, inserted, err = dbpkg.InsertTransaction(ctx, pool, rec)
+		return err
+	})
+	if err != nil {
+		s.txLogf(ctx, "Error storing transaction %s: %v", rec.Hash, err)
+		return false
+	}
+	return window > 0 && !inserted && time.Since(firstSeen) < window
+}
+
+// markSeen reports whether key was already forwarded within the dedup window,
+// marking it otherwise. Store errors fail open so alerts are never lost.
+func (s *Scanner) markSeen(ctx context.Context, key string) bool {
+	window := time.Duration(s.cfg.DedupWindow) * time.Second
+	if window <= 0 || s.state == nil {
+		return false
+	}
+	seen, err := s.state.MarkSeen(ctx, key, window)
+	if err != nil {
+		s.logf("Error checking %s dedup set: %v", s.state.Name(), err)
+		return false
+	}
+	return seen
+}
+
+// storeRiskAssessment persists one analyzer's result for a stored transaction.
+// Failures (e.g. the transaction row is missing) are logged, not fatal.
+func (s *Scanner) storeRiskAssessment(ctx context.Context, chainID uint64, txHash, analyzer string, result *RiskResult) {
+	if s.pool == nil {
+		return
+	}
+	score := result.Score
+	ra := dbpkg.RiskAssessment{
+		ChainID:   chainID,
+		TxHash:    txHash,
+		Analyzer:  analyzer,
+		RiskScore: &score,
+		Category:  result.Level,
+		Labels:    result.Labels,
+		Raw:       result.Raw,
+	}
+	err := s.writes.write(ctx, "risk assessment for "+txHash, func(ctx context.Context, pool *pgxpool.Pool) error {
+		return dbpkg.InsertRiskAssessment(ctx, pool, ra)
+	})
+	if err != nil {
+		s.txLogf(ctx, "Error storing risk assessment for %s: %v", txHash, err)
+	}
+}
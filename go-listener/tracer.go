@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errTracingUnsupported is returned when the node exposes neither tracing API.
+var errTracingUnsupported = errors.New("tracing not supported by RPC provider")
+
+// callFrame is a node of the geth callTracer output.
+type callFrame struct {
+	Type  string          `json:"type"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Value *hexutil.Big    `json:"value"`
+	Calls []callFrame     `json:"calls"`
+}
+
+// parityTrace is one entry of the trace_transaction (OpenEthereum/Erigon) output.
+type parityTrace struct {
+	Type   string `json:"type"`
+	Action struct {
+		CallType string          `json:"callType"`
+		From     common.Address  `json:"from"`
+		To       *common.Address `json:"to"`
+		Value    *hexutil.Big    `json:"value"`
+	} `json:"action"`
+	TraceAddress []int `json:"traceAddress"`
+}
+
+type internalTransfer struct {
+	From  common.Address
+	To    common.Address
+	Value *big.Int
+	Type  string
+}
+
+// isMethodUnavailable reports whether err means the RPC method is not exposed.
+func isMethodUnavailable(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32601 is method not found; some providers reject disallowed methods with -32600
+		return rpcErr.ErrorCode() == -32601 || rpcErr.ErrorCode() == -32600
+	}
+	return false
+}
+
+// traceInternalTransfers returns the value-bearing internal calls of txHash,
+// trying debug_traceTransaction first and trace_transaction as a fallback.
+func traceInternalTransfers(ctx context.Context, c EthClient, txHash common.Hash) ([]internalTransfer, error) {
+	var root callFrame
+	err := c.CallContext(ctx, &root, "debug_traceTransaction", txHash, map[string]string{"tracer": "callTracer"})
+	if err == nil {
+		var out []internalTransfer
+		// The root frame is the top-level transaction itself; only nested calls are internal
+		for _, child := range root.Calls {
+			collectCallFrames(child, &out)
+		}
+		return out, nil
+	}
+	if !isMethodUnavailable(err) {
+		return nil, err
+	}
+
+	var traces []parityTrace
+	err = c.CallContext(ctx, &traces, "trace_transaction", txHash)
+	if err != nil {
+		if isMethodUnavailable(err) {
+			return nil, errTracingUnsupported
+		}
+		return nil, err
+	}
+	var out []internalTransfer
+	for _, t := range traces {
+		if t.Type != "call" || len(t.TraceAddress) == 0 || t.Action.To == nil || t.Action.Value == nil {
+			continue
+		}
+		if v := t.Action.Value.ToInt(); v.Sign() > 0 {
+			out = append(out, internalTransfer{From: t.Action.From, To: *t.Action.To, Value: v, Type: t.Action.CallType})
+		}
+	}
+	return out, nil
+}
+
+func collectCallFrames(f callFrame, out *[]internalTransfer) {
+	if f.To != nil && f.Value != nil && f.Value.ToInt().Sign() > 0 {
+		*out = append(*out, internalTransfer{From: f.From, To: *f.To, Value: f.Value.ToInt(), Type: f.Type})
+	}
+	for _, child := range f.Calls {
+		collectCallFrames(child, out)
+	}
+}
+
+// traceWalletTransfers adds the internal transfers of a matched contract call
+// that touch monitored wallets to p. Tracing is switched off for the rest
+// of the session once the provider reports it is unsupported.
+func (s *Scanner) traceWalletTransfers(ctx context.Context, txHash common.Hash, walletSet map[common.Address]bool, p *TxPayload) {
+	if !s.cfg.EnableTracing || s.tracingUnsupported {
+		return
+	}
+	transfers, err := traceInternalTransfers(ctx, s.client, txHash)
+	if err != nil {
+		if errors.Is(err, errTracingUnsupported) {
+			s.tracingUnsupported = true
+			s.logf("⚠️  %v; internal transaction monitoring disabled", err)
+			return
+		}
+		s.logf("Error tracing transaction %s: %v", txHash.Hex(), err)
+		return
+	}
+
+	for _, t := range transfers {
+		if walletSet[t.From] || walletSet[t.To] {
+			p.InternalTransfers = append(p.InternalTransfers, InternalTransfer{
+				From:  t.From.Hex(),
+				To:    t.To.Hex(),
+				Value: t.Value.String(),
+				Type:  t.Type,
+			})
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff schedule for RetryWithBackoff: the wait doubles from
+// BackoffInitial after each failed attempt, up to BackoffMax.
+const (
+	BackoffInitial = 500 * time.Millisecond
+	BackoffMax     = 5 * time.Second
+)
+
+// Clock is the time source for retry loops, so tests can drive a backoff
+// schedule without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock is the wall clock.
+var RealClock Clock = realClock{}
+
+// RetryWithBackoff calls attempt until it succeeds, waiting 500ms, 1s, 2s,
+// 4s, then 5s between attempts. Once maxWait has passed since the first attempt
+// it returns the last attempt's error; a cancelled ctx returns ctx.Err().
+func RetryWithBackoff(ctx context.Context, clock Clock, maxWait time.Duration, attempt func(ctx context.Context) error) error {
+	wait := BackoffInitial
+	started := clock.Now()
+	for {
+		err := attempt(ctx)
+		if err == nil {
+			return nil
+		}
+		if clock.Now().Sub(started) >= maxWait {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(wait):
+		}
+
+		wait = min(wait*2, BackoffMax)
+	}
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeClock fires every After immediately, advancing its time by the wait,
+// and records the waits it was asked for.
+type fakeClock struct {
+	now   time.Time
+	waits []time.Duration
+	stall bool // never fire, as if the wait outlived the context
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	if !c.stall {
+		c.now = c.now.Add(d)
+		ch <- c.now
+	}
+	return ch
+}
+
+var errAttempt = errors.New("attempt failed")
+
+func TestRetryWithBackoffSchedule(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	attempts := 0
+	err := RetryWithBackoff(context.Background(), clock, 20*time.Second, func(ctx context.Context) error {
+		attempts++
+		return errAttempt
+	})
+	if !errors.Is(err, errAttempt) {
+		t.Fatalf("err = %v, want the last attempt's error", err)
+	}
+
+	// 0.5+1+2+4+5+5 = 17.5s is under maxWait, so one more 5s wait runs
+	// before the attempt at 22.5s gives up
+	want := []time.Duration{
+		500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second,
+		5 * time.Second, 5 * time.Second, 5 * time.Second,
+	}
+	if !slices.Equal(clock.waits, want) {
+		t.Errorf("waits = %v, want %v", clock.waits, want)
+	}
+	if attempts != len(want)+1 {
+		t.Errorf("attempts = %d, want %d", attempts, len(want)+1)
+	}
+}
+
+func TestRetryWithBackoffStopsOnSuccess(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	attempts := 0
+	err := RetryWithBackoff(context.Background(), clock, time.Minute, func(ctx context.Context) error {
+		if attempts++; attempts < 3 {
+			return errAttempt
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	if want := []time.Duration{500 * time.Millisecond, time.Second}; !slices.Equal(clock.waits, want) {
+		t.Errorf("waits = %v, want %v", clock.waits, want)
+	}
+}
+
+func TestRetryWithBackoffZeroMaxWait(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	attempts := 0
+	err := RetryWithBackoff(context.Background(), clock, 0, func(ctx context.Context) error {
+		attempts++
+		return errAttempt
+	})
+	if !errors.Is(err, errAttempt) || attempts != 1 || len(clock.waits) != 0 {
+		t.Errorf("got (%v, %d attempts, waits %v), want one attempt and no wait", err, attempts, clock.waits)
+	}
+}
+
+func TestRetryWithBackoffCancelled(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0), stall: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	err := RetryWithBackoff(ctx, clock, time.Minute, func(ctx context.Context) error {
+		cancel()
+		return errAttempt
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
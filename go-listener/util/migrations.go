@@ -1,26 +1,81 @@
 package util
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/nidhish1/BlockSentinel/go-listener/migrations"
 	goose "github.com/pressly/goose/v3"
 )
 
-func RunMigrations(dsn string, dir string) error {
+// migrationFS returns the migrations to apply: the SQL files in dir when it
+// is set, overriding the ones embedded in the binary.
+func migrationFS(dir string) (fs.FS, error) {
+	if dir == "" {
+		return migrations.FS, nil
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("migrations dir: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("migrations dir %s is not a directory", dir)
+	}
+	return os.DirFS(dir), nil
+}
+
+func openMigrationProvider(dsn, dir string) (*goose.Provider, error) {
+	fsys, err := migrationFS(dir)
+	if err != nil {
+		return nil, err
+	}
 	db, err := sql.Open("pgx", dsn)
 	if err != nil {
-		return fmt.Errorf("open db: %w", err)
+		return nil, fmt.Errorf("open db: %w", err)
 	}
-	defer db.Close()
+	p, err := goose.NewProvider(goose.DialectPostgres, db, fsys)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return p, nil
+}
 
-	if err := goose.SetDialect("postgres"); err != nil {
-		return fmt.Errorf("set dialect: %w", err)
+// RunMigrations applies every pending migration from dir, or from the
+// embedded set when dir is empty.
+func RunMigrations(dsn string, dir string) error {
+	p, err := openMigrationProvider(dsn, dir)
+	if err != nil {
+		return err
 	}
+	defer p.Close()
 
-	if err := goose.Up(db, dir); err != nil {
+	if _, err := p.Up(context.Background()); err != nil {
 		return fmt.Errorf("migrations up: %w", err)
 	}
 	return nil
 }
+
+// MigrationStatus returns the schema version applied to the database and the
+// latest version available in dir (or embedded when dir is empty). current <
+// target means migrations are pending.
+func MigrationStatus(dsn string, dir string) (current, target int64, err error) {
+	p, err := openMigrationProvider(dsn, dir)
+	if errors.Is(err, goose.ErrNoMigrations) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer p.Close()
+
+	current, err = p.GetDBVersion(context.Background())
+	if err != nil {
+		return 0, 0, fmt.Errorf("read db version: %w", err)
+	}
+	sources := p.ListSources()
+	return current, sources[len(sources)-1].Version, nil
+}
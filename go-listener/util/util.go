@@ -7,37 +7,59 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// ConnectPostgresWithBackoff attempts to create a pgx pool and ping the database
-// with exponential backoff up to maxWait. Returns a ready-to-use pool or error.
-func ConnectPostgresWithBackoff(ctx context.Context, dsn string, maxWait time.Duration) (*pgxpool.Pool, error) {
-	var pool *pgxpool.Pool
-	var err error
-	wait := 500 * time.Millisecond
-	started := time.Now()
+// PoolOptions overrides pgxpool settings parsed from the DSN. Zero values keep
+// whatever the DSN (or pgxpool's defaults) specify.
+type PoolOptions struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration
+}
 
-	for {
-		pool, err = pgxpool.New(ctx, dsn)
-		if err == nil {
-			if pingErr := pool.Ping(ctx); pingErr == nil {
-				return pool, nil
-			} else {
-				err = pingErr
-				pool.Close()
-			}
-		}
+// PoolConfig parses dsn and applies opts on top of it.
+func PoolConfig(dsn string, opts PoolOptions) (*pgxpool.Config, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxConns > 0 {
+		cfg.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		cfg.MinConns = opts.MinConns
+	}
+	if opts.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.HealthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = opts.HealthCheckPeriod
+	}
+	return cfg, nil
+}
 
-		if time.Since(started) >= maxWait {
-			return nil, err
-		}
+// ConnectPostgresWithBackoff attempts to create a pgx pool and ping the database
+// with exponential backoff up to maxWait. Returns a ready-to-use pool or error.
+func ConnectPostgresWithBackoff(ctx context.Context, cfg *pgxpool.Config, maxWait time.Duration) (*pgxpool.Pool, error) {
+	return connectPostgres(ctx, RealClock, cfg, maxWait)
+}
 
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(wait):
+// connectPostgres is ConnectPostgresWithBackoff on the given clock.
+func connectPostgres(ctx context.Context, clock Clock, cfg *pgxpool.Config, maxWait time.Duration) (*pgxpool.Pool, error) {
+	var pool *pgxpool.Pool
+	err := RetryWithBackoff(ctx, clock, maxWait, func(ctx context.Context) error {
+		p, err := pgxpool.NewWithConfig(ctx, cfg.Copy())
+		if err != nil {
+			return err
 		}
-
-		if wait < 5*time.Second {
-			wait *= 2
+		if err := p.Ping(ctx); err != nil {
+			p.Close()
+			return err
 		}
+		pool = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return pool, nil
 }
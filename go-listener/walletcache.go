@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbpkg "github.com/nidhish1/BlockSentinel/go-listener/db"
+)
+
+// walletGeneration is bumped whenever the stored watchlist changes, telling
+// every WalletCache to reload on its next use.
+var walletGeneration atomic.Uint64
+
+// invalidateWalletCaches marks all wallet caches stale.
+func invalidateWalletCaches() { walletGeneration.Add(1) }
+
+const (
+	listenRetryBaseWait = time.Second
+	listenRetryMaxWait  = time.Minute
+)
+
+// watchAddressChanges invalidates the wallet caches as soon as any process
+// writes to the stored watchlist. While the LISTEN connection is down the
+// caches keep reloading on their refresh interval, and it is re-established
+// with backoff. It runs until the process exits.
+func watchAddressChanges(pool *pgxpool.Pool) {
+	wait := listenRetryBaseWait
+	for {
+		err := dbpkg.ListenAddressChanges(context.Background(), pool, func() {
+			wait = listenRetryBaseWait
+			// Changes made while disconnected were missed
+			invalidateWalletCaches()
+			log.Printf("👂 Listening for address changes on %s", dbpkg.AddressesChannel)
+		}, invalidateWalletCaches)
+		log.Printf("⚠️  Address change listener stopped: %v; polling until it reconnects in %s", err, wait)
+		time.Sleep(wait)
+		if wait *= 2; wait > listenRetryMaxWait {
+			wait = listenRetryMaxWait
+		}
+	}
+}
+
+// normalizeAddress returns addr in lowercase canonical form ("0x" plus 40 hex
+// digits), accepting surrounding whitespace, a missing or upper-case prefix and
+// any letter case. ok is false when addr is not an address at all, which
+// common.HexToAddress would otherwise silently turn into a different one.
+func normalizeAddress(addr string) (canonical string, ok bool) {
+	addr = strings.TrimSpace(addr)
+	if !common.IsHexAddress(addr) {
+		return "", false
+	}
+	return strings.ToLower(common.HexToAddress(addr).Hex()), true
+}
+
+// WalletCache keeps a chain's watchlist in memory as a lookup set, reloading it
+// from the store at most once per interval or after an invalidation. Without a
+// store, or when it has no matching addresses, the configured wallets are used;
+// ENS names among them are watched at their resolved address.
+type WalletCache struct {
+	store    dbpkg.Store
+	label    string
+	fallback []string
+	ens      *ensResolver // nil when no configured wallet is an ENS name
+
+	list refreshingSet[walletList]
+}
+
+// walletList is one load of a WalletCache.
+type walletList struct {
+	set      map[common.Address]bool
+	settings map[common.Address]*dbpkg.WalletSettings
+	names    map[common.Address]string // ENS name of each resolved wallet
+}
+
+func newWalletCache(store dbpkg.Store, label string, fallback []string, interval time.Duration) *WalletCache {
+	return &WalletCache{store: store, label: label, fallback: fallback, list: refreshingSet[walletList]{interval: interval}}
+}
+
+// Set returns the current watchlist. The returned map is shared and must not be modified.
+func (c *WalletCache) Set(ctx context.Context) map[common.Address]bool {
+	return c.list.get(func() (walletList, error) { return c.load(ctx) }).set
+}
+
+// load builds the watchlist from the store, or from the configured wallets
+// when the store has none. On a store error it returns the configured wallets
+// along with the error.
+func (c *WalletCache) load(ctx context.Context) (walletList, error) {
+	var wallets []dbpkg.MonitoredWallet
+	names := make(map[common.Address]string)
+	for _, w := range c.fallback {
+		if isENSName(w) {
+			addr, ok := c.ens.Lookup(w)
+			if !ok {
+				// Not resolved (yet); the resolver has logged why
+				continue
+			}
+			names[addr] = w
+			w = addr.Hex()
+		}
+		wallets = append(wallets, dbpkg.MonitoredWallet{Address: w})
+	}
+	var err error
+	if c.store != nil {
+		var stored []dbpkg.MonitoredWallet
+		if stored, err = c.store.FetchMonitoredWallets(ctx, c.label); err == nil && len(stored) > 0 {
+			wallets = stored
+			names = nil
+		}
+	}
+
+	list := walletList{
+		set:      make(map[common.Address]bool, len(wallets)),
+		settings: make(map[common.Address]*dbpkg.WalletSettings),
+		names:    names,
+	}
+	for _, w := range wallets {
+		canonical, ok := normalizeAddress(w.Address)
+		if !ok {
+			log.Printf("Ignoring invalid watchlist address %q", w.Address)
+			continue
+		}
+		addr := common.HexToAddress(canonical)
+		list.set[addr] = true
+		if w.Settings != nil {
+			list.settings[addr] = w.Settings
+		}
+	}
+	return list, err
+}
+
+// Settings returns the per-address overrides for addr from the last load, or
+// nil when it has none.
+func (c *WalletCache) Settings(addr common.Address) *dbpkg.WalletSettings {
+	if c == nil {
+		return nil
+	}
+	return c.list.peek().settings[addr]
+}
+
+// ENSName returns the configured ENS name addr was resolved from, or "".
+func (c *WalletCache) ENSName(addr common.Address) string {
+	if c == nil {
+		return ""
+	}
+	return c.list.peek().names[addr]
+}
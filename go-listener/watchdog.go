@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStallWindow = 600 // seconds
+	maxStallCheckEvery = 30 * time.Second
+)
+
+// scanProgress is when a chain was last known to be healthy: either advancing
+// or fully caught up with the confirmed head.
+type scanProgress struct {
+	mu        sync.Mutex
+	lastBlock uint64
+	headBlock uint64 // latest confirmed block at the last successful head poll
+	healthyAt time.Time
+}
+
+// observe records a successful poll that left the scan at lastBlock with the
+// confirmed head at headBlock.
+func (p *scanProgress) observe(lastBlock, headBlock uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if lastBlock > p.lastBlock || lastBlock >= headBlock || p.healthyAt.IsZero() {
+		p.healthyAt = time.Now()
+	}
+	p.lastBlock, p.headBlock = lastBlock, headBlock
+}
+
+func (p *scanProgress) snapshot() (lastBlock, headBlock uint64, healthyAt time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastBlock, p.headBlock, p.healthyAt
+}
+
+// stalledChains holds a reason per chain whose scanner is stuck; /readyz
+// reports unhealthy while it is non-empty.
+var stalledChains = struct {
+	sync.Mutex
+	reasons map[string]string
+}{reasons: make(map[string]string)}
+
+func setStalled(chain, reason string) {
+	stalledChains.Lock()
+	defer stalledChains.Unlock()
+	if reason == "" {
+		delete(stalledChains.reasons, chain)
+		return
+	}
+	stalledChains.reasons[chain] = reason
+}
+
+// readinessProblems lists every stalled chain, for /readyz.
+func readinessProblems() []string {
+	stalledChains.Lock()
+	defer stalledChains.Unlock()
+	out := make([]string, 0, len(stalledChains.reasons))
+	for chain, reason := range stalledChains.reasons {
+		out = append(out, chain+": "+reason)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// watchForStalls alerts when the scanner has neither advanced nor been caught
+// up for StallWindow, which covers both a stuck RPC node and blocks that keep
+// failing to process. It alerts again on recovery. It runs until the process
+// exits and is a no-op when StallWindow is 0.
+func (s *Scanner) watchForStalls() {
+	window := time.Duration(s.cfg.StallWindow) * time.Second
+	if window <= 0 {
+		return
+	}
+	every := window / 4
+	if every > maxStallCheckEvery {
+		every = maxStallCheckEvery
+	}
+	// The first poll may legitimately take a while; start the clock now
+	s.progress.mu.Lock()
+	if s.progress.healthyAt.IsZero() {
+		s.progress.healthyAt = time.Now()
+	}
+	s.progress.mu.Unlock()
+
+	stalled := false
+	for range time.Tick(every) {
+		last, head, healthyAt := s.progress.snapshot()
+		since := time.Since(healthyAt)
+		switch {
+		case since >= window && !stalled:
+			stalled = true
+			reason := fmt.Sprintf("stuck at block %d for %s (confirmed head %d)", last, since.Round(time.Second), head)
+			setStalled(s.chain.Name, reason)
+			s.logf("🚨 Scanner stalled: %s", reason)
+			s.sendHealthAlert(HealthAlert{Chain: s.chain.Name, Status: "stalled", LastBlock: last, HeadBlock: head, Since: healthyAt.UTC()})
+		case since < window && stalled:
+			stalled = false
+			setStalled(s.chain.Name, "")
+			s.logf("✅ Scanner recovered at block %d", last)
+			s.sendHealthAlert(HealthAlert{Chain: s.chain.Name, Status: "recovered", LastBlock: last, HeadBlock: head, Since: healthyAt.UTC()})
+		}
+	}
+}
+
+// sendHealthAlert fans a scanner health change out to every notifier.
+func (s *Scanner) sendHealthAlert(a HealthAlert) {
+	if s.cfg.DryRun {
+		return
+	}
+	for _, nt := range s.notifiers {
+		go func(nt Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := nt.NotifyHealth(ctx, a); err != nil {
+				log.Printf("Error sending %s health alert for %s: %v", nt.Name(), a.Chain, err)
+			}
+		}(nt)
+	}
+}
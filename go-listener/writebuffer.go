@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nidhish1/BlockSentinel/go-listener/metrics"
+)
+
+// What to drop when the write buffer is full.
+const (
+	overflowDropOldest = "drop_oldest"
+	overflowDropNewest = "drop_newest"
+)
+
+const writeFlushInterval = 5 * time.Second
+
+var (
+	writeBufferDepth   = metrics.NewGauge("blocksentinel_db_write_buffer_depth", "Database writes buffered while Postgres is unreachable.")
+	writeBufferDropped = metrics.NewCounter("blocksentinel_db_write_buffer_dropped_total", "Buffered database writes dropped because the buffer was full.")
+)
+
+// bufferedWrite is one deferred database write.
+type bufferedWrite struct {
+	seq  uint64
+	what string // for logs, e.g. "transaction 0xabc..."
+	exec func(ctx context.Context, pool *pgxpool.Pool) error
+}
+
+// writeBuffer holds the scanner's database writes while Postgres is
+// unreachable and replays them in order once it is back. It is bounded:
+// when full, the oldest or the newest write is dropped per the overflow
+// policy. Buffered writes live in memory and are lost on restart.
+type writeBuffer struct {
+	pool       *pgxpool.Pool
+	capacity   int // 0 disables buffering
+	dropNewest bool
+
+	mu          sync.Mutex
+	items       []bufferedWrite
+	seq         uint64
+	overflowing bool // logged the first drop of this outage
+}
+
+func newWriteBuffer(pool *pgxpool.Pool, capacity int, overflow string) *writeBuffer {
+	return &writeBuffer{pool: pool, capacity: capacity, dropNewest: overflow == overflowDropNewest}
+}
+
+// write runs exec now, or buffers it when writes are already buffered (to
+// keep them in order) or when it fails because the database is unreachable.
+// It returns exec's error only when the database answered, so the caller can
+// log a write that will never succeed.
+func (b *writeBuffer) write(ctx context.Context, what string, exec func(ctx context.Context, pool *pgxpool.Pool) error) error {
+	if b.capacity == 0 {
+		return exec(ctx, b.pool)
+	}
+	b.mu.Lock()
+	if len(b.items) > 0 {
+		b.push(bufferedWrite{what: what, exec: exec})
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+
+	err := exec(ctx, b.pool)
+	if err == nil || !b.unreachable(ctx, err) {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.items) == 0 {
+		log.Printf("⚠️  Postgres unreachable (%v); buffering database writes until it recovers", err)
+	}
+	b.push(bufferedWrite{what: what, exec: exec})
+	return nil
+}
+
+// unreachable reports whether err means the database could not be reached,
+// rather than that it rejected the write.
+func (b *writeBuffer) unreachable(ctx context.Context, err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return false
+	}
+	return b.pool.Ping(ctx) != nil
+}
+
+// push appends w, applying the overflow policy. b.mu must be held.
+func (b *writeBuffer) push(w bufferedWrite) {
+	if len(b.items) >= b.capacity {
+		if !b.overflowing {
+			b.overflowing = true
+			which := "oldest"
+			if b.dropNewest {
+				which = "newest"
+			}
+			log.Printf("⚠️  Database write buffer full (%d); dropping the %s writes", b.capacity, which)
+		}
+		writeBufferDropped.Inc()
+		if b.dropNewest {
+			return
+		}
+		b.items = b.items[1:]
+	}
+	b.seq++
+	w.seq = b.seq
+	b.items = append(b.items, w)
+	writeBufferDepth.Set(float64(len(b.items)))
+}
+
+// run flushes the buffer whenever Postgres answers again. It runs until the
+// process exits.
+func (b *writeBuffer) run() {
+	if b.capacity == 0 {
+		return
+	}
+	ticker := time.NewTicker(writeFlushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		b.flush(context.Background())
+	}
+}
+
+// flush replays buffered writes in order, stopping at the first one that
+// cannot reach the database. Writes the database rejects are logged and
+// dropped, as they would have been without the outage.
+func (b *writeBuffer) flush(ctx context.Context) {
+	b.mu.Lock()
+	pending := len(b.items)
+	b.mu.Unlock()
+	if pending == 0 || b.pool.Ping(ctx) != nil {
+		return
+	}
+
+	flushed := 0
+	for {
+		b.mu.Lock()
+		if len(b.items) == 0 {
+			b.overflowing = false
+			b.mu.Unlock()
+			break
+		}
+		w := b.items[0]
+		b.mu.Unlock()
+
+		err := w.exec(ctx, b.pool)
+		if err != nil && b.unreachable(ctx, err) {
+			log.Printf("⚠️  Postgres unreachable again after flushing %d buffered write(s): %v", flushed, err)
+			return
+		}
+		if err != nil {
+			log.Printf("Error replaying buffered %s: %v", w.what, err)
+		}
+
+		b.mu.Lock()
+		// Unless a full buffer already dropped it meanwhile
+		if len(b.items) > 0 && b.items[0].seq == w.seq {
+			b.items = b.items[1:]
+		}
+		writeBufferDepth.Set(float64(len(b.items)))
+		b.mu.Unlock()
+		flushed++
+	}
+	log.Printf("✅ Postgres recovered; flushed %d buffered database write(s)", flushed)
+}